@@ -0,0 +1,155 @@
+// Package admin define as respostas da API HTTP de administração do
+// sequencer e das réplicas (/admin/...) e o cliente usado por dur admin.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status é a resposta de GET /admin/status
+type Status struct {
+	Role string `json:"role"` // "sequencer" ou "replica"
+	Addr string `json:"addr"`
+	// LastCommitted é a sequência do último commit: aplicado, na réplica;
+	// respondido ao cliente, no sequencer
+	LastCommitted uint64 `json:"lastCommitted"`
+	// LastApplied é a maior sequência aplicada por todos os membros; na
+	// réplica é igual a LastCommitted
+	LastApplied uint64   `json:"lastApplied"`
+	Members     []Member `json:"members,omitempty"`
+	Keys        int      `json:"keys,omitempty"`
+	Tombstones  int      `json:"tombstones,omitempty"`
+	Queue       int      `json:"queue,omitempty"` // commits na fila do sequencer
+}
+
+// Member é uma réplica vista pelo sequencer
+type Member struct {
+	Addr string `json:"addr"`
+	Seq  uint64 `json:"seq"` // última sequência confirmada pela réplica
+	Lag  uint64 `json:"lag"` // sequências atrás da réplica mais adiantada
+}
+
+// Version é uma versão de uma chave, com a transação que a escreveu
+type Version struct {
+	Version uint64 `json:"version"`
+	Cid     string `json:"cid,omitempty"`
+	Tid     string `json:"tid,omitempty"`
+	Value   []byte `json:"value,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// Key é a resposta de GET /admin/keys/{key}
+type Key struct {
+	Item    string    `json:"item"`
+	Found   bool      `json:"found"` // existe e não foi removida
+	Current Version   `json:"current"`
+	History []Version `json:"history"` // da mais antiga à atual
+	// Truncated indica que versões mais antigas que History foram descartadas
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Inflight é um commit recebido pelo sequencer e ainda não respondido
+type Inflight struct {
+	Cid   string    `json:"cid"`
+	Tid   string    `json:"tid"`
+	State string    `json:"state"` // "queued" ou "broadcasting"
+	Since time.Time `json:"since"` // entrada no estado atual
+	// Replica é a réplica que está certificando o commit, em "broadcasting"
+	Replica string `json:"replica,omitempty"`
+}
+
+// Abort é uma transação abortada, com o motivo
+type Abort struct {
+	At     time.Time `json:"at"`
+	Cid    string    `json:"cid"`
+	Tid    string    `json:"tid"`
+	Reason string    `json:"reason"`
+	Item   string    `json:"item,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// DefaultAborts é quantos aborts recentes cada componente guarda
+const DefaultAborts = 100
+
+// Aborts guarda os últimos aborts, até um limite
+type Aborts struct {
+	mu   sync.Mutex
+	max  int
+	list []Abort
+}
+
+// NewAborts cria um registro de até n aborts
+func NewAborts(n int) *Aborts {
+	return &Aborts{max: n}
+}
+
+// Add registra a, descartando o mais antigo se o limite foi atingido
+func (a *Aborts) Add(ab Abort) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.list = append(a.list, ab)
+	if len(a.list) > a.max {
+		a.list = a.list[len(a.list)-a.max:]
+	}
+}
+
+// List retorna os aborts guardados, do mais recente ao mais antigo
+func (a *Aborts) List() []Abort {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]Abort, len(a.list))
+	for i, ab := range a.list {
+		out[len(a.list)-1-i] = ab
+	}
+	return out
+}
+
+// WriteJSON responde v como JSON com o código code
+func WriteJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// Error é o corpo das respostas de erro
+type Error struct {
+	Error string `json:"error"`
+}
+
+// ErrNotFound é retornado por Get quando o recurso não existe
+var ErrNotFound = errors.New("not found")
+
+// Get busca path em addr, um endereço host:porta ou URL, e decodifica a
+// resposta em out
+func Get(client *http.Client, addr, path string, out any) error {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	resp, err := client.Get(strings.TrimSuffix(addr, "/") + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var e Error
+		msg := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			msg = e.Error
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %s", ErrNotFound, msg)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
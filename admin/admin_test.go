@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAborts(t *testing.T) {
+	a := NewAborts(2)
+	for _, tid := range []string{"t1", "t2", "t3"} {
+		a.Add(Abort{Tid: tid})
+	}
+	got := a.List()
+	if len(got) != 2 || got[0].Tid != "t3" || got[1].Tid != "t2" {
+		t.Fatalf("List() = %+v", got)
+	}
+}
+
+func TestGet(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ok", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, Status{Role: "replica", LastCommitted: 7})
+	})
+	mux.HandleFunc("GET /missing", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusNotFound, Error{Error: "key k not found"})
+	})
+	mux.HandleFunc("GET /broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var st Status
+	// sem esquema, o endereço é host:porta
+	if err := Get(srv.Client(), strings.TrimPrefix(srv.URL, "http://"), "/ok", &st); err != nil || st.LastCommitted != 7 {
+		t.Fatalf("Get = %+v, %v", st, err)
+	}
+	if err := Get(srv.Client(), srv.URL+"/", "/missing", &st); !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "key k not found") {
+		t.Fatalf("Expected ErrNotFound with the message, got %v", err)
+	}
+	if err := Get(srv.Client(), srv.URL, "/broken", &st); err == nil || !strings.Contains(err.Error(), "500") || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("Expected the server error, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+// Package antientropy verifica se as réplicas concordam. Cada réplica
+// mantém um hash encadeado dos commits aplicados e uma árvore de hashes
+// das chaves vivas (types.DigestReply); o Checker compara os hashes
+// encadeados na maior sequência comum, aponta as réplicas fora da maioria
+// e as chaves em que diferem e, com Repair, copia para elas o estado da
+// referência.
+package antientropy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/hrodric0/dur-impl/logging"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// Checker compara as réplicas de Replicas
+type Checker struct {
+	Replicas  []string
+	Transport network.Transport // nil usa TCP
+	Timeout   time.Duration     // prazo de cada pedido; zero não impõe prazo
+	// Repair, se verdadeiro, instala nas réplicas divergentes o estado da
+	// referência. Commits em andamento durante o reparo podem exigir uma
+	// nova rodada.
+	Repair bool
+	Logger *slog.Logger // nil usa slog.Default()
+}
+
+// Divergence é uma réplica que não concorda com a referência
+type Divergence struct {
+	Replica string `json:"replica"`
+	// Chain indica que os commits aplicados até Report.Seq diferem; falso
+	// quando só o estado atual difere
+	Chain bool `json:"chain"`
+	// Items são as chaves vivas com valor ou versão diferentes da referência
+	Items    []string `json:"items,omitempty"`
+	Repaired bool     `json:"repaired,omitempty"`
+	Error    string   `json:"error,omitempty"` // falha ao comparar ou reparar
+}
+
+// Report é o resultado de uma verificação
+type Report struct {
+	Seq         uint64            `json:"seq"`       // sequência em que os hashes foram comparados
+	Reference   string            `json:"reference"` // réplica da maioria mais adiantada
+	Divergences []Divergence      `json:"divergences,omitempty"`
+	Unreachable map[string]string `json:"unreachable,omitempty"` // réplica -> erro
+}
+
+// OK diz se todas as réplicas alcançadas concordam
+func (r Report) OK() bool { return len(r.Divergences) == 0 }
+
+// ErrTooFewReplicas é retornado quando menos de duas réplicas respondem
+var ErrTooFewReplicas = errors.New("fewer than two replicas answered")
+
+func (c *Checker) transport() network.Transport {
+	if c.Transport == nil {
+		return network.TCP
+	}
+	return c.Transport
+}
+
+func (c *Checker) digest(addr string, req types.DigestRequest) (types.DigestReply, error) {
+	req.Digest = true
+	var rep types.DigestReply
+	err := network.RequestTimeout(c.transport(), addr, req, &rep, c.Timeout)
+	return rep, err
+}
+
+// Check compara as réplicas uma vez
+func (c *Checker) Check() (Report, error) {
+	log := logging.Or(c.Logger).With("component", "antientropy")
+	rep := Report{Unreachable: map[string]string{}}
+	digests := map[string]types.DigestReply{}
+	var alive []string
+	for _, addr := range c.Replicas {
+		d, err := c.digest(addr, types.DigestRequest{})
+		if err != nil {
+			rep.Unreachable[addr] = err.Error()
+			continue
+		}
+		digests[addr] = d
+		alive = append(alive, addr)
+	}
+	if len(alive) < 2 {
+		return rep, ErrTooFewReplicas
+	}
+	rep.Seq = digests[alive[0]].Seq
+	for _, addr := range alive {
+		rep.Seq = min(rep.Seq, digests[addr].Seq)
+	}
+
+	// hash encadeado de cada réplica na sequência comum
+	chains := map[string]string{}
+	for _, addr := range alive {
+		chain := digests[addr].Chain
+		if digests[addr].Seq != rep.Seq {
+			d, err := c.digest(addr, types.DigestRequest{Seq: rep.Seq})
+			if err != nil {
+				rep.Unreachable[addr] = err.Error()
+				continue
+			}
+			chain = d.Chain
+		}
+		if chain == "" {
+			rep.Unreachable[addr] = fmt.Sprintf("no chain hash for seq %d", rep.Seq)
+			continue
+		}
+		chains[addr] = chain
+	}
+
+	// a referência é a maioria; no empate, a que está mais adiantada, já
+	// que uma réplica que perdeu um commit fica para trás
+	groups := map[string][]string{}
+	for _, addr := range alive {
+		if ch, ok := chains[addr]; ok {
+			groups[ch] = append(groups[ch], addr)
+		}
+	}
+	best := ""
+	ahead := func(ch string) uint64 {
+		var s uint64
+		for _, a := range groups[ch] {
+			s = max(s, digests[a].Seq)
+		}
+		return s
+	}
+	for ch, g := range groups {
+		if best == "" || len(g) > len(groups[best]) || len(g) == len(groups[best]) && ahead(ch) > ahead(best) {
+			best = ch
+		}
+	}
+	if best == "" {
+		return rep, ErrTooFewReplicas
+	}
+	ref := groups[best][0]
+	for _, a := range groups[best] {
+		if digests[a].Seq > digests[ref].Seq {
+			ref = a
+		}
+	}
+	rep.Reference = ref
+
+	for _, addr := range alive {
+		ch, ok := chains[addr]
+		if !ok || addr == ref {
+			continue
+		}
+		d := Divergence{Replica: addr, Chain: ch != best}
+		// na mesma sequência e com os mesmos commits, o estado deve ser igual
+		if !d.Chain && (digests[addr].Seq != digests[ref].Seq || digests[addr].Root == digests[ref].Root) {
+			continue
+		}
+		c.compare(&d, ref, digests[ref], digests[addr])
+		rep.Divergences = append(rep.Divergences, d)
+		log.Warn("replica diverged", "replica", addr, "reference", ref, "seq", rep.Seq, "chain", d.Chain, "items", len(d.Items), "repaired", d.Repaired, "err", d.Error)
+	}
+	if len(rep.Unreachable) == 0 {
+		rep.Unreachable = nil
+	}
+	return rep, nil
+}
+
+// compare lista em d as chaves que diferem entre a referência refAddr e
+// d.Replica e, com Repair, repara d.Replica
+func (c *Checker) compare(d *Divergence, refAddr string, ref, target types.DigestReply) {
+	var buckets []int
+	for i := range ref.Buckets {
+		if i >= len(target.Buckets) || ref.Buckets[i] != target.Buckets[i] {
+			buckets = append(buckets, i)
+		}
+	}
+	// referência e alvo no mesmo pedido cada: Seq, Chain e Items consistentes
+	refItems, err := c.digest(refAddr, types.DigestRequest{Buckets: buckets, Decisions: c.Repair})
+	if err != nil {
+		d.Error = err.Error()
+		return
+	}
+	var mine types.DigestReply
+	if len(buckets) > 0 {
+		if mine, err = c.digest(d.Replica, types.DigestRequest{Buckets: buckets}); err != nil {
+			d.Error = err.Error()
+			return
+		}
+	}
+	d.Items = diff(refItems.Items, mine.Items)
+	if !c.Repair {
+		return
+	}
+	req := types.RepairRequest{Repair: true, FromSeq: target.Seq, Seq: refItems.Seq, Chain: refItems.Chain, Buckets: buckets, Items: refItems.Items, Decisions: refItems.Decisions}
+	if len(buckets) > 0 {
+		req.FromSeq = mine.Seq
+	}
+	var out types.RepairReply
+	if err := network.RequestTimeout(c.transport(), d.Replica, req, &out, c.Timeout); err != nil {
+		d.Error = err.Error()
+		return
+	}
+	d.Repaired, d.Error = out.Repaired, out.Error
+}
+
+// diff retorna as chaves presentes em só um lado ou com valor, versão ou
+// tombstone diferentes
+func diff(a, b []types.DigestItem) []string {
+	m := map[string]types.DigestItem{}
+	for _, it := range a {
+		m[it.Item] = it
+	}
+	var out []string
+	for _, it := range b {
+		o, ok := m[it.Item]
+		if !ok || o.Version != it.Version || o.Deleted != it.Deleted || string(o.Value) != string(it.Value) {
+			out = append(out, it.Item)
+		}
+		delete(m, it.Item)
+	}
+	for k := range m {
+		out = append(out, k)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Run verifica a cada interval até ctx acabar, entregando cada resultado a report
+func (c *Checker) Run(ctx context.Context, interval time.Duration, report func(Report, error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		report(c.Check())
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
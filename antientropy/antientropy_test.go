@@ -0,0 +1,123 @@
+package antientropy
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/logging"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/server"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// cluster inicia réplicas num transporte em memória
+func cluster(t *testing.T, addrs ...string) (network.Transport, []*server.Replica) {
+	tr := network.NewMemTransport()
+	var reps []*server.Replica
+	for _, addr := range addrs {
+		rep := server.NewReplica(addr)
+		rep.Transport = tr
+		rep.Logger = logging.Discard
+		if err := rep.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { rep.Shutdown(context.Background()) })
+		reps = append(reps, rep)
+	}
+	return tr, reps
+}
+
+// commit aplica um commit nas réplicas reps, como o sequencer faria
+func commit(tid, item, val string, reps ...*server.Replica) {
+	for _, rep := range reps {
+		rep.Certify(types.CommitRequest{Cid: "c", Tid: tid, Ws: []types.WriteEntry{{Item: item, Value: []byte(val)}}})
+	}
+}
+
+func TestCheckAgreement(t *testing.T) {
+	tr, reps := cluster(t, "r1", "r2", "r3")
+	commit("t1", "a", "1", reps...)
+	// r3 está atrasada, mas não divergente
+	commit("t2", "b", "2", reps[0], reps[1])
+	c := &Checker{Replicas: []string{"r1", "r2", "r3", "r4"}, Transport: tr, Logger: logging.Discard}
+	rep, err := c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.OK() || rep.Seq != 1 || rep.Reference != "r1" {
+		t.Fatalf("Expected agreement at seq 1, got %+v", rep)
+	}
+	if _, ok := rep.Unreachable["r4"]; !ok || len(rep.Unreachable) != 1 {
+		t.Errorf("Expected r4 unreachable, got %v", rep.Unreachable)
+	}
+}
+
+func TestCheckMissedCommit(t *testing.T) {
+	tr, reps := cluster(t, "r1", "r2")
+	commit("t1", "a", "1", reps...)
+	// r2 perde t2, como quando o sequencer não consegue conectar
+	commit("t2", "b", "2", reps[0])
+	commit("t3", "a", "3", reps...)
+	c := &Checker{Replicas: []string{"r1", "r2"}, Transport: tr, Logger: logging.Discard}
+	rep, err := c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no empate, a réplica mais adiantada é a referência
+	if rep.Reference != "r1" || len(rep.Divergences) != 1 {
+		t.Fatalf("Expected r2 to diverge from r1, got %+v", rep)
+	}
+	d := rep.Divergences[0]
+	if d.Replica != "r2" || !d.Chain || !slices.Equal(d.Items, []string{"a", "b"}) || d.Repaired {
+		t.Fatalf("Expected a and b to differ on r2, got %+v", d)
+	}
+
+	c.Repair = true
+	if rep, err = c.Check(); err != nil || len(rep.Divergences) != 1 || !rep.Divergences[0].Repaired {
+		t.Fatalf("Expected r2 repaired, got %+v, %v", rep, err)
+	}
+	// o reparo traz a janela de decisões: t2 reenviado não é reaplicado
+	if st := reps[1].Status(types.StatusRequest{Cid: "c", Tid: "t2"}); !st.Known || st.Decision.Seq != 2 {
+		t.Fatalf("Expected r2 to know t2 from r1, got %+v", st)
+	}
+	commit("t2", "b", "2", reps[1])
+	commit("t4", "b", "4", reps...)
+	c.Repair = false
+	if rep, err = c.Check(); err != nil || !rep.OK() || rep.Seq != 4 {
+		t.Fatalf("Expected agreement after repair, got %+v, %v", rep, err)
+	}
+}
+
+func TestCheckStateDivergence(t *testing.T) {
+	tr, reps := cluster(t, "r1", "r2", "r3")
+	commit("t1", "a", "1", reps...)
+	// mesma sequência e mesmos commits, mas r3 coletou o tombstone de x
+	commit("t2", "x", "", reps...)
+	for _, rep := range reps {
+		rep.Certify(types.CommitRequest{Cid: "c", Tid: "t3", Ws: []types.WriteEntry{{Item: "a", Op: types.OpDelete}}})
+	}
+	reps[2].CollectTombstones(3)
+	// sem o tombstone, r3 certificaria uma leitura de ausência que r1 e r2 abortam
+	c := &Checker{Replicas: []string{"r1", "r2", "r3"}, Transport: tr, Logger: logging.Discard}
+	rep, err := c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Divergences) != 1 || rep.Divergences[0].Replica != "r3" || rep.Divergences[0].Chain || !slices.Equal(rep.Divergences[0].Items, []string{"a"}) {
+		t.Fatalf("Expected the collected tombstone of a to diverge on r3 only, got %+v", rep)
+	}
+	c.Repair = true
+	if rep, err = c.Check(); err != nil || !rep.Divergences[0].Repaired {
+		t.Fatalf("Expected r3 repaired, got %+v, %v", rep, err)
+	}
+	absent := types.CommitRequest{Cid: "c", Tid: "t4", Rs: []types.ReadEntry{{Item: "a", Absent: true}}}
+	for _, r := range reps {
+		if dec := r.Certify(absent); dec.Commit || dec.Reason != types.ReasonStaleRead {
+			t.Errorf("Expected %s to abort the stale absent read of a, got %+v", r.Addr, dec)
+		}
+	}
+	if _, err := (&Checker{Replicas: []string{"r1"}, Transport: tr}).Check(); err != ErrTooFewReplicas {
+		t.Errorf("Expected ErrTooFewReplicas, got %v", err)
+	}
+}
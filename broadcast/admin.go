@@ -0,0 +1,114 @@
+package broadcast
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/hrodric0/dur-impl/admin"
+	"github.com/hrodric0/dur-impl/health"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// track registra req como na fila e retorna a sua chave em inflight
+func (s *Sequencer) track(req types.CommitRequest) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.inflight[s.nextID] = &admin.Inflight{Cid: req.Cid, Tid: req.Tid, State: "queued", Since: time.Now()}
+	return s.nextID
+}
+
+// progress marca o commit id como sendo certificado por replica
+func (s *Sequencer) progress(id uint64, replica string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if in, ok := s.inflight[id]; ok {
+		if in.State != "broadcasting" {
+			in.State, in.Since = "broadcasting", time.Now()
+		}
+		in.Replica = replica
+	}
+}
+
+func (s *Sequencer) untrack(id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inflight, id)
+}
+
+// Inflight retorna os commits recebidos e ainda não respondidos, do mais
+// antigo ao mais novo
+func (s *Sequencer) Inflight() []admin.Inflight {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]uint64, 0, len(s.inflight))
+	for id := range s.inflight {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	out := make([]admin.Inflight, len(ids))
+	for i, id := range ids {
+		out[i] = *s.inflight[id]
+	}
+	return out
+}
+
+// AdminStatus resume o estado do sequencer para GET /admin/status
+func (s *Sequencer) AdminStatus() admin.Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := admin.Status{Role: "sequencer", Addr: s.Addr, LastCommitted: s.lastCommitted, Queue: len(s.queue)}
+	var head uint64
+	for _, r := range s.Replicas {
+		head = max(head, s.replicaSeq[r])
+	}
+	for i, r := range s.Replicas {
+		seq := s.replicaSeq[r]
+		if i == 0 || seq < st.LastApplied {
+			st.LastApplied = seq
+		}
+		st.Members = append(st.Members, admin.Member{Addr: r, Seq: seq, Lag: head - seq})
+	}
+	return st
+}
+
+// Aborts retorna os aborts recentes, do mais novo ao mais antigo
+func (s *Sequencer) Aborts() []admin.Abort { return s.aborts.List() }
+
+// sequencerConfig é a resposta de GET /admin/config
+type sequencerConfig struct {
+	Addr          string   `json:"addr"`
+	Replicas      []string `json:"replicas"`
+	QueueCapacity int      `json:"queueCapacity"`
+	Tracing       bool     `json:"tracing"`
+	Heartbeat     string   `json:"heartbeat,omitempty"` // intervalo do Detector
+}
+
+// Admin é o handler da API de administração:
+//
+//	GET /admin/status    membros, sequências e tamanho da fila
+//	GET /admin/inflight  commits na fila ou em difusão
+//	GET /admin/aborts    aborts recentes, com o motivo
+//	GET /admin/config    configuração do sequencer
+func (s *Sequencer) Admin() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/status", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, s.AdminStatus())
+	})
+	mux.HandleFunc("GET /admin/inflight", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, s.Inflight())
+	})
+	mux.HandleFunc("GET /admin/aborts", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, s.Aborts())
+	})
+	mux.HandleFunc("GET /admin/config", func(w http.ResponseWriter, r *http.Request) {
+		c := sequencerConfig{Addr: s.Addr, Replicas: s.Replicas, QueueCapacity: cap(s.queue), Tracing: s.Tracer != nil}
+		if d := s.Detector; d != nil {
+			c.Heartbeat = cmp.Or(d.Interval, health.DefaultInterval).String()
+		}
+		admin.WriteJSON(w, http.StatusOK, c)
+	})
+	return mux
+}
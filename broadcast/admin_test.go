@@ -0,0 +1,94 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hrodric0/dur-impl/admin"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+func TestSequencerAdmin(t *testing.T) {
+	tr := network.NewMemTransport()
+	release := make(chan struct{})
+	var n uint64
+	rln, _ := tr.Listen("r1")
+	go network.Serve(rln, func(raw []byte, conn net.Conn) {
+		var req types.CommitRequest
+		json.Unmarshal(raw, &req)
+		if req.Tid == "slow" {
+			<-release
+		}
+		n++
+		dec := types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Commit: req.Tid != "bad", Seq: n}
+		if !dec.Commit {
+			dec.Reason, dec.Item = types.ReasonStaleRead, "x"
+		}
+		json.NewEncoder(conn).Encode(dec)
+	})
+	seq := NewSequencer("seq", []string{"r1", "r2"})
+	seq.Transport = tr
+	seq.Start(context.Background())
+	defer seq.Shutdown(context.Background())
+	srv := httptest.NewServer(seq.Admin())
+	defer srv.Close()
+
+	var dec types.CommitDecision
+	network.RequestWith(tr, "seq", types.CommitRequest{Cid: "c", Tid: "bad"}, &dec)
+	var aborts []admin.Abort
+	if err := admin.Get(srv.Client(), srv.URL, "/admin/aborts", &aborts); err != nil {
+		t.Fatal(err)
+	}
+	// r2 não existe: o primeiro motivo, de r1, é o registrado
+	if len(aborts) != 1 || aborts[0].Tid != "bad" || aborts[0].Reason != types.ReasonStaleRead || aborts[0].Item != "x" {
+		t.Fatalf("Expected the stale-read abort, got %+v", aborts)
+	}
+
+	go network.RequestWith(tr, "seq", types.CommitRequest{Cid: "c", Tid: "slow"}, &types.CommitDecision{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		network.RequestWith(tr, "seq", types.CommitRequest{Cid: "c", Tid: "next"}, &types.CommitDecision{})
+	}()
+	var inflight []admin.Inflight
+	deadline := time.Now().Add(2 * time.Second)
+	for len(inflight) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		if err := admin.Get(srv.Client(), srv.URL, "/admin/inflight", &inflight); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []admin.Inflight{{Cid: "c", Tid: "slow", State: "broadcasting", Replica: "r1"}, {Cid: "c", Tid: "next", State: "queued"}}
+	if len(inflight) != 2 {
+		t.Fatalf("Expected 2 in-flight commits, got %+v", inflight)
+	}
+	for i, in := range inflight {
+		in.Since = time.Time{}
+		if in != want[i] {
+			t.Errorf("inflight[%d] = %+v, want %+v", i, in, want[i])
+		}
+	}
+	close(release)
+
+	var st admin.Status
+	if err := admin.Get(srv.Client(), srv.URL, "/admin/status", &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Role != "sequencer" || len(st.Members) != 2 || st.Members[1].Addr != "r2" {
+		t.Errorf("status = %+v", st)
+	}
+	var cfg map[string]any
+	if err := admin.Get(srv.Client(), srv.URL, "/admin/config", &cfg); err != nil || cfg["queueCapacity"] != float64(100) {
+		t.Errorf("config = %v, %v", cfg, err)
+	}
+	resp, _ := http.Get(srv.URL + "/admin/keys/x")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected no key lookup on the sequencer, got %s", resp.Status)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,74 @@
+package broadcast
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hrodric0/dur-impl/health"
+	"github.com/hrodric0/dur-impl/network"
+)
+
+// targets separa as réplicas em disponíveis e suspeitas ou ainda não
+// prontas, segundo o Detector; sem Detector, todas estão disponíveis
+func (s *Sequencer) targets() (up, down []string) {
+	if s.Detector == nil {
+		return s.Replicas, nil
+	}
+	for _, addr := range s.Replicas {
+		if s.Detector.Ready(addr) {
+			up = append(up, addr)
+		} else {
+			down = append(down, addr)
+		}
+	}
+	return up, down
+}
+
+// expected é a sequência que toda réplica disponível já deve ter aplicado:
+// a do último commit confirmado ou, se maior, a de uma réplica pronta
+// segundo o Detector (depois de reiniciar, o sequencer só a conhece assim)
+func (s *Sequencer) expected() uint64 {
+	s.mu.Lock()
+	seq := s.lastCommitted
+	s.mu.Unlock()
+	if s.Detector != nil {
+		for _, p := range s.Detector.Peers() {
+			if p.Up && p.Ready {
+				seq = max(seq, p.Seq)
+			}
+		}
+	}
+	return seq
+}
+
+// unavailable retorna um erro se nenhuma réplica está disponível
+func (s *Sequencer) unavailable() error {
+	up, down := s.targets()
+	if len(up) == 0 {
+		return fmt.Errorf("replicas unavailable: %s", strings.Join(down, ", "))
+	}
+	return nil
+}
+
+// live retorna nil enquanto o sequencer aceita commits
+func (s *Sequencer) live() error {
+	select {
+	case <-s.quit:
+		return network.ErrServerClosed
+	default:
+		return nil
+	}
+}
+
+// Health atende GET /healthz, que responde 200 enquanto o sequencer aceita
+// commits, e GET /readyz, que também exige ao menos uma réplica disponível
+// segundo o Detector
+func (s *Sequencer) Health() http.Handler {
+	return health.Handler(s.live, func() error {
+		if err := s.live(); err != nil {
+			return err
+		}
+		return s.unavailable()
+	})
+}
@@ -0,0 +1,96 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/health"
+	"github.com/hrodric0/dur-impl/logging"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// serveReplica atende pings e confirma todo commit em addr, contando-os e
+// guardando o último After recebido
+func serveReplica(tr *network.MemTransport, addr string, commits, after *atomic.Int64) net.Listener {
+	rln, _ := tr.Listen(addr)
+	go network.Serve(rln, func(raw []byte, conn net.Conn) {
+		var probe map[string]json.RawMessage
+		json.Unmarshal(raw, &probe)
+		if _, isPing := probe["ping"]; isPing {
+			json.NewEncoder(conn).Encode(types.PingReply{Ready: true})
+			return
+		}
+		var req types.CommitRequest
+		json.Unmarshal(raw, &req)
+		after.Store(int64(req.After))
+		n := commits.Add(1)
+		json.NewEncoder(conn).Encode(types.CommitDecision{Commit: true, Seq: uint64(n)})
+	})
+	return rln
+}
+
+func TestSequencerSkipsUnavailableReplicas(t *testing.T) {
+	tr := network.NewMemTransport()
+	var commits, after atomic.Int64
+	ln1 := serveReplica(tr, "r1", &commits, &after)
+	seq := NewSequencer("seq", []string{"r1", "r2"})
+	seq.Transport, seq.Logger = tr, logging.Discard
+	seq.Detector = &health.Detector{Addrs: seq.Replicas, Transport: tr, Misses: 1, Logger: logging.Discard}
+	seq.Start(context.Background())
+	defer seq.Shutdown(context.Background())
+	h := seq.Health()
+	code := func(path string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code
+	}
+
+	// r2 não existe, mas ainda não foi verificada
+	if code("/healthz") != http.StatusOK || code("/readyz") != http.StatusOK {
+		t.Fatalf("Expected live and ready before the first heartbeat")
+	}
+	seq.Detector.Probe()
+	if code("/readyz") != http.StatusOK {
+		t.Errorf("Expected ready with r1 available")
+	}
+	var dec types.CommitDecision
+	network.RequestWith(tr, "seq", types.CommitRequest{Cid: "c", Tid: "t1"}, &dec)
+	if !dec.Commit || commits.Load() != 1 {
+		t.Fatalf("Expected t1 committed on r1 alone, got %+v", dec)
+	}
+
+	// r2 volta e recebe o próximo commit com a sequência que já devia ter
+	var commits2, after2 atomic.Int64
+	ln2 := serveReplica(tr, "r2", &commits2, &after2)
+	seq.Detector.Probe()
+	network.RequestWith(tr, "seq", types.CommitRequest{Cid: "c", Tid: "t2"}, &dec)
+	if !dec.Commit || commits.Load() != 2 || commits2.Load() != 1 {
+		t.Errorf("Expected t2 committed on both replicas, got %+v (r1 %d, r2 %d)", dec, commits.Load(), commits2.Load())
+	}
+	if after2.Load() != 1 {
+		t.Errorf("Expected r2 asked to be after seq 1, got %d", after2.Load())
+	}
+
+	// sem nenhuma réplica disponível, o sequencer não está pronto e aborta
+	ln1.Close()
+	ln2.Close()
+	seq.Detector.Probe()
+	if code("/readyz") != http.StatusServiceUnavailable {
+		t.Errorf("Expected not ready with every replica suspected")
+	}
+	network.RequestWith(tr, "seq", types.CommitRequest{Cid: "c", Tid: "t3"}, &dec)
+	if dec.Commit || dec.Reason != types.ReasonUnavailable || dec.Detail != "replicas unavailable: r1, r2" {
+		t.Errorf("Expected abort naming r1 and r2, got %+v", dec)
+	}
+
+	seq.Shutdown(context.Background())
+	if code("/healthz") != http.StatusServiceUnavailable {
+		t.Errorf("Expected not live after Shutdown")
+	}
+}
@@ -0,0 +1,64 @@
+package broadcast
+
+import (
+	"time"
+
+	"github.com/hrodric0/dur-impl/metrics"
+)
+
+// sequencerMetrics são as métricas expostas pelo sequencer
+type sequencerMetrics struct {
+	reg        *metrics.Registry
+	commits    *metrics.Counter
+	aborts     *metrics.Counter
+	broadcast  *metrics.Histogram
+	queueWait  *metrics.Histogram
+	replica    *metrics.Histogram
+	replicaSeq *metrics.Gauge
+	replicaLag *metrics.Gauge
+}
+
+func newSequencerMetrics(s *Sequencer) *sequencerMetrics {
+	reg := metrics.NewRegistry()
+	reg.GaugeFunc("dur_sequencer_queue_depth", "Commit requests waiting to be broadcast.", func() float64 {
+		return float64(len(s.queue))
+	})
+	reg.GaugeFunc("dur_sequencer_queue_capacity", "Capacity of the commit queue.", func() float64 {
+		return float64(cap(s.queue))
+	})
+	return &sequencerMetrics{
+		reg:        reg,
+		commits:    reg.Counter("dur_sequencer_commits_total", "Transactions committed by every replica."),
+		aborts:     reg.Counter("dur_sequencer_aborts_total", "Transactions aborted, by the first reason reported.", "reason"),
+		broadcast:  reg.Histogram("dur_sequencer_broadcast_seconds", "Time to send a commit request to every replica and aggregate the decisions.", metrics.DefBuckets),
+		queueWait:  reg.Histogram("dur_sequencer_queue_wait_seconds", "Time a commit request waited in the queue.", metrics.DefBuckets),
+		replica:    reg.Histogram("dur_sequencer_replica_seconds", "Round trip of a commit request to one replica.", metrics.DefBuckets, "replica"),
+		replicaSeq: reg.Gauge("dur_sequencer_replica_seq", "Last sequence number a replica committed.", "replica"),
+		replicaLag: reg.Gauge("dur_sequencer_replica_lag", "Sequence numbers a replica is behind the most advanced one.", "replica"),
+	}
+}
+
+// decided conta a decisão agregada de um broadcast iniciado em start
+func (m *sequencerMetrics) decided(reason string, start time.Time) {
+	m.broadcast.Observe(time.Since(start).Seconds())
+	if reason == "" {
+		m.commits.Inc()
+	} else {
+		m.aborts.Inc(reason)
+	}
+}
+
+// lag atualiza a sequência e o atraso de cada réplica
+func (m *sequencerMetrics) lag(replicas []string, seqs map[string]uint64) {
+	var head uint64
+	for _, r := range replicas {
+		head = max(head, seqs[r])
+	}
+	for _, r := range replicas {
+		m.replicaSeq.Set(float64(seqs[r]), r)
+		m.replicaLag.Set(float64(head-seqs[r]), r)
+	}
+}
+
+// Metrics retorna o registro de métricas do sequencer, para expor em /metrics
+func (s *Sequencer) Metrics() *metrics.Registry { return s.metrics.reg }
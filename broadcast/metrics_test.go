@@ -0,0 +1,61 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+func TestSequencerMetrics(t *testing.T) {
+	tr := network.NewMemTransport()
+	// r1 acompanha; r2 ficou parada na sequência 1
+	seqs := map[string]func(n uint64) uint64{"r1": func(n uint64) uint64 { return n }, "r2": func(uint64) uint64 { return 1 }}
+	for addr, seqOf := range seqs {
+		ln, _ := tr.Listen(addr)
+		var n uint64
+		go network.Serve(ln, func(raw []byte, conn net.Conn) {
+			var req types.CommitRequest
+			json.Unmarshal(raw, &req)
+			n++
+			dec := types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Commit: req.Tid != "bad", Seq: seqOf(n)}
+			if !dec.Commit {
+				dec.Reason = types.ReasonStaleRead
+			}
+			json.NewEncoder(conn).Encode(dec)
+		})
+	}
+	seq := NewSequencer("seq", []string{"r1", "r2"})
+	seq.Transport = tr
+	seq.Start(context.Background())
+	defer seq.Shutdown(context.Background())
+	for i := 0; i < 3; i++ {
+		var dec types.CommitDecision
+		network.RequestWith(tr, "seq", types.CommitRequest{Cid: "c", Tid: fmt.Sprint(i)}, &dec)
+	}
+	var dec types.CommitDecision
+	network.RequestWith(tr, "seq", types.CommitRequest{Cid: "c", Tid: "bad"}, &dec)
+
+	var b strings.Builder
+	seq.Metrics().WriteText(&b)
+	for _, want := range []string{
+		"dur_sequencer_commits_total 3\n",
+		`dur_sequencer_aborts_total{reason="stale-read"} 1` + "\n",
+		"dur_sequencer_broadcast_seconds_count 4\n",
+		`dur_sequencer_replica_seconds_count{replica="r2"} 4` + "\n",
+		"dur_sequencer_queue_depth 0\n",
+		"dur_sequencer_queue_capacity 100\n",
+		`dur_sequencer_replica_seq{replica="r1"} 3` + "\n",
+		`dur_sequencer_replica_lag{replica="r1"} 0` + "\n",
+		`dur_sequencer_replica_lag{replica="r2"} 2` + "\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, b.String())
+		}
+	}
+}
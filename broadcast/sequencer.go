@@ -1,69 +1,338 @@
 package broadcast
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/hrodric0/dur-impl/admin"
+	"github.com/hrodric0/dur-impl/health"
+	"github.com/hrodric0/dur-impl/logging"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/trace"
 	"github.com/hrodric0/dur-impl/types"
 )
 
+// Sequencer ordena os CommitRequests recebidos e os difunde, um por vez,
+// às réplicas disponíveis, respondendo ao cliente com a decisão agregada
+// das que certificaram. Crie com NewSequencer.
+type Sequencer struct {
+	Addr     string
+	Replicas []string
+	// Transport usado para escutar e falar com as réplicas; nil usa TCP
+	Transport network.Transport
+	// Tracer, se não for nil, registra a espera na fila, a conexão e a ida
+	// e volta a cada réplica e a resposta ao cliente, como filhos do
+	// TraceContext do CommitRequest
+	Tracer *trace.Tracer
+	// Detector, se não for nil, acompanha as réplicas por heartbeat: as
+	// que não respondem ou não estão prontas são puladas, sem esperar por
+	// elas, e alcançam as demais quando voltam. Deve ser iniciado com
+	// Start pelo dono.
+	Detector *health.Detector
+	// ReplicaTimeout limita a ida e volta a cada réplica: uma réplica que
+	// aceita a conexão e não responde no prazo é pulada nesse commit, em
+	// vez de bloquear o sequencer. Zero usa DefaultReplicaTimeout.
+	ReplicaTimeout time.Duration
+	// Logger recebe os eventos do sequencer; nil usa slog.Default(). Cada
+	// commit é registrado em Debug, falhas das réplicas em Warn.
+	Logger *slog.Logger
+
+	server  network.Server
+	metrics *sequencerMetrics
+	queue   chan pending
+	quit    chan struct{} // fechado no fim de Shutdown
+	exited  chan struct{} // fechado quando o processador termina
+	once    sync.Once
+	stop    sync.Once
+	// mu protege o estado lido pela API de administração; só o processador
+	// escreve replicaSeq e lastCommitted
+	mu sync.Mutex
+	// replicaSeq é a última sequência confirmada por cada réplica
+	replicaSeq    map[string]uint64
+	lastCommitted uint64
+	inflight      map[uint64]*admin.Inflight
+	nextID        uint64
+	aborts        *admin.Aborts
+}
+
+// DefaultReplicaTimeout é o ReplicaTimeout padrão
+const DefaultReplicaTimeout = 5 * time.Second
+
+// pending é um commit na fila; done é fechado depois da resposta ao cliente
+type pending struct {
+	id     uint64 // chave em inflight
+	req    types.CommitRequest
+	conn   net.Conn
+	at     time.Time // entrada na fila
+	span   *trace.Span
+	queued *trace.Span
+	done   chan struct{}
+}
+
+// NewSequencer cria um sequencer para addr que difunde a replicaAddrs
+func NewSequencer(addr string, replicaAddrs []string) *Sequencer {
+	s := &Sequencer{
+		Addr:       addr,
+		Replicas:   replicaAddrs,
+		queue:      make(chan pending, 100),
+		quit:       make(chan struct{}),
+		exited:     make(chan struct{}),
+		replicaSeq: make(map[string]uint64),
+		inflight:   make(map[uint64]*admin.Inflight),
+		aborts:     admin.NewAborts(admin.DefaultAborts),
+	}
+	s.server.Handler = s.handle
+	s.metrics = newSequencerMetrics(s)
+	return s
+}
+
 // StartSequencer implementa broadcast atômico com ordenação FIFO garantida.
 func StartSequencer(listenAddr string, replicaAddrs []string) error {
-	ln, err := net.Listen("tcp", listenAddr)
+	return StartSequencerWith(network.TCP, listenAddr, replicaAddrs)
+}
+
+// StartSequencerWith é StartSequencer sobre o transporte tr
+func StartSequencerWith(tr network.Transport, listenAddr string, replicaAddrs []string) error {
+	ln, err := tr.Listen(listenAddr)
 	if err != nil {
 		return err
 	}
-	// Canal para requisições recebidas
-	type reqConn struct {
-		req  types.CommitRequest
-		conn net.Conn
-	}
-	ch := make(chan reqConn, 100)
-
-	// Processador sequencial de commits
-	go func() {
-		for rc := range ch {
-			r := rc.req
-			timeSpent := log.Printf // alias para evitar import cycl
-			timeSpent("[Sequencer] Processando CommitRequest cid=%s tid=%s", r.Cid, r.Tid)
-			agg := true
-			for _, addr := range replicaAddrs {
-				log.Printf("[Sequencer] Enviando a réplica %s", addr)
-				conn2, err := net.Dial("tcp", addr)
-				if err != nil {
-					log.Printf("[Sequencer] falha conectar %s: %v", addr, err)
-					agg = false
-					continue
-				}
-				json.NewEncoder(conn2).Encode(r)
-				var dec types.CommitDecision
-				json.NewDecoder(conn2).Decode(&dec)
-				log.Printf("[Sequencer] Decisão da réplica %s -> %v", addr, dec.Commit)
-				if !dec.Commit {
-					agg = false
-				}
-				conn2.Close()
-			}
-			// Retorna decisão ao cliente
-			out := types.CommitDecision{Cid: r.Cid, Tid: r.Tid, Commit: agg}
-			json.NewEncoder(rc.conn).Encode(out)
-			rc.conn.Close()
-		}
-	}()
+	return ServeSequencer(ln, tr, replicaAddrs)
+}
+
+// ServeSequencer atende CommitRequests recebidos em ln e os difunde às réplicas via tr
+func ServeSequencer(ln net.Listener, tr network.Transport, replicaAddrs []string) error {
+	s := NewSequencer(ln.Addr().String(), replicaAddrs)
+	s.Transport = tr
+	return s.Serve(ln)
+}
 
-	// Aceita conexões e envia para canal
+func (s *Sequencer) transport() network.Transport {
+	if s.Transport == nil {
+		return network.TCP
+	}
+	return s.Transport
+}
+
+func (s *Sequencer) replicaTimeout() time.Duration {
+	if s.ReplicaTimeout <= 0 {
+		return DefaultReplicaTimeout
+	}
+	return s.ReplicaTimeout
+}
+
+// init inicia o processador sequencial de commits, uma vez
+func (s *Sequencer) init() {
+	s.once.Do(func() { go s.run() })
+}
+
+// Start escuta s.Addr e atende em segundo plano. Quando ctx acaba, o
+// sequencer é encerrado como em Shutdown, sem prazo.
+func (s *Sequencer) Start(ctx context.Context) error {
+	ln, err := s.transport().Listen(s.Addr)
+	if err != nil {
+		return err
+	}
+	s.init()
+	go s.Serve(ln)
+	context.AfterFunc(ctx, func() { s.Shutdown(context.Background()) })
+	return nil
+}
+
+// Serve atende CommitRequests recebidos em ln até Shutdown
+func (s *Sequencer) Serve(ln net.Listener) error {
+	s.init()
+	s.logger().Info("sequencer listening", "replicas", s.Replicas)
+	return s.server.Serve(ln)
+}
+
+// logger é o Logger do sequencer, identificado pelo endereço
+func (s *Sequencer) logger() *slog.Logger {
+	return logging.Or(s.Logger).With("component", "sequencer", "sequencer", s.Addr)
+}
+
+// Shutdown para de aceitar commits, espera os já enfileirados serem
+// difundidos e respondidos e encerra o processador. Se ctx acabar antes,
+// fecha as conexões restantes e retorna o erro de ctx.
+func (s *Sequencer) Shutdown(ctx context.Context) error {
+	s.init()
+	err := s.server.Shutdown(ctx)
+	s.stop.Do(func() { close(s.quit) })
+	if err != nil {
+		return err
+	}
+	select {
+	case <-s.exited:
+		s.logger().Info("sequencer stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handle enfileira o commit e espera a resposta ao cliente, para que
+// Shutdown saiba quais commits ainda estão em andamento
+func (s *Sequencer) handle(raw []byte, conn net.Conn) {
+	var req types.CommitRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return
+	}
+	span := s.Tracer.Start(req.Trace, "sequencer.commit")
+	defer span.End()
+	p := pending{id: s.track(req), req: req, conn: conn, at: time.Now(), span: span, queued: span.Child("sequencer.queue"), done: make(chan struct{})}
+	defer s.untrack(p.id)
+	select {
+	case s.queue <- p:
+	case <-s.quit:
+		span.Fail(network.ErrServerClosed)
+		return
+	}
+	select {
+	case <-p.done:
+	case <-s.quit:
+	}
+}
+
+// run é o processador sequencial de commits
+func (s *Sequencer) run() {
+	defer close(s.exited)
 	for {
-		conn, err := ln.Accept()
+		select {
+		case <-s.quit:
+			return
+		case p := <-s.queue:
+			s.metrics.queueWait.Observe(time.Since(p.at).Seconds())
+			p.queued.End()
+			start := time.Now()
+			dec := s.broadcast(p)
+			s.metrics.decided(dec.Reason, start)
+			p.span.SetAttr("commit", strconv.FormatBool(dec.Commit))
+			reply := p.span.Child("sequencer.reply")
+			reply.Fail(json.NewEncoder(p.conn).Encode(dec))
+			reply.End()
+			close(p.done)
+		}
+	}
+}
+
+// broadcast envia o commit de p a cada réplica disponível, em ordem, e
+// agrega as decisões das que certificaram; uma réplica que falha é pulada,
+// como as suspeitas, e alcança as demais depois. Cada réplica vira um span
+// filho de p.span.
+func (s *Sequencer) broadcast(p pending) types.CommitDecision {
+	r, span := p.req, p.span
+	log := s.logger().With("cid", r.Cid, "tid", r.Tid)
+	targets, skipped := s.targets()
+	if len(skipped) > 0 {
+		log.Debug("skipping unavailable replicas", "replicas", skipped)
+		span.SetAttr("skipped", strings.Join(skipped, ","))
+	}
+	if len(targets) == 0 {
+		out := types.CommitDecision{Cid: r.Cid, Tid: r.Tid, Reason: types.ReasonUnavailable, Detail: "replicas unavailable: " + strings.Join(skipped, ", ")}
+		log.Warn("commit skipped", "err", out.Detail)
+		s.aborts.Add(admin.Abort{At: time.Now(), Cid: r.Cid, Tid: r.Tid, Reason: out.Reason, Detail: out.Detail})
+		return out
+	}
+	// uma réplica atrás desta sequência perdeu commits: recusa e alcança as demais
+	r.After = s.expected()
+	log.Debug("broadcasting commit", "replicas", len(targets), "after", r.After)
+	tr := s.transport()
+	agg, certified := true, 0
+	var seq uint64
+	// o primeiro motivo de abort é repassado ao cliente; lost é a primeira
+	// falha, usada se nenhuma réplica certificar
+	var why, lost types.CommitDecision
+	for _, addr := range targets {
+		sent := time.Now()
+		s.progress(p.id, addr)
+		rspan := span.Child("sequencer.replica")
+		rspan.SetAttr("replica", addr)
+		dec, err := s.send(tr, addr, r, rspan)
+		if err == nil && dec.Reason == types.ReasonUnavailable {
+			err = errors.New(dec.Detail)
+		}
 		if err != nil {
+			rspan.Fail(err)
+			rspan.End()
+			log.Warn("replica failed", "replica", addr, "err", err)
+			if lost.Reason == "" {
+				lost = types.CommitDecision{Reason: types.ReasonUnavailable, Detail: fmt.Sprintf("replica %s: %v", addr, err)}
+			}
 			continue
 		}
-		var req types.CommitRequest
-		if err := json.NewDecoder(conn).Decode(&req); err != nil {
-			conn.Close()
-			continue
+		log.Debug("replica decision", "replica", addr, "commit", dec.Commit, "seq", dec.Seq, "reason", dec.Reason)
+		s.metrics.replica.Observe(time.Since(sent).Seconds(), addr)
+		rspan.SetAttr("commit", strconv.FormatBool(dec.Commit))
+		if dec.Reason != "" {
+			rspan.SetAttr("reason", dec.Reason)
+		}
+		rspan.End()
+		certified++
+		if dec.Commit {
+			s.mu.Lock()
+			s.replicaSeq[addr] = dec.Seq
+			s.mu.Unlock()
+			if seq != 0 && dec.Seq != seq {
+				log.Error("replicas committed at different seqs", "replica", addr, "seq", dec.Seq, "other_seq", seq)
+			}
+			seq = max(seq, dec.Seq)
+		} else {
+			agg = false
+			if why.Reason == "" {
+				why = dec
+			}
 		}
-		// Enfileira para processamento ordenado
-		ch <- reqConn{req: req, conn: conn}
 	}
+	s.metrics.lag(s.Replicas, s.replicaSeq)
+	// Retorna decisão ao cliente
+	out := types.CommitDecision{Cid: r.Cid, Tid: r.Tid, Commit: agg && certified > 0}
+	switch {
+	case out.Commit:
+		out.Seq = seq
+		log.Debug("commit", "seq", seq)
+		s.mu.Lock()
+		s.lastCommitted = max(s.lastCommitted, seq)
+		s.mu.Unlock()
+		return out
+	case certified == 0:
+		// nenhuma decisão: alguma réplica pode ter aplicado, e o cliente
+		// reenvia o mesmo Tid
+		why = lost
+	}
+	out.Reason, out.Item, out.Detail = why.Reason, why.Item, why.Detail
+	log.Debug("abort", "reason", out.Reason, "item", out.Item)
+	s.aborts.Add(admin.Abort{At: time.Now(), Cid: r.Cid, Tid: r.Tid, Reason: out.Reason, Item: out.Item, Detail: out.Detail})
+	return out
+}
+
+// send encaminha r a addr, com prazo ReplicaTimeout, e retorna a decisão
+// da réplica
+func (s *Sequencer) send(tr network.Transport, addr string, r types.CommitRequest, rspan *trace.Span) (types.CommitDecision, error) {
+	dial := rspan.Child("sequencer.dial")
+	conn, err := tr.Dial(addr)
+	dial.Fail(err)
+	dial.End()
+	if err != nil {
+		return types.CommitDecision{}, err
+	}
+	defer conn.Close()
+	if rspan != nil {
+		// sem Tracer, a réplica continua o trace do cliente
+		r.Trace = rspan.Context()
+	}
+	conn.SetDeadline(network.ClockOf(tr).Now().Add(s.replicaTimeout()))
+	var dec types.CommitDecision
+	if err := json.NewEncoder(conn).Encode(r); err != nil {
+		return dec, err
+	}
+	err = json.NewDecoder(conn).Decode(&dec)
+	return dec, err
 }
@@ -1,11 +1,15 @@
 package broadcast
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/hrodric0/dur-impl/network"
 	"github.com/hrodric0/dur-impl/types"
 )
 
@@ -24,7 +28,7 @@ func TestSequencerFIFO(t *testing.T) {
 	time.Sleep(10 * time.Millisecond)
 	// start sequencer
 	seqLn, _ := net.Listen("tcp", "localhost:0")
-	go StartSequencer(seqLn.Addr().String(), []string{ln.Addr().String()})
+	go ServeSequencer(seqLn, network.TCP, []string{ln.Addr().String()})
 	time.Sleep(10 * time.Millisecond)
 
 	// send two requests
@@ -40,3 +44,77 @@ func TestSequencerFIFO(t *testing.T) {
 		t.Errorf("Expected FIFO order, got %v then %v", d1.Tid, d2.Tid)
 	}
 }
+
+func TestSequencerShutdownDrainsQueue(t *testing.T) {
+	tr := network.NewMemTransport()
+	release := make(chan struct{})
+	rln, _ := tr.Listen("r1")
+	go network.Serve(rln, func(raw []byte, conn net.Conn) {
+		var req types.CommitRequest
+		json.Unmarshal(raw, &req)
+		<-release
+		json.NewEncoder(conn).Encode(types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Commit: true, Seq: 1})
+	})
+	seq := NewSequencer("seq", []string{"r1"})
+	seq.Transport = tr
+	if err := seq.Start(context.Background()); err != nil {
+		t.Fatalf("Start error: %v", err)
+	}
+
+	const n = 3
+	decisions := make(chan types.CommitDecision, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			var dec types.CommitDecision
+			network.RequestWith(tr, "seq", types.CommitRequest{Cid: "c", Tid: fmt.Sprint(i)}, &dec)
+			decisions <- dec
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	shut := make(chan error, 1)
+	go func() { shut <- seq.Shutdown(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	var dec types.CommitDecision
+	if err := network.RequestWith(tr, "seq", types.CommitRequest{Cid: "late"}, &dec); !errors.Is(err, network.ErrConnRefused) {
+		t.Errorf("Expected commits refused during shutdown, got %v", err)
+	}
+	close(release)
+	for i := 0; i < n; i++ {
+		if d := <-decisions; !d.Commit {
+			t.Errorf("Expected queued commit %s to be answered, got %+v", d.Tid, d)
+		}
+	}
+	if err := <-shut; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+}
+
+func TestSequencerReplicaTimeout(t *testing.T) {
+	tr := network.NewMemTransport()
+	hung := make(chan struct{})
+	defer close(hung)
+	ln1, _ := tr.Listen("r1")
+	go network.Serve(ln1, func(raw []byte, conn net.Conn) { <-hung })
+	ln2, _ := tr.Listen("r2")
+	go network.Serve(ln2, func(raw []byte, conn net.Conn) {
+		var req types.CommitRequest
+		json.Unmarshal(raw, &req)
+		json.NewEncoder(conn).Encode(types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Commit: true, Seq: 1})
+	})
+	seq := NewSequencer("seq", []string{"r1", "r2"})
+	seq.Transport, seq.ReplicaTimeout = tr, 20*time.Millisecond
+	seq.Start(context.Background())
+	defer seq.Shutdown(context.Background())
+
+	// r1 aceita e nunca responde; cada commit espera no máximo o prazo e
+	// é decidido por r2
+	for _, tid := range []string{"t1", "t2"} {
+		var dec types.CommitDecision
+		if err := network.RequestTimeout(tr, "seq", types.CommitRequest{Cid: "c", Tid: tid}, &dec, time.Second); err != nil {
+			t.Fatalf("Expected a decision for %s, got %v", tid, err)
+		}
+		if !dec.Commit {
+			t.Errorf("Expected %s committed by r2, got %+v", tid, dec)
+		}
+	}
+}
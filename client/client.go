@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hrodric0/dur-impl/history"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/trace"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// Config configura um Client
+type Config struct {
+	Cid           string // identificador do cliente; gerado se vazio
+	Replicas      []string
+	Sequencer     string
+	Transport     network.Transport // nil usa TCP
+	Selector      Selector          // nil usa First
+	ReadTimeout   time.Duration
+	CommitTimeout time.Duration // zero usa DefaultCommitTimeout
+	Recorder      *history.Recorder
+	Retry         RetryPolicy
+	Tracer        *trace.Tracer // nil desliga o tracing
+	Logger        *slog.Logger  // nil usa slog.Default()
+}
+
+// Client é uma sessão de longa duração: guarda configuração, transporte e
+// membership, gera Tids únicos e oferece as garantias de sessão
+// read-your-writes e monotonic reads entre transações consecutivas.
+type Client struct {
+	cfg    Config
+	prefix string
+
+	mu       sync.Mutex
+	next     uint64
+	replicas []string
+	session  uint64
+}
+
+// New cria um Client a partir de cfg
+func New(cfg Config) *Client {
+	if cfg.Cid == "" {
+		cfg.Cid = "c-" + NewTid()
+	}
+	return &Client{cfg: cfg, prefix: NewTid()[:8], replicas: append([]string(nil), cfg.Replicas...)}
+}
+
+// Cid retorna o identificador do cliente
+func (c *Client) Cid() string { return c.cfg.Cid }
+
+// Replicas retorna a membership atual
+func (c *Client) Replicas() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.replicas...)
+}
+
+// SetReplicas troca a membership usada pelas próximas transações
+func (c *Client) SetReplicas(replicas []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replicas = append([]string(nil), replicas...)
+}
+
+// Session retorna a maior sequência observada pela sessão; toda transação
+// nova lê de réplicas que já a aplicaram
+func (c *Client) Session() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session
+}
+
+func (c *Client) observe(seq uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if seq > c.session {
+		c.session = seq
+	}
+}
+
+func (c *Client) nextTid() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next++
+	return fmt.Sprintf("%s-%d", c.prefix, c.next)
+}
+
+// Begin inicia uma transação com Tid único e o snapshot mínimo da sessão
+func (c *Client) Begin() *Transaction {
+	tid := c.nextTid()
+	replicas := c.Replicas()
+	session := c.Session()
+
+	tx := NewTransaction(c.cfg.Cid, tid, replicas, c.cfg.Sequencer)
+	tx.Transport = c.cfg.Transport
+	tx.Selector = c.cfg.Selector
+	tx.ReadTimeout = c.cfg.ReadTimeout
+	tx.CommitTimeout = c.cfg.CommitTimeout
+	tx.Recorder = c.cfg.Recorder
+	tx.Snapshot = session
+	tx.Tracer = c.cfg.Tracer
+	tx.Logger = c.cfg.Logger
+	tx.observe = c.observe
+	tx.begin = c.Begin
+	return tx
+}
+
+// QueryStatus pergunta às réplicas a decisão da transação tid deste cliente
+func (c *Client) QueryStatus(tid string) (types.StatusReply, error) {
+	tx := &Transaction{Cid: c.cfg.Cid, Tid: tid, Replicas: c.Replicas(), Transport: c.cfg.Transport, Selector: c.cfg.Selector, ReadTimeout: c.cfg.ReadTimeout, Logger: c.cfg.Logger}
+	return tx.QueryStatus()
+}
+
+// Run executa fn com RunTransaction, usando a política de retry do Client
+func (c *Client) Run(ctx context.Context, fn func(tx *Transaction) error) (*Transaction, error) {
+	return RunTransaction(ctx, &Transaction{begin: c.Begin}, c.cfg.Retry, fn)
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/network"
+)
+
+func TestClientUniqueTids(t *testing.T) {
+	c := New(Config{Replicas: []string{"r1"}, Sequencer: "seq"})
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				tx := c.Begin()
+				mu.Lock()
+				if seen[tx.Tid] {
+					t.Errorf("duplicate tid %s", tx.Tid)
+				}
+				seen[tx.Tid] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if other := New(Config{}).Begin(); seen[other.Tid] || other.Cid == c.Cid() {
+		t.Errorf("Expected distinct ids across clients, got cid=%s tid=%s", other.Cid, other.Tid)
+	}
+}
+
+func TestClientSessionGuarantees(t *testing.T) {
+	tr := network.NewMemTransport()
+	fakeReplica(t, tr, "old", 2, "old")
+	fakeReplica(t, tr, "new", 5, "new")
+	fakeSequencer(t, tr, "seq", 0)
+
+	c := New(Config{Replicas: []string{"new", "old"}, Sequencer: "seq", Transport: tr, Selector: RoundRobin()})
+	tx := c.Begin()
+	if v, err := tx.Read("x"); err != nil || string(v) != "new" {
+		t.Fatalf("Expected first read from new, got %s err=%v", v, err)
+	}
+	if c.Session() != 5 {
+		t.Fatalf("Expected session at 5, got %d", c.Session())
+	}
+	// monotonic reads: a próxima transação prefere "old", mas ele está atrás da sessão
+	tx2 := c.Begin()
+	if tx2.Snapshot != 5 {
+		t.Fatalf("Expected new transaction to start at session 5, got %d", tx2.Snapshot)
+	}
+	if v, err := tx2.Read("x"); err != nil || string(v) != "new" {
+		t.Fatalf("Expected lagging replica to be skipped, got %s err=%v", v, err)
+	}
+
+	c.SetReplicas([]string{"old"})
+	if _, err := c.Begin().Read("x"); err == nil {
+		t.Errorf("Expected read to fail when only replicas behind the session remain")
+	}
+
+	if _, err := c.Run(context.Background(), func(tx *Transaction) error {
+		if tx.Snapshot != 5 {
+			t.Errorf("Expected Run attempts to start at the session, got %d", tx.Snapshot)
+		}
+		tx.Write("x", []byte("v"))
+		return nil
+	}); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+}
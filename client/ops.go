@@ -0,0 +1,44 @@
+package client
+
+import (
+	"github.com/hrodric0/dur-impl/history"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// Increment soma delta ao contador item no commit. A chave não entra no rs,
+// então incrementos concorrentes comutam e não abortam uns aos outros;
+// uma chave ausente vale zero.
+func (tx *Transaction) Increment(item string, delta int64) error {
+	return tx.stage(types.WriteEntry{Item: item, Op: types.OpIncrement, Delta: delta})
+}
+
+// Append concatena b ao valor de item no commit, sem entrar no rs
+func (tx *Transaction) Append(item string, b []byte) error {
+	return tx.stage(types.WriteEntry{Item: item, Op: types.OpAppend, Value: b})
+}
+
+// SetAdd inclui members no conjunto item (um array JSON) no commit, sem entrar no rs
+func (tx *Transaction) SetAdd(item string, members ...string) error {
+	return tx.stage(types.WriteEntry{Item: item, Op: types.OpSet, Add: members})
+}
+
+// SetRemove retira members do conjunto item no commit, sem entrar no rs
+func (tx *Transaction) SetRemove(item string, members ...string) error {
+	return tx.stage(types.WriteEntry{Item: item, Op: types.OpSet, Remove: members})
+}
+
+// stage combina we com a escrita já pendente em item; falha com
+// types.ErrBadOperand se as duas não se combinam
+func (tx *Transaction) stage(we types.WriteEntry) error {
+	if prev, ok := tx.Ws[we.Item]; ok {
+		merged, err := types.Merge(prev, we)
+		if err != nil {
+			return err
+		}
+		we = merged
+	}
+	tx.logger().Debug("write", "item", we.Item, "op", we.Op)
+	tx.Ws[we.Item] = we
+	tx.record(history.Event{Kind: history.KindWrite, Item: we.Item, Value: we.Value})
+	return nil
+}
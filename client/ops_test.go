@@ -0,0 +1,50 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+func TestOpsStayOutOfReadSet(t *testing.T) {
+	tx := NewTransaction("c1", "t1", nil, "")
+	tx.Increment("n", 1)
+	tx.Increment("n", 4)
+	tx.Append("log", []byte("a"))
+	tx.Append("log", []byte("b"))
+	tx.SetAdd("tags", "x", "y")
+	tx.SetRemove("tags", "x")
+	if len(tx.Rs) != 0 {
+		t.Fatalf("Expected empty rs, got %+v", tx.Rs)
+	}
+	if we := tx.Ws["n"]; we.Op != types.OpIncrement || we.Delta != 5 {
+		t.Errorf("Expected merged increment of 5, got %+v", we)
+	}
+	if we := tx.Ws["log"]; string(we.Value) != "ab" {
+		t.Errorf("Expected merged append 'ab', got %+v", we)
+	}
+	if err := tx.Append("n", []byte("x")); !errors.Is(err, types.ErrBadOperand) {
+		t.Errorf("Expected ErrBadOperand mixing ops, got %v", err)
+	}
+}
+
+// TestReadAppliesPendingOp: ler uma chave com operação pendente lê o valor
+// da réplica (entrando no rs) e aplica a operação sobre ele
+func TestReadAppliesPendingOp(t *testing.T) {
+	tr := network.NewMemTransport()
+	fakeReplica(t, tr, "r", 1, "10")
+	tx := NewTransaction("c1", "t1", []string{"r"}, "")
+	tx.Transport = tr
+	tx.Increment("n", 5)
+	if v, err := tx.Read("n"); err != nil || string(v) != "15" {
+		t.Fatalf("Expected 15, got %s err=%v", v, err)
+	}
+	if re, ok := tx.Rs["n"]; !ok || string(re.Value) != "10" {
+		t.Errorf("Expected the remote read in rs, got %+v", tx.Rs)
+	}
+	if got, _ := tx.ReadMany("n"); string(got["n"]) != "15" {
+		t.Errorf("Expected ReadMany to apply the op too, got %q", got["n"])
+	}
+}
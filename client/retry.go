@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mrand "math/rand"
+	"time"
+
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// ErrTooManyAborts é retornado quando todas as tentativas abortaram por
+// conflito ou indisponibilidade
+var ErrTooManyAborts = errors.New("transaction aborted too many times")
+
+// AbortError é retornado por RunTransaction quando o abort não se resolve
+// reexecutando nem reenviando, como uma precondition falha ou um operando
+// inválido
+type AbortError struct {
+	Decision types.CommitDecision
+}
+
+func (e *AbortError) Error() string {
+	d := e.Decision
+	return fmt.Sprintf("transaction %s aborted: %s %s: %s", d.Tid, d.Reason, d.Item, d.Detail)
+}
+
+// RetryPolicy controla as reexecuções de RunTransaction
+type RetryPolicy struct {
+	MaxAttempts int           // total de tentativas, incluindo a primeira
+	BaseBackoff time.Duration // espera máxima antes da segunda tentativa
+	MaxBackoff  time.Duration // teto da espera exponencial
+}
+
+// DefaultRetryPolicy é usada pelos campos zerados de uma RetryPolicy
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, BaseBackoff: 10 * time.Millisecond, MaxBackoff: time.Second}
+
+// NewTid gera um identificador de transação aleatório
+func NewTid() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// derive cria uma transação vazia com a configuração de tx e o Tid tid
+func (tx *Transaction) derive(tid string) *Transaction {
+	out := NewTransaction(tx.Cid, tid, tx.Replicas, tx.Sequencer)
+	out.Transport = tx.Transport
+	out.Recorder = tx.Recorder
+	out.Selector = tx.Selector
+	out.ReadTimeout = tx.ReadTimeout
+	out.CommitTimeout = tx.CommitTimeout
+	out.Snapshot = tx.Snapshot
+	out.Tracer = tx.Tracer
+	out.Logger = tx.Logger
+	return out
+}
+
+// RunTransaction executa fn numa transação nova configurada como tmpl e
+// confirma; se tmpl veio de Client.Begin, cada tentativa é um novo Begin.
+// Se a certificação abortar, reexecuta fn numa transação com Tid novo,
+// após um backoff exponencial com jitter. Num abort por
+// indisponibilidade, algumas réplicas podem já ter aplicado o commit: o
+// mesmo pedido é reenviado, com o mesmo Tid, e elas respondem a decisão
+// original em vez de aplicá-lo de novo. Erros de fn ou de rede não são
+// reexecutados, nem os demais aborts (AbortError). Cada envio conta como
+// uma tentativa. Retorna a transação confirmada.
+func RunTransaction(ctx context.Context, tmpl *Transaction, policy RetryPolicy, fn func(tx *Transaction) error) (*Transaction, error) {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if policy.BaseBackoff <= 0 {
+		policy.BaseBackoff = DefaultRetryPolicy.BaseBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	backoff := policy.BaseBackoff
+	var tx *Transaction
+	resubmit := false
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !resubmit {
+			if tmpl.begin != nil {
+				tx = tmpl.begin()
+			} else {
+				tx = tmpl.derive(NewTid())
+			}
+			if err := fn(tx); err != nil {
+				return nil, err
+			}
+		}
+		ok, err := tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return tx, nil
+		}
+		resubmit = tx.Decision.Reason == types.ReasonUnavailable
+		if !resubmit && !tx.Decision.Retryable() {
+			return nil, &AbortError{Decision: tx.Decision}
+		}
+		if attempt == policy.MaxAttempts {
+			return nil, fmt.Errorf("%w: %d attempts", ErrTooManyAborts, attempt)
+		}
+		wait := time.Duration(mrand.Int63n(int64(backoff) + 1))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-network.ClockOf(tx.transport()).After(wait):
+		}
+		backoff = min(backoff*2, policy.MaxBackoff)
+	}
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// fakeSequencer aborta os primeiros aborts commits e registra os Tids recebidos
+func fakeSequencer(t *testing.T, tr network.Transport, addr string, aborts int) func() []string {
+	ln, err := tr.Listen(addr)
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	var mu sync.Mutex
+	var tids []string
+	go network.Serve(ln, func(raw []byte, conn net.Conn) {
+		var req types.CommitRequest
+		json.Unmarshal(raw, &req)
+		mu.Lock()
+		tids = append(tids, req.Tid)
+		commit := len(tids) > aborts
+		mu.Unlock()
+		dec := types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Commit: commit}
+		if !commit {
+			dec.Reason = types.ReasonStaleRead
+		}
+		json.NewEncoder(conn).Encode(dec)
+	})
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), tids...)
+	}
+}
+
+func retryTemplate(tr network.Transport) *Transaction {
+	tmpl := NewTransaction("c1", "", nil, "seq")
+	tmpl.Transport = tr
+	return tmpl
+}
+
+var fastRetry = RetryPolicy{MaxAttempts: 4, BaseBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+func TestRunTransactionRetriesWithFreshTids(t *testing.T) {
+	tr := network.NewMemTransport()
+	tids := fakeSequencer(t, tr, "seq", 2)
+	runs := 0
+	tx, err := RunTransaction(context.Background(), retryTemplate(tr), fastRetry, func(tx *Transaction) error {
+		runs++
+		tx.Write("x", []byte("v"))
+		return nil
+	})
+	if err != nil || tx == nil {
+		t.Fatalf("Expected commit on third attempt, got err=%v", err)
+	}
+	got := tids()
+	if runs != 3 || len(got) != 3 {
+		t.Fatalf("Expected 3 attempts, got runs=%d commits=%v", runs, got)
+	}
+	if got[0] == got[1] || got[1] == got[2] || got[0] == got[2] || got[2] != tx.Tid {
+		t.Errorf("Expected a fresh Tid per attempt, got %v (committed %s)", got, tx.Tid)
+	}
+}
+
+func TestRunTransactionGivesUp(t *testing.T) {
+	tr := network.NewMemTransport()
+	tids := fakeSequencer(t, tr, "seq", 100)
+	_, err := RunTransaction(context.Background(), retryTemplate(tr), fastRetry, func(tx *Transaction) error { return nil })
+	if !errors.Is(err, ErrTooManyAborts) || len(tids()) != 4 {
+		t.Fatalf("Expected ErrTooManyAborts after 4 attempts, got %v after %d", err, len(tids()))
+	}
+}
+
+func TestRunTransactionDoesNotRetryOtherFailures(t *testing.T) {
+	tr := network.NewMemTransport()
+	tids := fakeSequencer(t, tr, "seq", 100)
+	boom := errors.New("boom")
+	runs := 0
+	_, err := RunTransaction(context.Background(), retryTemplate(tr), fastRetry, func(tx *Transaction) error {
+		runs++
+		return boom
+	})
+	if !errors.Is(err, boom) || runs != 1 || len(tids()) != 0 {
+		t.Fatalf("Expected fn error without retry, got %v runs=%d", err, runs)
+	}
+
+	runs = 0
+	tmpl := retryTemplate(tr)
+	tmpl.Sequencer = "nowhere"
+	_, err = RunTransaction(context.Background(), tmpl, fastRetry, func(tx *Transaction) error {
+		runs++
+		return nil
+	})
+	if !errors.Is(err, network.ErrConnRefused) || runs != 1 {
+		t.Fatalf("Expected network error without retry, got %v runs=%d", err, runs)
+	}
+}
+
+func TestRunTransactionContextCancel(t *testing.T) {
+	tr := network.NewMemTransport()
+	fakeSequencer(t, tr, "seq", 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{MaxAttempts: 100, BaseBackoff: time.Hour, MaxBackoff: time.Hour}
+	runs := 0
+	_, err := RunTransaction(ctx, retryTemplate(tr), policy, func(tx *Transaction) error {
+		runs++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || runs != 1 {
+		t.Fatalf("Expected context.Canceled after one attempt, got %v runs=%d", err, runs)
+	}
+}
+
+func TestRunTransactionDoesNotRetryPrecondition(t *testing.T) {
+	tr := network.NewMemTransport()
+	ln, _ := tr.Listen("seq")
+	go network.Serve(ln, func(raw []byte, conn net.Conn) {
+		var req types.CommitRequest
+		json.Unmarshal(raw, &req)
+		json.NewEncoder(conn).Encode(types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Reason: types.ReasonPrecondition, Item: "x", Detail: "expected absent, found v3"})
+	})
+	runs := 0
+	_, err := RunTransaction(context.Background(), retryTemplate(tr), fastRetry, func(tx *Transaction) error {
+		runs++
+		tx.WriteIfAbsent("x", []byte("v"))
+		return nil
+	})
+	var abort *AbortError
+	if !errors.As(err, &abort) || abort.Decision.Reason != types.ReasonPrecondition || abort.Decision.Item != "x" {
+		t.Fatalf("Expected precondition AbortError on x, got %v", err)
+	}
+	if runs != 1 {
+		t.Errorf("Expected no retry, got %d runs", runs)
+	}
+}
+
+func TestRunTransactionResubmitsUnavailable(t *testing.T) {
+	tr := network.NewMemTransport()
+	ln, _ := tr.Listen("seq")
+	var mu sync.Mutex
+	var tids []string
+	go network.Serve(ln, func(raw []byte, conn net.Conn) {
+		var req types.CommitRequest
+		json.Unmarshal(raw, &req)
+		mu.Lock()
+		tids = append(tids, req.Tid)
+		first := len(tids) == 1
+		mu.Unlock()
+		dec := types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Commit: !first}
+		if first {
+			dec.Reason = types.ReasonUnavailable
+		}
+		json.NewEncoder(conn).Encode(dec)
+	})
+	runs := 0
+	tx, err := RunTransaction(context.Background(), retryTemplate(tr), fastRetry, func(tx *Transaction) error {
+		runs++
+		tx.Write("x", []byte("v"))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected commit on resubmission, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if runs != 1 || len(tids) != 2 || tids[0] != tids[1] || tids[1] != tx.Tid {
+		t.Errorf("Expected the same Tid resubmitted without rerunning fn, got runs=%d tids=%v", runs, tids)
+	}
+}
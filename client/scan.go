@@ -0,0 +1,108 @@
+package client
+
+import (
+	"sort"
+
+	"github.com/hrodric0/dur-impl/history"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// Scan lê, em ordem, as chaves em [start, end); end vazio vai até o fim e
+// limit <= 0 não limita. O resultado inclui o ws da própria transação.
+// O intervalo efetivamente visto entra em Ranges: se outra transação
+// inserir, alterar ou remover uma chave nele antes do commit, este aborta.
+func (tx *Transaction) Scan(start, end string, limit int) ([]types.ScanItem, error) {
+	tx.logger().Debug("scan", "start", start, "end", end, "limit", limit)
+	// deletes locais escondem itens remotos: pede a mais para compensar
+	remoteLimit := limit
+	local := map[string]types.WriteEntry{}
+	for k, we := range tx.Ws {
+		if inRange(k, start, end) {
+			local[k] = we
+			if we.Op == types.OpDelete && limit > 0 {
+				remoteLimit++
+			}
+		}
+	}
+	span := tx.span("client.scan")
+	defer span.End()
+	span.SetAttr("start", start)
+	span.SetAttr("end", end)
+	req := types.ScanRequest{Cid: tx.Cid, Start: start, End: end, Limit: remoteLimit, MinSeq: tx.Snapshot, Trace: span.Context()}
+	rep, err := askReplicas(tx, req, func(r *types.ScanReply) (uint64, bool) { return r.Seq, r.Behind })
+	span.Fail(err)
+	if err != nil {
+		tx.logger().Debug("scan failed", "start", start, "end", end, "err", err)
+		tx.record(history.Event{Kind: history.KindRead, Item: start, Err: err.Error()})
+		return nil, err
+	}
+	tx.logger().Debug("scan reply", "items", len(rep.Items), "seq", rep.Seq, "more", rep.More)
+	tx.advance(rep.Seq)
+
+	// se a réplica cortou o intervalo, só vale até a última chave recebida
+	seen := end
+	if rep.More && len(rep.Items) > 0 {
+		seen = after(rep.Items[len(rep.Items)-1].Item)
+	}
+	merged := make(map[string]types.ScanItem, len(rep.Items))
+	for _, it := range rep.Items {
+		merged[it.Item] = it
+	}
+	for k, we := range local {
+		if !inRange(k, start, seen) {
+			continue
+		}
+		it, found := merged[k]
+		val, found, err := types.Apply(it.Value, found, we)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			merged[k] = types.ScanItem{Item: k, Value: val}
+		} else {
+			delete(merged, k)
+		}
+	}
+	items := make([]types.ScanItem, 0, len(merged))
+	for _, it := range merged {
+		items = append(items, it)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Item < items[j].Item })
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+		seen = after(items[limit-1].Item)
+	}
+
+	tx.Ranges = append(tx.Ranges, types.RangeRead{Start: start, End: seen, Seq: rep.Seq})
+	for _, it := range items {
+		if _, ok := local[it.Item]; !ok {
+			tx.record(history.Event{Kind: history.KindRead, Item: it.Item, Value: it.Value, Version: it.Version})
+		}
+	}
+	return items, nil
+}
+
+// ScanPrefix é Scan sobre as chaves que começam com prefix
+func (tx *Transaction) ScanPrefix(prefix string, limit int) ([]types.ScanItem, error) {
+	return tx.Scan(prefix, PrefixEnd(prefix), limit)
+}
+
+// PrefixEnd retorna o menor valor maior que todas as chaves com prefixo
+// prefix, ou "" (sem limite) se ele não existe
+func PrefixEnd(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
+
+// after retorna a menor chave maior que k
+func after(k string) string { return k + "\x00" }
+
+func inRange(k, start, end string) bool {
+	return k >= start && (end == "" || k < end)
+}
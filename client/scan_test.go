@@ -0,0 +1,78 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+func TestPrefixEnd(t *testing.T) {
+	cases := map[string]string{"user/": "user0", "a\xff": "b", "\xff\xff": "", "": ""}
+	for in, want := range cases {
+		if got := PrefixEnd(in); got != want {
+			t.Errorf("PrefixEnd(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// fakeScanReplica responde varreduras com items, respeitando Limit
+func fakeScanReplica(t *testing.T, tr network.Transport, addr string, seq uint64, items []types.ScanItem) {
+	ln, err := tr.Listen(addr)
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	go network.Serve(ln, func(raw []byte, conn net.Conn) {
+		var req types.ScanRequest
+		json.Unmarshal(raw, &req)
+		rep := types.ScanReply{Cid: req.Cid, Seq: seq}
+		for _, it := range items {
+			if it.Item < req.Start || (req.End != "" && it.Item >= req.End) {
+				continue
+			}
+			if req.Limit > 0 && len(rep.Items) == req.Limit {
+				rep.More = true
+				break
+			}
+			rep.Items = append(rep.Items, it)
+		}
+		json.NewEncoder(conn).Encode(rep)
+	})
+}
+
+func TestScanMergesWriteSet(t *testing.T) {
+	tr := network.NewMemTransport()
+	fakeScanReplica(t, tr, "r", 4, []types.ScanItem{
+		{Item: "u/1", Value: []byte("a"), Version: 1},
+		{Item: "u/2", Value: []byte("b"), Version: 2},
+		{Item: "u/4", Value: []byte("d"), Version: 3},
+		{Item: "u/5", Value: []byte("e"), Version: 4},
+	})
+	tx := NewTransaction("c1", "t1", []string{"r"}, "")
+	tx.Transport = tr
+	tx.Delete("u/2")
+	tx.Write("u/3", []byte("mine"))
+	tx.Write("w", []byte("outside"))
+
+	items, err := tx.ScanPrefix("u/", 3)
+	if err != nil {
+		t.Fatalf("Scan error: %v", err)
+	}
+	var got []string
+	for _, it := range items {
+		got = append(got, it.Item+"="+string(it.Value))
+	}
+	if want := "[u/1=a u/3=mine u/4=d]"; fmt.Sprint(got) != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+	// o limite corta o predicado logo após a última chave retornada
+	if len(tx.Ranges) != 1 || tx.Ranges[0] != (types.RangeRead{Start: "u/", End: "u/4\x00", Seq: 4}) {
+		t.Errorf("Unexpected ranges: %+v", tx.Ranges)
+	}
+	if tx.Snapshot != 4 {
+		t.Errorf("Expected snapshot 4, got %d", tx.Snapshot)
+	}
+}
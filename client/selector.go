@@ -0,0 +1,158 @@
+package client
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hrodric0/dur-impl/health"
+)
+
+// Selector decide a ordem em que as réplicas são tentadas numa leitura.
+// A primeira é a preferida; as demais servem de failover. Um Selector é
+// compartilhado entre transações, por isso deve ser seguro para uso concorrente.
+type Selector interface {
+	Order(replicas []string) []string
+	// Observe informa o resultado de uma tentativa de leitura
+	Observe(replica string, latency time.Duration, err error)
+}
+
+// First tenta as réplicas na ordem configurada (comportamento original)
+func First() Selector { return firstSelector{} }
+
+type firstSelector struct{}
+
+func (firstSelector) Order(replicas []string) []string {
+	return append([]string(nil), replicas...)
+}
+func (firstSelector) Observe(string, time.Duration, error) {}
+
+// RoundRobin alterna a réplica preferida a cada leitura
+func RoundRobin() Selector { return &roundRobin{} }
+
+type roundRobin struct {
+	mu   sync.Mutex
+	next int
+}
+
+func (s *roundRobin) Order(replicas []string) []string {
+	s.mu.Lock()
+	start := s.next
+	s.next++
+	s.mu.Unlock()
+	out := make([]string, len(replicas))
+	for i := range replicas {
+		out[i] = replicas[(start+i)%len(replicas)]
+	}
+	return out
+}
+func (s *roundRobin) Observe(string, time.Duration, error) {}
+
+// Random embaralha as réplicas a cada leitura
+func Random(seed int64) Selector { return &randomSelector{rng: rand.New(rand.NewSource(seed))} }
+
+type randomSelector struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func (s *randomSelector) Order(replicas []string) []string {
+	out := append([]string(nil), replicas...)
+	s.mu.Lock()
+	s.rng.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	s.mu.Unlock()
+	return out
+}
+func (s *randomSelector) Observe(string, time.Duration, error) {}
+
+// LeastLatency prefere a réplica com menor latência média (EWMA).
+// Réplicas ainda não medidas vêm primeiro; falhas contam como penalty.
+func LeastLatency(penalty time.Duration) Selector {
+	return &leastLatency{penalty: penalty, ewma: map[string]time.Duration{}}
+}
+
+type leastLatency struct {
+	mu      sync.Mutex
+	penalty time.Duration
+	ewma    map[string]time.Duration
+}
+
+func (s *leastLatency) Order(replicas []string) []string {
+	out := append([]string(nil), replicas...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sort.SliceStable(out, func(i, j int) bool { return s.ewma[out[i]] < s.ewma[out[j]] })
+	return out
+}
+
+func (s *leastLatency) Observe(replica string, latency time.Duration, err error) {
+	if err != nil {
+		latency = s.penalty
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.ewma[replica]; ok {
+		latency = (old*7 + latency) / 8
+	}
+	s.ewma[replica] = latency
+}
+
+// Nearest prefere as réplicas em preferred, nessa ordem (por exemplo, as
+// da mesma zona), e depois as demais na ordem configurada
+func Nearest(preferred ...string) Selector {
+	rank := map[string]int{}
+	for i, r := range preferred {
+		rank[r] = i + 1
+	}
+	return nearest{rank: rank}
+}
+
+type nearest struct{ rank map[string]int }
+
+func (s nearest) Order(replicas []string) []string {
+	out := append([]string(nil), replicas...)
+	key := func(r string) int {
+		if k, ok := s.rank[r]; ok {
+			return k
+		}
+		return len(s.rank) + 1
+	}
+	sort.SliceStable(out, func(i, j int) bool { return key(out[i]) < key(out[j]) })
+	return out
+}
+func (nearest) Observe(string, time.Duration, error) {}
+
+// Healthy usa a ordem de next (nil usa First), mas deixa para o fim do
+// failover as réplicas de que d suspeita e, antes delas, as que ainda não
+// estão prontas
+func Healthy(d *health.Detector, next Selector) Selector {
+	if next == nil {
+		next = First()
+	}
+	return healthy{d: d, next: next}
+}
+
+type healthy struct {
+	d    *health.Detector
+	next Selector
+}
+
+func (s healthy) Order(replicas []string) []string {
+	out := s.next.Order(replicas)
+	rank := func(r string) int {
+		switch {
+		case s.d.Ready(r):
+			return 0
+		case s.d.Up(r):
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(out, func(i, j int) bool { return rank(out[i]) < rank(out[j]) })
+	return out
+}
+
+func (s healthy) Observe(replica string, latency time.Duration, err error) {
+	s.next.Observe(replica, latency, err)
+}
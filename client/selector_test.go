@@ -0,0 +1,61 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hrodric0/dur-impl/health"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+func TestSelectorOrders(t *testing.T) {
+	reps := []string{"a", "b", "c"}
+	rr := RoundRobin()
+	if got := rr.Order(reps); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("RoundRobin first order: %v", got)
+	}
+	if got := rr.Order(reps); !reflect.DeepEqual(got, []string{"b", "c", "a"}) {
+		t.Errorf("RoundRobin second order: %v", got)
+	}
+	if got := Nearest("c").Order(reps); !reflect.DeepEqual(got, []string{"c", "a", "b"}) {
+		t.Errorf("Nearest order: %v", got)
+	}
+	if got := Random(1).Order(reps); len(got) != 3 {
+		t.Errorf("Random must keep all replicas: %v", got)
+	}
+
+	ll := LeastLatency(time.Second)
+	ll.Observe("a", 50*time.Millisecond, nil)
+	ll.Observe("b", 5*time.Millisecond, nil)
+	ll.Observe("c", time.Millisecond, errors.New("timeout"))
+	if got := ll.Order(reps); !reflect.DeepEqual(got, []string{"b", "a", "c"}) {
+		t.Errorf("LeastLatency order: %v", got)
+	}
+}
+
+func TestHealthyRoutesAroundDownReplicas(t *testing.T) {
+	tr := network.NewMemTransport()
+	for addr, ready := range map[string]bool{"b": false, "c": true} {
+		ln, err := tr.Listen(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		go network.Serve(ln, func(raw []byte, conn net.Conn) {
+			json.NewEncoder(conn).Encode(types.PingReply{Ready: ready})
+		})
+	}
+	d := &health.Detector{Addrs: []string{"a", "b", "c"}, Transport: tr, Misses: 1}
+	sel := Healthy(d, nil)
+	if got := sel.Order(d.Addrs); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("Expected the configured order before any ping, got %v", got)
+	}
+	d.Probe()
+	if got := sel.Order(d.Addrs); !reflect.DeepEqual(got, []string{"c", "b", "a"}) {
+		t.Errorf("Expected ready, then not ready, then down replicas, got %v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"strconv"
+
+	"github.com/hrodric0/dur-impl/trace"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// span abre um span filho da raiz da transação; a raiz, "transaction",
+// começa no primeiro uso e termina no Commit. Sem Tracer retorna nil.
+func (tx *Transaction) span(name string) *trace.Span {
+	if tx.Tracer == nil {
+		return nil
+	}
+	if tx.root == nil {
+		tx.root = tx.Tracer.Start(nil, "transaction")
+		tx.root.SetAttr("cid", tx.Cid)
+		tx.root.SetAttr("tid", tx.Tid)
+	}
+	return tx.root.Child(name)
+}
+
+// TraceID identifica o trace da transação; vazio sem Tracer ou antes da
+// primeira operação
+func (tx *Transaction) TraceID() string {
+	if tx.root == nil {
+		return ""
+	}
+	return tx.root.Context().TraceID
+}
+
+// traceDecision anota a decisão dec em span e na raiz, e termina ambos
+func (tx *Transaction) traceDecision(span *trace.Span, dec types.CommitDecision, err error) {
+	for _, s := range []*trace.Span{span, tx.root} {
+		s.Fail(err)
+		if err == nil {
+			s.SetAttr("commit", strconv.FormatBool(dec.Commit))
+			if dec.Commit {
+				s.SetAttr("seq", strconv.FormatUint(dec.Seq, 10))
+			} else {
+				s.SetAttr("reason", dec.Reason)
+			}
+		}
+		s.End()
+	}
+}
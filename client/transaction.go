@@ -1,56 +1,291 @@
 package client
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/hrodric0/dur-impl/history"
+	"github.com/hrodric0/dur-impl/logging"
 	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/trace"
 	"github.com/hrodric0/dur-impl/types"
 )
 
 // Transaction mantém estado local de rs/ws
 type Transaction struct {
-	Cid       string
-	Tid       string
-	Rs        map[string]types.ReadEntry
-	Ws        map[string]types.WriteEntry
+	Cid string
+	Tid string
+	Rs  map[string]types.ReadEntry
+	Ws  map[string]types.WriteEntry
+	// Ranges guarda os intervalos varridos por Scan, certificados como predicados
+	Ranges []types.RangeRead
+	// Pre guarda as condições de WriteIf e WriteIfAbsent
+	Pre       []types.Precondition
 	Replicas  []string
 	Sequencer string
+	// Transport usado para falar com réplicas e sequencer; nil usa TCP
+	Transport network.Transport
+	// Recorder, se não for nil, recebe cada Read, Write e Commit
+	Recorder *history.Recorder
+	// Decision é a última decisão recebida do sequencer
+	Decision types.CommitDecision
+	// Selector escolhe a réplica de cada leitura e a ordem de failover; nil usa First
+	Selector Selector
+	// ReadTimeout limita cada tentativa de leitura; zero não impõe prazo
+	ReadTimeout time.Duration
+	// CommitTimeout limita a espera pela decisão do sequencer; passado o
+	// prazo, Commit retorna ErrAmbiguousCommit. Zero usa DefaultCommitTimeout.
+	CommitTimeout time.Duration
+	// Snapshot é a maior sequência já observada pela transação. Leituras
+	// só são aceitas de réplicas que já a aplicaram, então o failover
+	// nunca volta no tempo dentro da transação.
+	Snapshot uint64
+	// Tracer, se não for nil, registra um span por leitura e pelo commit,
+	// filhos de um span "transaction", e os propaga às réplicas e ao sequencer
+	Tracer *trace.Tracer
+	// Logger recebe os eventos da transação, com cid e tid; nil usa
+	// slog.Default(). Leituras e escritas vão em Debug, sem os valores.
+	Logger *slog.Logger
+
+	root *trace.Span
+	// observe e begin ligam a transação à sessão do Client que a criou
+	observe func(seq uint64)
+	begin   func() *Transaction
 }
 
+// ErrNoReplicas é retornado quando a transação não tem réplicas configuradas
+var ErrNoReplicas = errors.New("no replicas configured")
+
+// ErrAmbiguousCommit é retornado por Commit quando o pedido pode ter chegado
+// ao sequencer mas a decisão não voltou: a transação pode ter confirmado.
+// Use QueryStatus, ou chame Commit de novo, o que é seguro.
+var ErrAmbiguousCommit = errors.New("commit outcome unknown")
+
+// DefaultCommitTimeout é o CommitTimeout padrão
+const DefaultCommitTimeout = 30 * time.Second
+
+// ErrNotFound é retornado por Read quando a chave não existe ou foi removida
+var ErrNotFound = errors.New("key not found")
+
 // NewTransaction inicializa um novo tx
 func NewTransaction(cid, tid string, replicas []string, seq string) *Transaction {
-	log.Printf("[Client %s] Criando transação %s", cid, tid)
 	return &Transaction{Cid: cid, Tid: tid, Rs: make(map[string]types.ReadEntry), Ws: make(map[string]types.WriteEntry), Replicas: replicas, Sequencer: seq}
 }
 
-// Read usa primitiva 1:1
+func (tx *Transaction) record(e history.Event) {
+	if tx.Recorder != nil {
+		e.Cid, e.Tid = tx.Cid, tx.Tid
+		tx.Recorder.Record(e)
+	}
+}
+
+// logger é o Logger da transação, identificado por cid e tid
+func (tx *Transaction) logger() *slog.Logger {
+	return logging.Or(tx.Logger).With("component", "client", "cid", tx.Cid, "tid", tx.Tid)
+}
+
+func (tx *Transaction) transport() network.Transport {
+	if tx.Transport == nil {
+		return network.TCP
+	}
+	return tx.Transport
+}
+
+// Read usa primitiva 1:1; retorna ErrNotFound se a chave não existe
 func (tx *Transaction) Read(item string) ([]byte, error) {
-	log.Printf("[Client %s] Sending ReadRequest(item=%s)", tx.Cid, item)
-	if we, ok := tx.Ws[item]; ok {
-		log.Printf("[Client %s] Read from WS: %s=%s", tx.Cid, item, string(we.Value))
-		return we.Value, nil
-	}
-	req := types.ReadRequest{Cid: tx.Cid, Item: item}
-	var rep types.ReadReply
-	err := network.Request(tx.Replicas[0], req, &rep)
+	val, found, err := tx.Get(item)
+	if err == nil && !found {
+		return nil, ErrNotFound
+	}
+	return val, err
+}
+
+// Get lê item e informa se a chave existe. Uma leitura de chave ausente
+// também entra no rs: se outra transação a criar antes do commit, este aborta.
+// Reler uma chave retorna a versão já lida (leituras repetíveis).
+func (tx *Transaction) Get(item string) ([]byte, bool, error) {
+	if val, found, ok, err := tx.local(item); ok {
+		return val, found, err
+	}
+	tx.logger().Debug("read", "item", item)
+	span := tx.span("client.read")
+	defer span.End()
+	span.SetAttr("item", item)
+	req := types.ReadRequest{Cid: tx.Cid, Item: item, MinSeq: tx.Snapshot, Trace: span.Context()}
+	rep, err := askReplicas(tx, req, func(r *types.ReadReply) (uint64, bool) { return r.Seq, r.Behind })
+	span.Fail(err)
 	if err != nil {
-		log.Printf("[Client %s] Read error: %v", tx.Cid, err)
+		tx.logger().Debug("read failed", "item", item, "err", err)
+		tx.record(history.Event{Kind: history.KindRead, Item: item, Err: err.Error()})
+		return nil, false, err
+	}
+	tx.advance(rep.Seq)
+	tx.remember(rep)
+	val, found, _, err := tx.local(item)
+	return val, found, err
+}
+
+// ReadMany lê items numa única requisição, todos do mesmo snapshot da
+// réplica, e registra as leituras no rs juntas. O mapa só traz as chaves
+// existentes; chaves já no ws ou no rs não vão à réplica.
+func (tx *Transaction) ReadMany(items ...string) (map[string][]byte, error) {
+	out := make(map[string][]byte, len(items))
+	var remote []string
+	seen := map[string]bool{}
+	for _, item := range items {
+		if seen[item] {
+			continue
+		}
+		seen[item] = true
+		val, found, ok, err := tx.local(item)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			remote = append(remote, item)
+		} else if found {
+			out[item] = val
+		}
+	}
+	if len(remote) == 0 {
+		return out, nil
+	}
+	tx.logger().Debug("multi-read", "items", len(remote))
+	span := tx.span("client.read_many")
+	defer span.End()
+	span.SetAttr("items", strconv.Itoa(len(remote)))
+	req := types.MultiReadRequest{Cid: tx.Cid, Items: remote, MinSeq: tx.Snapshot, Trace: span.Context()}
+	rep, err := askReplicas(tx, req, func(r *types.MultiReadReply) (uint64, bool) { return r.Seq, r.Behind })
+	if err == nil && len(rep.Replies) != len(remote) {
+		err = fmt.Errorf("multi-read: expected %d replies, got %d", len(remote), len(rep.Replies))
+	}
+	span.Fail(err)
+	if err != nil {
+		tx.logger().Debug("multi-read failed", "items", len(remote), "err", err)
+		for _, item := range remote {
+			tx.record(history.Event{Kind: history.KindRead, Item: item, Err: err.Error()})
+		}
 		return nil, err
 	}
-	log.Printf("[Client %s] Received ReadReply: %s=%s (v%d)", tx.Cid, rep.Item, string(rep.Value), rep.Version)
-	tx.Rs[item] = types.ReadEntry{Item: item, Value: rep.Value, Version: rep.Version}
-	return rep.Value, nil
+	tx.advance(rep.Seq)
+	for _, r := range rep.Replies {
+		tx.remember(r)
+	}
+	for _, item := range remote {
+		val, found, _, err := tx.local(item)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			out[item] = val
+		}
+	}
+	return out, nil
+}
+
+// local responde item a partir do ws ou de uma leitura anterior; ok é
+// falso se é preciso perguntar a uma réplica. Uma operação comutativa
+// pendente é aplicada sobre o valor lido.
+func (tx *Transaction) local(item string) (val []byte, found, ok bool, err error) {
+	we, pending := tx.Ws[item]
+	if pending && !we.Commutative() {
+		return we.Value, we.Op != types.OpDelete, true, nil
+	}
+	re, read := tx.Rs[item]
+	if !read {
+		return nil, false, false, nil
+	}
+	val, found = re.Value, !re.Absent
+	if pending {
+		val, found, err = types.Apply(val, found, we)
+	}
+	return val, found, true, err
+}
+
+// remember guarda no rs a leitura rep vinda de uma réplica
+func (tx *Transaction) remember(rep types.ReadReply) {
+	tx.logger().Debug("read reply", "item", rep.Item, "value", logging.Redacted(rep.Value), "version", rep.Version, "found", rep.Found)
+	tx.Rs[rep.Item] = types.ReadEntry{Item: rep.Item, Value: rep.Value, Version: rep.Version, Absent: !rep.Found}
+	tx.record(history.Event{Kind: history.KindRead, Item: rep.Item, Value: rep.Value, Version: rep.Version})
+}
+
+// askReplicas envia req às réplicas na ordem do Selector até uma responder
+// sem estar atrás do snapshot; lag extrai da resposta a sequência e o Behind
+func askReplicas[R any](tx *Transaction, req any, lag func(*R) (uint64, bool)) (R, error) {
+	var rep R
+	if len(tx.Replicas) == 0 {
+		return rep, ErrNoReplicas
+	}
+	sel := tx.Selector
+	if sel == nil {
+		sel = First()
+	}
+	var err error
+	for _, replica := range sel.Order(tx.Replicas) {
+		start := time.Now()
+		var zero R
+		rep = zero
+		err = network.RequestTimeout(tx.transport(), replica, req, &rep, tx.ReadTimeout)
+		if err == nil {
+			if seq, behind := lag(&rep); behind {
+				err = fmt.Errorf("replica %s behind snapshot (seq %d < %d)", replica, seq, tx.Snapshot)
+			}
+		}
+		sel.Observe(replica, time.Since(start), err)
+		if err == nil {
+			break
+		}
+		tx.logger().Warn("replica read failed, trying next", "replica", replica, "err", err)
+	}
+	return rep, err
+}
+
+// advance avança o snapshot da transação e da sessão para seq
+func (tx *Transaction) advance(seq uint64) {
+	if seq > tx.Snapshot {
+		tx.Snapshot = seq
+	}
+	if tx.observe != nil {
+		tx.observe(seq)
+	}
 }
 
 // Write armazena localmente
 func (tx *Transaction) Write(item string, val []byte) {
-	log.Printf("[Client %s] Write_WS: %s=%s", tx.Cid, item, string(val))
+	tx.logger().Debug("write", "item", item, "value", logging.Redacted(val))
 	tx.Ws[item] = types.WriteEntry{Item: item, Value: val}
+	tx.record(history.Event{Kind: history.KindWrite, Item: item, Value: val})
 }
 
-// Commit faz broadcast atômico via sequencer e retorna decisão agregada
+// WriteIf grava val em item só se, no commit, item estiver na versão
+// version; não lê a chave nem a coloca no rs. Se a condição falhar, o
+// commit aborta com Decision.Reason = types.ReasonPrecondition.
+func (tx *Transaction) WriteIf(item string, val []byte, version uint64) {
+	tx.Write(item, val)
+	tx.Pre = append(tx.Pre, types.Precondition{Item: item, Version: version})
+}
+
+// WriteIfAbsent grava val em item só se, no commit, a chave não existir
+func (tx *Transaction) WriteIfAbsent(item string, val []byte) {
+	tx.Write(item, val)
+	tx.Pre = append(tx.Pre, types.Precondition{Item: item, Absent: true})
+}
+
+// Delete remove item no commit; a remoção vira um tombstone versionado
+func (tx *Transaction) Delete(item string) {
+	tx.logger().Debug("delete", "item", item)
+	tx.Ws[item] = types.WriteEntry{Item: item, Op: types.OpDelete}
+	tx.record(history.Event{Kind: history.KindWrite, Item: item})
+}
+
+// Commit faz broadcast atômico via sequencer e retorna decisão agregada.
+// Repetir Commit com o mesmo Tid não reaplica o ws: as réplicas respondem
+// a decisão original.
 func (tx *Transaction) Commit() (bool, error) {
-	log.Printf("[Client %s] Collecting rs/ws for Commit", tx.Cid)
 	rs := make([]types.ReadEntry, 0, len(tx.Rs))
 	for _, v := range tx.Rs {
 		rs = append(rs, v)
@@ -59,14 +294,70 @@ func (tx *Transaction) Commit() (bool, error) {
 	for _, v := range tx.Ws {
 		ws = append(ws, v)
 	}
-	req := types.CommitRequest{Cid: tx.Cid, Tid: tx.Tid, Rs: rs, Ws: ws}
-	log.Printf("[Client %s] Sending CommitRequest to Sequencer", tx.Cid)
+	// em ordem de item, o mesmo pedido é sempre codificado igual
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Item < rs[j].Item })
+	sort.Slice(ws, func(i, j int) bool { return ws[i].Item < ws[j].Item })
+	span := tx.span("client.commit")
+	req := types.CommitRequest{Cid: tx.Cid, Tid: tx.Tid, Rs: rs, Ws: ws, Ranges: tx.Ranges, Pre: tx.Pre, Trace: span.Context()}
+	log := tx.logger()
+	log.Debug("commit", "rs", len(rs), "ws", len(ws), "sequencer", tx.Sequencer)
 	var dec types.CommitDecision
-	err := network.Request(tx.Sequencer, req, &dec)
+	err := network.RequestTimeout(tx.transport(), tx.Sequencer, req, &dec, tx.commitTimeout())
+	if errors.Is(err, network.ErrNoReply) {
+		err = fmt.Errorf("%w: %w", ErrAmbiguousCommit, err)
+	}
+	tx.traceDecision(span, dec, err)
 	if err != nil {
-		log.Printf("[Client %s] Commit error: %v", tx.Cid, err)
+		log.Warn("commit failed", "err", err)
+		tx.record(history.Event{Kind: history.KindCommit, Err: err.Error()})
 		return false, err
 	}
-	log.Printf("[Client %s] Received CommitDecision -> %v", tx.Cid, dec.Commit)
+	log.Debug("commit decision", "commit", dec.Commit, "seq", dec.Seq, "reason", dec.Reason)
+	tx.Decision = dec
+	if tx.observe != nil && dec.Commit {
+		tx.observe(dec.Seq)
+	}
+	tx.record(history.Event{Kind: history.KindCommit, Committed: dec.Commit, Version: dec.Seq})
 	return dec.Commit, nil
 }
+
+func (tx *Transaction) commitTimeout() time.Duration {
+	if tx.CommitTimeout <= 0 {
+		return DefaultCommitTimeout
+	}
+	return tx.CommitTimeout
+}
+
+// QueryStatus pergunta às réplicas a decisão desta transação, para resolver
+// um ErrAmbiguousCommit. Retorna a primeira resposta com Known; se nenhuma
+// réplica conhece o Tid, Known é falso e reenviar Commit é seguro.
+func (tx *Transaction) QueryStatus() (types.StatusReply, error) {
+	if len(tx.Replicas) == 0 {
+		return types.StatusReply{}, ErrNoReplicas
+	}
+	sel := tx.Selector
+	if sel == nil {
+		sel = First()
+	}
+	req := types.StatusRequest{Cid: tx.Cid, Tid: tx.Tid}
+	var out types.StatusReply
+	var lastErr error
+	answered := false
+	for _, replica := range sel.Order(tx.Replicas) {
+		var rep types.StatusReply
+		if err := network.RequestTimeout(tx.transport(), replica, req, &rep, tx.ReadTimeout); err != nil {
+			tx.logger().Warn("status query failed", "replica", replica, "err", err)
+			lastErr = err
+			continue
+		}
+		if rep.Known {
+			tx.logger().Debug("status", "replica", replica, "commit", rep.Decision.Commit)
+			return rep, nil
+		}
+		out, answered = rep, true
+	}
+	if !answered {
+		return out, lastErr
+	}
+	return out, nil
+}
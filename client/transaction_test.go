@@ -2,10 +2,14 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/hrodric0/dur-impl/network"
 	"github.com/hrodric0/dur-impl/types"
 )
 
@@ -18,7 +22,7 @@ func TestReadPopulatesRs(t *testing.T) {
 			var req types.ReadRequest
 			json.NewDecoder(conn).Decode(&req)
 			// respond with fixed value
-			rep := types.ReadReply{Cid: req.Cid, Item: req.Item, Value: []byte("val"), Version: 5}
+			rep := types.ReadReply{Cid: req.Cid, Item: req.Item, Value: []byte("val"), Version: 5, Found: true}
 			json.NewEncoder(conn).Encode(rep)
 			conn.Close()
 		}
@@ -38,3 +42,171 @@ func TestReadPopulatesRs(t *testing.T) {
 		t.Errorf("Rs not populated correctly: %+v", tx.Rs)
 	}
 }
+
+// fakeReplica responde leituras em addr como uma réplica na sequência seq
+func fakeReplica(t *testing.T, tr network.Transport, addr string, seq uint64, val string) {
+	ln, err := tr.Listen(addr)
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	go network.Serve(ln, func(raw []byte, conn net.Conn) {
+		var req types.ReadRequest
+		json.Unmarshal(raw, &req)
+		rep := types.ReadReply{Cid: req.Cid, Item: req.Item, Seq: seq}
+		if seq < req.MinSeq {
+			rep.Behind = true
+		} else {
+			rep.Value, rep.Version, rep.Found = []byte(val), seq, true
+		}
+		json.NewEncoder(conn).Encode(rep)
+	})
+}
+
+func TestReadFailover(t *testing.T) {
+	tr := network.NewMemTransport()
+	fakeReplica(t, tr, "up", 3, "val")
+
+	tx := NewTransaction("c1", "t1", []string{"down", "up"}, "")
+	tx.Transport = tr
+	v, err := tx.Read("x")
+	if err != nil || string(v) != "val" {
+		t.Fatalf("Expected failover to the healthy replica, got %s err=%v", v, err)
+	}
+	if tx.Snapshot != 3 {
+		t.Errorf("Expected snapshot 3, got %d", tx.Snapshot)
+	}
+}
+
+func TestReadSkipsReplicaBehindSnapshot(t *testing.T) {
+	tr := network.NewMemTransport()
+	fakeReplica(t, tr, "new", 5, "new")
+	fakeReplica(t, tr, "old", 2, "old")
+
+	tx := NewTransaction("c1", "t1", []string{"new", "old"}, "")
+	tx.Transport = tr
+	tx.Selector = RoundRobin()
+	if v, err := tx.Read("x"); err != nil || string(v) != "new" {
+		t.Fatalf("Expected first read from new, got %s err=%v", v, err)
+	}
+	// o round-robin agora prefere "old", que está atrás do snapshot da transação
+	if v, err := tx.Read("y"); err != nil || string(v) != "new" {
+		t.Fatalf("Expected lagging replica to be skipped, got %s err=%v", v, err)
+	}
+
+	stale := NewTransaction("c1", "t2", []string{"old"}, "")
+	stale.Transport = tr
+	stale.Snapshot = 5
+	if _, err := stale.Read("x"); err == nil {
+		t.Errorf("Expected error when every replica is behind the snapshot")
+	}
+}
+
+func TestGetReportsMissingKey(t *testing.T) {
+	tr := network.NewMemTransport()
+	ln, _ := tr.Listen("r")
+	go network.Serve(ln, func(raw []byte, conn net.Conn) {
+		var req types.ReadRequest
+		json.Unmarshal(raw, &req)
+		json.NewEncoder(conn).Encode(types.ReadReply{Cid: req.Cid, Item: req.Item, Seq: 2})
+	})
+
+	tx := NewTransaction("c1", "t1", []string{"r"}, "")
+	tx.Transport = tr
+	v, found, err := tx.Get("k")
+	if err != nil || found || v != nil {
+		t.Fatalf("Expected missing key, got %q found=%v err=%v", v, found, err)
+	}
+	if re := tx.Rs["k"]; !re.Absent {
+		t.Errorf("Expected absent read in rs, got %+v", re)
+	}
+	if _, err := tx.Read("k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from Read, got %v", err)
+	}
+	// um valor vazio gravado na própria transação existe
+	tx.Write("k", []byte{})
+	if _, found, _ := tx.Get("k"); !found {
+		t.Errorf("Expected empty value to be found")
+	}
+}
+
+func TestReadManyOneRoundTrip(t *testing.T) {
+	tr := network.NewMemTransport()
+	ln, _ := tr.Listen("r")
+	var requests atomic.Int32
+	go network.Serve(ln, func(raw []byte, conn net.Conn) {
+		requests.Add(1)
+		var req types.MultiReadRequest
+		json.Unmarshal(raw, &req)
+		rep := types.MultiReadReply{Cid: req.Cid, Seq: 7}
+		for _, item := range req.Items {
+			r := types.ReadReply{Cid: req.Cid, Item: item, Seq: 7}
+			if item != "missing" {
+				r.Value, r.Version, r.Found = []byte("v-"+item), 3, true
+			}
+			rep.Replies = append(rep.Replies, r)
+		}
+		json.NewEncoder(conn).Encode(rep)
+	})
+
+	tx := NewTransaction("c1", "t1", []string{"r"}, "")
+	tx.Transport = tr
+	tx.Write("local", []byte("mine"))
+	got, err := tx.ReadMany("a", "b", "missing", "local", "a")
+	if err != nil {
+		t.Fatalf("ReadMany error: %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("Expected one request, got %d", n)
+	}
+	if len(got) != 3 || string(got["a"]) != "v-a" || string(got["b"]) != "v-b" || string(got["local"]) != "mine" {
+		t.Fatalf("Unexpected values: %q", got)
+	}
+	if len(tx.Rs) != 3 || !tx.Rs["missing"].Absent || tx.Rs["a"].Version != 3 {
+		t.Errorf("Rs not populated correctly: %+v", tx.Rs)
+	}
+	if tx.Snapshot != 7 {
+		t.Errorf("Expected snapshot 7, got %d", tx.Snapshot)
+	}
+}
+
+// TestReadIsRepeatable: reler uma chave não volta à réplica, mesmo que
+// ela já tenha uma versão mais nova
+func TestReadIsRepeatable(t *testing.T) {
+	tr := network.NewMemTransport()
+	ln, _ := tr.Listen("r")
+	var version atomic.Uint64
+	go network.Serve(ln, func(raw []byte, conn net.Conn) {
+		var req types.ReadRequest
+		json.Unmarshal(raw, &req)
+		v := version.Add(1)
+		json.NewEncoder(conn).Encode(types.ReadReply{Cid: req.Cid, Item: req.Item, Value: []byte(fmt.Sprint(v)), Version: v, Found: true, Seq: v})
+	})
+
+	tx := NewTransaction("c1", "t1", []string{"r"}, "")
+	tx.Transport = tr
+	first, _ := tx.Read("x")
+	again, _ := tx.Read("x")
+	many, _ := tx.ReadMany("x")
+	if string(first) != "1" || string(again) != "1" || string(many["x"]) != "1" || version.Load() != 1 {
+		t.Fatalf("Expected repeatable read of v1, got %s, %s, %s after %d requests", first, again, many["x"], version.Load())
+	}
+}
+
+func TestCommitTimeoutIsAmbiguous(t *testing.T) {
+	tr := network.NewMemTransport()
+	stalled := make(chan struct{})
+	defer close(stalled)
+	ln, _ := tr.Listen("seq")
+	go network.Serve(ln, func(raw []byte, conn net.Conn) { <-stalled })
+
+	tx := NewTransaction("c1", "t1", []string{"r"}, "seq")
+	tx.Transport, tx.CommitTimeout = tr, 20*time.Millisecond
+	tx.Write("x", []byte("v"))
+	start := time.Now()
+	if _, err := tx.Commit(); !errors.Is(err, ErrAmbiguousCommit) {
+		t.Fatalf("Expected ErrAmbiguousCommit from a stalled sequencer, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Expected Commit to give up after CommitTimeout, took %v", d)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hrodric0/dur-impl/admin"
+)
+
+// adminOps são as consultas de dur admin e o caminho de cada uma
+var adminOps = map[string]struct {
+	args string
+	path func(args []string) string
+}{
+	"status":   {"", func([]string) string { return "/admin/status" }},
+	"key":      {"KEY", func(args []string) string { return "/admin/keys/" + url.PathEscape(args[0]) }},
+	"inflight": {"", func([]string) string { return "/admin/inflight" }},
+	"aborts":   {"", func([]string) string { return "/admin/aborts" }},
+	"config":   {"", func([]string) string { return "/admin/config" }},
+}
+
+// runAdmin consulta a API de administração: dur admin OP [flags] [args]
+func runAdmin(e *env, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(e.stderr, "usage: dur admin status|key|inflight|aborts|config [flags] [args]")
+		return exitUsage
+	}
+	op, ok := adminOps[args[0]]
+	if !ok {
+		fmt.Fprintf(e.stderr, "dur admin: unknown query %q (want status, key, inflight, aborts or config)\n", args[0])
+		return exitUsage
+	}
+	fs := newFlagSet(e, "admin "+args[0])
+	addr := fs.String("http-addr", "localhost:9000", "HTTP address of the sequencer or replica (DUR_HTTP_ADDR)")
+	timeout := fs.Duration("timeout", 5*time.Second, "request timeout (DUR_TIMEOUT)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [flags] %s\n", fs.Name(), op.args)
+		fs.PrintDefaults()
+	}
+	if err := e.parse(fs, args[1:], nil); err != nil {
+		return parseCode(err)
+	}
+	want := 0
+	if op.args != "" {
+		want = 1
+	}
+	if fs.NArg() != want {
+		return usageError(fs, e.stderr, "want %d arguments, got %v", want, fs.Args())
+	}
+	var out json.RawMessage
+	err := admin.Get(&http.Client{Timeout: *timeout}, *addr, op.path(fs.Args()), &out)
+	switch {
+	case errors.Is(err, admin.ErrNotFound) && args[0] == "key":
+		fmt.Fprintf(e.stderr, "dur admin key: %v\n", err)
+		return exitNotFound
+	case err != nil:
+		fmt.Fprintf(e.stderr, "dur admin %s: %v\n", args[0], err)
+		return exitError
+	}
+	fmt.Fprintf(e.stdout, "%s\n", out)
+	return exitOK
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/admin"
+)
+
+func TestAdmin(t *testing.T) {
+	rAddr, sAddr, httpAddr := freeAddr(t), freeAddr(t), freeAddr(t)
+	defer serve(t, "replica", "--listen", rAddr, "--http-addr", httpAddr)()
+	defer serve(t, "sequencer", "--listen", sAddr, "--replicas", rAddr)()
+	vars := map[string]string{"DUR_SEQUENCER": sAddr, "DUR_REPLICAS": rAddr}
+	for _, v := range []string{"one", "two"} {
+		e, _, errOut := testEnv("", vars)
+		if code := run(e, []string{"client", "put", "a/b", v}); code != exitOK {
+			t.Fatalf("Expected put to commit, got %d: %s", code, errOut)
+		}
+	}
+
+	vars = map[string]string{"DUR_HTTP_ADDR": httpAddr}
+	e, out, errOut := testEnv("", vars)
+	if code := run(e, []string{"admin", "key", "a/b"}); code != exitOK {
+		t.Fatalf("Expected key lookup to succeed, got %d: %s", code, errOut)
+	}
+	var k admin.Key
+	if err := json.Unmarshal(out.Bytes(), &k); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if len(k.History) != 2 || string(k.History[0].Value) != "one" || string(k.Current.Value) != "two" {
+		t.Errorf("Expected two versions of a/b, got %s", out)
+	}
+
+	e, out, _ = testEnv("", vars)
+	var st admin.Status
+	if code := run(e, []string{"admin", "status"}); code != exitOK || json.Unmarshal(out.Bytes(), &st) != nil || st.LastCommitted != 2 {
+		t.Errorf("Expected status at seq 2, got %d %s", code, out)
+	}
+	e, _, _ = testEnv("", vars)
+	if code := run(e, []string{"admin", "key", "missing"}); code != exitNotFound {
+		t.Errorf("Expected exit %d for a missing key, got %d", exitNotFound, code)
+	}
+	e, _, _ = testEnv("", vars)
+	if code := run(e, []string{"admin", "frobnicate"}); code != exitUsage {
+		t.Errorf("Expected usage error for an unknown query, got %d", code)
+	}
+	e, _, _ = testEnv("", nil)
+	if code := run(e, []string{"admin", "status", "--http-addr", freeAddr(t)}); code != exitError {
+		t.Errorf("Expected exit %d when nothing listens, got %d", exitError, code)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hrodric0/dur-impl/antientropy"
+	"github.com/hrodric0/dur-impl/config"
+)
+
+func checkTopology(t *config.Topology, _ *flag.FlagSet) (map[string]string, error) {
+	vals := map[string]string{"replicas": strings.Join(t.ReplicaAddrs(), ",")}
+	if t.Timeouts.Read > 0 {
+		vals["timeout"] = time.Duration(t.Timeouts.Read).String()
+	}
+	return vals, nil
+}
+
+// runCheck compara os digests das réplicas: dur check [flags]. Cada
+// verificação imprime uma linha JSON com o antientropy.Report; a saída é
+// exitDiverged se alguma réplica divergente ficou sem reparo.
+func runCheck(e *env, args []string) int {
+	fs := newFlagSet(e, "check")
+	replicas := fs.String("replicas", "", "comma-separated replica addresses (DUR_REPLICAS)")
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout of each replica request (DUR_TIMEOUT)")
+	repair := fs.Bool("repair", false, "copy the reference state to divergent replicas (DUR_REPAIR)")
+	interval := fs.Duration("interval", 0, "check again every interval until interrupted; 0 checks once (DUR_INTERVAL)")
+	lf := newLogFlags(fs, "warn")
+	if err := e.parse(fs, args, checkTopology); err != nil {
+		return parseCode(err)
+	}
+	if fs.NArg() != 0 {
+		return usageError(fs, e.stderr, "unexpected arguments %v", fs.Args())
+	}
+	reps := splitList(*replicas)
+	if len(reps) < 2 {
+		return usageError(fs, e.stderr, "--replicas needs at least two addresses")
+	}
+	if *interval < 0 {
+		return usageError(fs, e.stderr, "--interval must not be negative")
+	}
+	logger, err := lf.logger(e)
+	if err != nil {
+		return usageError(fs, e.stderr, "%v", err)
+	}
+	c := &antientropy.Checker{Replicas: reps, Timeout: *timeout, Repair: *repair, Logger: logger}
+	code := exitOK
+	print := func(r antientropy.Report, err error) {
+		if err != nil {
+			fmt.Fprintf(e.stderr, "dur check: %v\n", err)
+			code = exitError
+			return
+		}
+		line, _ := json.Marshal(r)
+		fmt.Fprintf(e.stdout, "%s\n", line)
+		code = exitOK
+		for _, d := range r.Divergences {
+			if !d.Repaired {
+				code = exitDiverged
+			}
+		}
+	}
+	if *interval == 0 {
+		print(c.Check())
+		return code
+	}
+	c.Run(e.ctx, *interval, print)
+	return code
+}
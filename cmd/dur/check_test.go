@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/antientropy"
+)
+
+func TestCheck(t *testing.T) {
+	r1, r2, s1, s2 := freeAddr(t), freeAddr(t), freeAddr(t), freeAddr(t)
+	defer serve(t, "replica", "--listen", r1)()
+	defer serve(t, "replica", "--listen", r2)()
+	defer serve(t, "sequencer", "--listen", s1, "--replicas", r1+","+r2)()
+	// s2 só alcança r1: o commit dele falta em r2
+	defer serve(t, "sequencer", "--listen", s2, "--replicas", r1)()
+	for _, put := range [][]string{{s1, "a", "1"}, {s2, "b", "2"}, {s1, "a", "3"}} {
+		e, _, errOut := testEnv("", map[string]string{"DUR_SEQUENCER": put[0], "DUR_REPLICAS": r1})
+		if code := run(e, []string{"client", "put", put[1], put[2]}); code != exitOK {
+			t.Fatalf("Expected put to commit, got %d: %s", code, errOut)
+		}
+	}
+
+	vars := map[string]string{"DUR_REPLICAS": r1 + "," + r2}
+	check := func(args ...string) (int, antientropy.Report) {
+		t.Helper()
+		e, out, errOut := testEnv("", vars)
+		code := run(e, append([]string{"check"}, args...))
+		var r antientropy.Report
+		if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+			t.Fatalf("%v: %s %s", err, out, errOut)
+		}
+		return code, r
+	}
+	if code, r := check(); code != exitDiverged || len(r.Divergences) != 1 || r.Divergences[0].Replica != r2 {
+		t.Fatalf("Expected %s to diverge, got %d %+v", r2, code, r)
+	}
+	if code, r := check("--repair"); code != exitOK || !r.Divergences[0].Repaired {
+		t.Fatalf("Expected %s repaired, got %d %+v", r2, code, r)
+	}
+	if code, r := check(); code != exitOK || !r.OK() {
+		t.Errorf("Expected agreement after repair, got %d %+v", code, r)
+	}
+
+	e, _, _ := testEnv("", map[string]string{"DUR_REPLICAS": r1})
+	if code := run(e, []string{"check"}); code != exitUsage {
+		t.Errorf("Expected usage error with one replica, got %d", code)
+	}
+}
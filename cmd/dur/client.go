@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hrodric0/dur-impl/client"
+	"github.com/hrodric0/dur-impl/config"
+	"github.com/hrodric0/dur-impl/health"
+)
+
+// clientFlags são os flags comuns a get, put e txn
+type clientFlags struct {
+	sequencer *string
+	replicas  *string
+	cid       *string
+	timeout   *time.Duration
+	commit    *time.Duration
+	attempts  *int
+	verbose   *bool
+	trace     *string
+	heartbeat *time.Duration
+	log       logFlags
+}
+
+func newClientFlags(fs *flag.FlagSet) clientFlags {
+	return clientFlags{
+		sequencer: fs.String("sequencer", "localhost:8000", "sequencer address (DUR_SEQUENCER)"),
+		replicas:  fs.String("replicas", "", "comma-separated replica addresses (DUR_REPLICAS)"),
+		cid:       fs.String("cid", "", "client id; random if empty (DUR_CID)"),
+		timeout:   fs.Duration("timeout", 2*time.Second, "timeout of each replica read (DUR_TIMEOUT)"),
+		commit:    fs.Duration("commit-timeout", client.DefaultCommitTimeout, "time to wait for the sequencer's decision; the outcome is then unknown (DUR_COMMIT_TIMEOUT)"),
+		attempts:  fs.Int("attempts", 1, "attempts when the commit aborts on a conflict (DUR_ATTEMPTS)"),
+		verbose:   fs.Bool("v", false, "log protocol messages to stderr; same as --log-level debug (DUR_V)"),
+		trace:     fs.String("trace", "", traceHelp),
+		heartbeat: fs.Duration("heartbeat", 0, "interval of replica heartbeats; reads try unresponsive replicas last; 0 disables (DUR_HEARTBEAT)"),
+		log:       newLogFlags(fs, "error"),
+	}
+}
+
+func clientTopology(t *config.Topology, _ *flag.FlagSet) (map[string]string, error) {
+	vals := map[string]string{"sequencer": t.Sequencer, "replicas": strings.Join(t.ReplicaAddrs(), ",")}
+	if t.Timeouts.Read > 0 {
+		vals["timeout"] = time.Duration(t.Timeouts.Read).String()
+	}
+	if t.Timeouts.Commit > 0 {
+		vals["commit-timeout"] = time.Duration(t.Timeouts.Commit).String()
+	}
+	if t.Retry.Attempts > 0 {
+		vals["attempts"] = strconv.Itoa(t.Retry.Attempts)
+	}
+	if t.Timeouts.Heartbeat > 0 {
+		vals["heartbeat"] = time.Duration(t.Timeouts.Heartbeat).String()
+	}
+	return vals, nil
+}
+
+// client cria o Client dos flags; a função retornada fecha a saída de
+// --trace
+func (f clientFlags) client(e *env) (*client.Client, func() error, error) {
+	reps := splitList(*f.replicas)
+	if len(reps) == 0 {
+		return nil, nil, errors.New("--replicas is required")
+	}
+	if *f.attempts < 1 {
+		return nil, nil, errors.New("--attempts must be at least 1")
+	}
+	if *f.verbose {
+		*f.log.level = "debug"
+	}
+	logger, err := f.log.logger(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracer, closeTrace, err := openTracer(logger, "dur-client", *f.trace)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sel client.Selector
+	if *f.heartbeat > 0 {
+		d := &health.Detector{Addrs: reps, Interval: *f.heartbeat, Logger: logger}
+		d.Start(e.ctx)
+		sel = client.Healthy(d, nil)
+	}
+	return client.New(client.Config{
+		Cid:           *f.cid,
+		Replicas:      reps,
+		Sequencer:     *f.sequencer,
+		Selector:      sel,
+		ReadTimeout:   *f.timeout,
+		CommitTimeout: *f.commit,
+		Retry:         client.RetryPolicy{MaxAttempts: *f.attempts},
+		Tracer:        tracer,
+		Logger:        logger,
+	}), closeTrace, nil
+}
+
+// clientOps são as operações de dur client
+var clientOps = map[string]struct {
+	args string
+	run  func(e *env, c *client.Client, args []string) int
+}{
+	"get": {"KEY", clientGet},
+	"put": {"KEY VALUE", clientPut},
+	"txn": {"[OP...]", clientTxn},
+}
+
+// runClient executa uma operação: dur client get|put|txn [flags] args
+func runClient(e *env, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(e.stderr, "usage: dur client get|put|txn [flags] [args]")
+		return exitUsage
+	}
+	op, ok := clientOps[args[0]]
+	if !ok {
+		fmt.Fprintf(e.stderr, "dur client: unknown operation %q (want get, put or txn)\n", args[0])
+		return exitUsage
+	}
+	fs := newFlagSet(e, "client "+args[0])
+	cf := newClientFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [flags] %s\n", fs.Name(), op.args)
+		if args[0] == "txn" {
+			fmt.Fprint(fs.Output(), txnHelp)
+		}
+		fs.PrintDefaults()
+	}
+	if err := e.parse(fs, args[1:], clientTopology); err != nil {
+		return parseCode(err)
+	}
+	c, closeTrace, err := cf.client(e)
+	if err != nil {
+		return usageError(fs, e.stderr, "%v", err)
+	}
+	defer closeTrace()
+	return op.run(e, c, fs.Args())
+}
+
+func clientGet(e *env, c *client.Client, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(e.stderr, "usage: dur client get [flags] KEY")
+		return exitUsage
+	}
+	val, found, err := c.Begin().Get(args[0])
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dur client get: %v\n", err)
+		return exitError
+	}
+	if !found {
+		fmt.Fprintf(e.stderr, "dur client get: %s: %v\n", args[0], client.ErrNotFound)
+		return exitNotFound
+	}
+	fmt.Fprintf(e.stdout, "%s\n", val)
+	return exitOK
+}
+
+func clientPut(e *env, c *client.Client, args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(e.stderr, "usage: dur client put [flags] KEY VALUE")
+		return exitUsage
+	}
+	return commitOps(e, c, [][]string{{"put", args[0], args[1]}})
+}
+
+const txnHelp = `
+Runs the operations in one transaction and commits. Each OP is one
+argument, or one line of stdin when no OP is given:
+  get KEY | put KEY VALUE | del KEY | putif KEY VALUE VERSION | putnx KEY VALUE
+  incr KEY DELTA | append KEY VALUE | sadd KEY MEMBER... | srem KEY MEMBER...
+  scan START END [LIMIT] | prefix PREFIX [LIMIT]
+
+`
+
+func clientTxn(e *env, c *client.Client, args []string) int {
+	var lines []string
+	if len(args) > 0 {
+		lines = args
+	} else {
+		sc := bufio.NewScanner(e.stdin)
+		for sc.Scan() {
+			lines = append(lines, sc.Text())
+		}
+		if err := sc.Err(); err != nil {
+			fmt.Fprintf(e.stderr, "dur client txn: %v\n", err)
+			return exitError
+		}
+	}
+	var ops [][]string
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		op, err := parseOp(l)
+		if err != nil {
+			fmt.Fprintf(e.stderr, "dur client txn: %v\n", err)
+			return exitUsage
+		}
+		ops = append(ops, op)
+	}
+	if len(ops) == 0 {
+		fmt.Fprintln(e.stderr, "dur client txn: no operations")
+		return exitUsage
+	}
+	return commitOps(e, c, ops)
+}
+
+// opArgs dá o número mínimo e máximo (-1: sem limite) de argumentos de cada op
+var opArgs = map[string][2]int{
+	"get": {1, 1}, "put": {2, 2}, "del": {1, 1}, "putif": {3, 3}, "putnx": {2, 2},
+	"incr": {2, 2}, "append": {2, 2}, "sadd": {2, -1}, "srem": {2, -1},
+	"scan": {2, 3}, "prefix": {1, 2},
+}
+
+// parseOp separa uma op em nome e argumentos; o valor de put, putnx e
+// append é o resto da linha
+func parseOp(line string) ([]string, error) {
+	f := strings.Fields(line)
+	n, ok := opArgs[f[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown operation %q", f[0])
+	}
+	switch f[0] {
+	case "put", "putnx", "append":
+		if len(f) > 3 {
+			rest := strings.TrimSpace(strings.TrimPrefix(line, f[0]))
+			rest = strings.TrimSpace(strings.TrimPrefix(rest, f[1]))
+			f = []string{f[0], f[1], rest}
+		}
+	}
+	if got := len(f) - 1; got < n[0] || (n[1] >= 0 && got > n[1]) {
+		return nil, fmt.Errorf("%q: wrong number of arguments", line)
+	}
+	return f, nil
+}
+
+// apply executa uma op em tx, escrevendo o resultado das leituras em w;
+// com versions, cada leitura mostra também a versão lida
+func apply(tx *client.Transaction, op []string, w io.Writer, versions bool) error {
+	switch op[0] {
+	case "get":
+		val, found, err := tx.Get(op[1])
+		if err != nil {
+			return err
+		}
+		suffix := ""
+		if versions {
+			suffix = " " + readVersion(tx, op[1])
+		}
+		if !found {
+			fmt.Fprintf(w, "%s (not found)%s\n", op[1], suffix)
+		} else {
+			fmt.Fprintf(w, "%s=%s%s\n", op[1], val, suffix)
+		}
+	case "put":
+		tx.Write(op[1], []byte(op[2]))
+	case "del":
+		tx.Delete(op[1])
+	case "putif":
+		v, err := strconv.ParseUint(op[3], 10, 64)
+		if err != nil {
+			return fmt.Errorf("putif %s: bad version %q", op[1], op[3])
+		}
+		tx.WriteIf(op[1], []byte(op[2]), v)
+	case "putnx":
+		tx.WriteIfAbsent(op[1], []byte(op[2]))
+	case "incr":
+		d, err := strconv.ParseInt(op[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("incr %s: bad delta %q", op[1], op[2])
+		}
+		return tx.Increment(op[1], d)
+	case "append":
+		return tx.Append(op[1], []byte(op[2]))
+	case "sadd":
+		return tx.SetAdd(op[1], op[2:]...)
+	case "srem":
+		return tx.SetRemove(op[1], op[2:]...)
+	case "scan", "prefix":
+		start, rest := op[1], op[2:]
+		end := client.PrefixEnd(start)
+		if op[0] == "scan" {
+			end, rest = rest[0], rest[1:]
+		}
+		limit := 0
+		if len(rest) > 0 {
+			n, err := strconv.Atoi(rest[0])
+			if err != nil {
+				return fmt.Errorf("%s: bad limit %q", op[0], rest[0])
+			}
+			limit = n
+		}
+		items, err := tx.Scan(start, end, limit)
+		if err != nil {
+			return err
+		}
+		for _, it := range items {
+			if versions {
+				fmt.Fprintf(w, "%s=%s (v%d)\n", it.Item, it.Value, it.Version)
+			} else {
+				fmt.Fprintf(w, "%s=%s\n", it.Item, it.Value)
+			}
+		}
+	}
+	return nil
+}
+
+// readVersion descreve de onde veio a leitura de item: a versão do rs ou
+// o ws local
+func readVersion(tx *client.Transaction, item string) string {
+	if we, ok := tx.Ws[item]; ok && !we.Commutative() {
+		return "(local)"
+	}
+	if re, ok := tx.Rs[item]; ok {
+		return fmt.Sprintf("(v%d)", re.Version)
+	}
+	return ""
+}
+
+// commitOps executa ops numa transação e confirma, reexecutando conforme
+// --attempts; só a saída da tentativa confirmada é impressa
+func commitOps(e *env, c *client.Client, ops [][]string) int {
+	var out bytes.Buffer
+	tx, err := c.Run(e.ctx, func(tx *client.Transaction) error {
+		out.Reset()
+		for _, op := range ops {
+			if err := apply(tx, op, &out, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var abort *client.AbortError
+	switch {
+	case err == nil:
+		e.stdout.Write(out.Bytes())
+		fmt.Fprintf(e.stdout, "committed tid=%s seq=%d\n", tx.Tid, tx.Decision.Seq)
+		return exitOK
+	case errors.As(err, &abort):
+		fmt.Fprintf(e.stderr, "dur client: %v\n", err)
+		return exitAborted
+	case errors.Is(err, client.ErrTooManyAborts):
+		fmt.Fprintf(e.stderr, "dur client: %v\n", err)
+		return exitAborted
+	default:
+		fmt.Fprintf(e.stderr, "dur client: %v\n", err)
+		return exitError
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/broadcast"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/server"
+)
+
+// startCluster sobe um sequencer e uma réplica em portas livres e retorna
+// as variáveis de ambiente que apontam o cliente para eles
+func startCluster(t *testing.T) map[string]string {
+	rln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	sln, _ := net.Listen("tcp", "localhost:0")
+	go server.NewReplica(rln.Addr().String()).Serve(rln)
+	go broadcast.ServeSequencer(sln, network.TCP, []string{rln.Addr().String()})
+	return map[string]string{"DUR_SEQUENCER": sln.Addr().String(), "DUR_REPLICAS": rln.Addr().String()}
+}
+
+func TestClientPutGet(t *testing.T) {
+	vars := startCluster(t)
+	e, out, errOut := testEnv("", vars)
+	if code := run(e, []string{"client", "put", "k", "hello"}); code != exitOK {
+		t.Fatalf("Expected put to commit, got %d: %s", code, errOut)
+	}
+	e, out, _ = testEnv("", vars)
+	if code := run(e, []string{"client", "get", "k"}); code != exitOK || out.String() != "hello\n" {
+		t.Fatalf("Expected hello, got %d %q", code, out)
+	}
+	e, _, _ = testEnv("", vars)
+	if code := run(e, []string{"client", "get", "missing"}); code != exitNotFound {
+		t.Fatalf("Expected exit %d for a missing key, got %d", exitNotFound, code)
+	}
+}
+
+func TestClientTxn(t *testing.T) {
+	vars := startCluster(t)
+	script := "# contadores\nincr n 2\nput a one two\nsadd s x y\n"
+	e, _, errOut := testEnv(script, vars)
+	if code := run(e, []string{"client", "txn"}); code != exitOK {
+		t.Fatalf("Expected txn from stdin to commit, got %d: %s", code, errOut)
+	}
+	e, out, _ := testEnv("", vars)
+	if code := run(e, []string{"client", "txn", "get n", "get a", "prefix s", "get nope"}); code != exitOK {
+		t.Fatalf("Expected read txn to commit, got %d", code)
+	}
+	want := "n=2\na=one two\ns=[\"x\",\"y\"]\nnope (not found)\n"
+	if !strings.HasPrefix(out.String(), want) || !strings.Contains(out.String(), "committed") {
+		t.Errorf("Expected output %q, got %q", want, out)
+	}
+
+	e, _, errOut = testEnv("", vars)
+	if code := run(e, []string{"client", "txn", "putnx a again"}); code != exitAborted || !strings.Contains(errOut.String(), "precondition") {
+		t.Errorf("Expected precondition abort, got %d: %s", code, errOut)
+	}
+	e, _, _ = testEnv("", vars)
+	if code := run(e, []string{"client", "txn", "frobnicate a"}); code != exitUsage {
+		t.Errorf("Expected usage error for an unknown op, got %d", code)
+	}
+}
+
+func TestClientLogFlags(t *testing.T) {
+	vars := startCluster(t)
+	e, _, errOut := testEnv("", vars)
+	if code := run(e, []string{"client", "put", "--log-level", "debug", "--log-format", "json", "k", "secret"}); code != exitOK {
+		t.Fatalf("Expected put to commit, got %d: %s", code, errOut)
+	}
+	if !strings.Contains(errOut.String(), `"component":"client"`) || !strings.Contains(errOut.String(), `"msg":"commit decision"`) {
+		t.Errorf("Expected JSON client logs, got %q", errOut)
+	}
+	if strings.Contains(errOut.String(), "secret") {
+		t.Errorf("Expected values redacted, got %q", errOut)
+	}
+	e, _, errOut = testEnv("", vars)
+	if code := run(e, []string{"client", "get", "k"}); code != exitOK || errOut.Len() != 0 {
+		t.Errorf("Expected a quiet get by default, got %d: %q", code, errOut)
+	}
+	e, _, _ = testEnv("", vars)
+	if code := run(e, []string{"client", "get", "--log-level", "loud", "k"}); code != exitUsage {
+		t.Errorf("Expected usage error for a bad level, got %d", code)
+	}
+}
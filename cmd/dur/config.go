@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hrodric0/dur-impl/config"
+)
+
+// newFlagSet cria o FlagSet de um subcomando, já com --config e --topology
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("dur "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.String("config", "", "JSON file of flag values for this process (DUR_CONFIG)")
+	fs.String("topology", "", "cluster topology file shared by all processes (DUR_TOPOLOGY)")
+	return fs
+}
+
+// fromTopology deriva valores de flags de uma topologia
+type fromTopology func(t *config.Topology, fs *flag.FlagSet) (map[string]string, error)
+
+// parse lê args em fs e completa os flags não informados com a variável
+// DUR_<FLAG>, depois com o arquivo de --config e por fim com a topologia
+// de --topology, via topo. Chaves do arquivo que não são flags do
+// subcomando são ignoradas, para que um arquivo sirva a vários processos.
+//
+// Os dois arquivos não se sobrepõem: a topologia descreve o cluster
+// (endereços, diretórios de dados, prazos e retry) e --config só os
+// ajustes deste processo. Com os dois, uma chave de --config que a
+// topologia também define é erro, em vez de uma vencer a outra em silêncio.
+func (e *env) parse(fs *flag.FlagSet, args []string, topo fromTopology) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.defaults(fs, topo); err != nil {
+		fmt.Fprintf(e.stderr, "%s: %v\n", fs.Name(), err)
+		return err
+	}
+	return nil
+}
+
+// defaults completa os flags que não vieram da linha de comando
+func (e *env) defaults(fs *flag.FlagSet, topo fromTopology) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || err != nil {
+			return
+		}
+		name := envName(f.Name)
+		if v := e.getenv(name); v != "" {
+			if err = fs.Set(f.Name, v); err != nil {
+				err = fmt.Errorf("%s: %w", name, err)
+				return
+			}
+			set[f.Name] = true
+		}
+	})
+	if err != nil {
+		return err
+	}
+	var vals map[string]string
+	topoPath := fs.Lookup("topology").Value.String()
+	if topoPath != "" && topo != nil {
+		t, err := config.Load(topoPath)
+		if err != nil {
+			return err
+		}
+		if vals, err = topo(t, fs); err != nil {
+			return fmt.Errorf("%s: %w", topoPath, err)
+		}
+	}
+	if path := fs.Lookup("config").Value.String(); path != "" {
+		file, err := loadConfig(path)
+		if err != nil {
+			return err
+		}
+		for _, name := range slices.Sorted(maps.Keys(file)) {
+			if _, dup := vals[name]; dup && fs.Lookup(name) != nil {
+				return fmt.Errorf("%s: %s is described by the topology %s; remove it from the config file", path, name, topoPath)
+			}
+		}
+		if err := fill(fs, set, file, path); err != nil {
+			return err
+		}
+	}
+	return fill(fs, set, vals, topoPath)
+}
+
+// fill aplica vals aos flags de fs ainda não definidos, marcando-os em set
+func fill(fs *flag.FlagSet, set map[string]bool, vals map[string]string, source string) error {
+	for name, v := range vals {
+		if set[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, v); err != nil {
+			return fmt.Errorf("%s: %s: %w", source, name, err)
+		}
+		set[name] = true
+	}
+	return nil
+}
+
+// envName é a variável de ambiente do flag name
+func envName(name string) string {
+	return "DUR_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loadConfig lê um objeto JSON de flag → valor. Listas viram valores
+// separados por vírgula.
+func loadConfig(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	out := make(map[string]string, len(obj))
+	for k, v := range obj {
+		s, err := flagValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, k, err)
+		}
+		out[k] = s
+	}
+	return out, nil
+}
+
+func flagValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, x := range v {
+			s, ok := x.(string)
+			if !ok {
+				return "", fmt.Errorf("list entries must be strings, got %v", x)
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+// splitList separa uma lista de endereços por vírgula
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// parseCode traduz o erro de parse no código de saída; -h sai com sucesso
+func parseCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitUsage
+}
+
+// usageError reporta um erro de uso com o resumo dos flags
+func usageError(fs *flag.FlagSet, w io.Writer, format string, args ...any) int {
+	fmt.Fprintf(w, "%s: %s\n", fs.Name(), fmt.Sprintf(format, args...))
+	fs.Usage()
+	return exitUsage
+}
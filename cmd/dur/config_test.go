@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dur.json")
+	os.WriteFile(path, []byte(`{"listen": "file:1", "replicas": ["a", "b"], "data-dir": "/var/dur", "sequencer": "ignored"}`), 0o644)
+	e, _, _ := testEnv("", map[string]string{"DUR_CONFIG": path, "DUR_REPLICAS": "env1,env2"})
+
+	fs := newFlagSet(e, "test")
+	listen := fs.String("listen", "default", "")
+	replicas := fs.String("replicas", "", "")
+	dataDir := fs.String("data-dir", "", "")
+	if err := e.parse(fs, []string{"--listen", "flag:1"}, nil); err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if *listen != "flag:1" {
+		t.Errorf("Expected flag to win, got %s", *listen)
+	}
+	if *replicas != "env1,env2" {
+		t.Errorf("Expected env to beat the file, got %s", *replicas)
+	}
+	if *dataDir != "/var/dur" {
+		t.Errorf("Expected value from file, got %s", *dataDir)
+	}
+	if got := splitList(" a, ,b "); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("Expected [a b], got %v", got)
+	}
+}
+
+func TestParseBadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dur.json")
+	os.WriteFile(path, []byte(`{"attempts": "many"}`), 0o644)
+	e, _, _ := testEnv("", nil)
+	fs := newFlagSet(e, "test")
+	fs.Int("attempts", 1, "")
+	if err := e.parse(fs, []string{"--config", path}, nil); err == nil {
+		t.Fatalf("Expected error for a non-integer attempts")
+	}
+	e, _, _ = testEnv("", map[string]string{"DUR_ATTEMPTS": "x"})
+	fs = newFlagSet(e, "test")
+	fs.Int("attempts", 1, "")
+	if err := e.parse(fs, nil, nil); err == nil {
+		t.Fatalf("Expected error for a bad DUR_ATTEMPTS")
+	}
+}
+
+func TestParseTopology(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluster.json")
+	os.WriteFile(path, []byte(`{
+		"sequencer": "localhost:7000",
+		"replicas": [{"name": "r1", "addr": "localhost:7001"}, {"name": "r2", "addr": "localhost:7002", "dataDir": "/data/r2"}],
+		"timeouts": {"replica": "3s", "catchUp": "20s", "heartbeat": "250ms"},
+		"retry": {"attempts": 4}
+	}`), 0o644)
+
+	e, _, _ := testEnv("", map[string]string{"DUR_TOPOLOGY": path})
+	fs := newFlagSet(e, "replica")
+	listen := fs.String("listen", "localhost:8001", "")
+	dataDir := fs.String("data-dir", "", "")
+	peers := fs.String("peers", "", "")
+	fs.String("name", "", "")
+	catchUp := fs.Duration("catch-up-timeout", time.Second, "")
+	if err := e.parse(fs, []string{"--name", "r2"}, replicaTopology); err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if *listen != "localhost:7002" || *dataDir != "/data/r2" || *peers != "localhost:7001" || *catchUp != 20*time.Second {
+		t.Errorf("Expected r2 from topology, got listen=%s data-dir=%s peers=%s catch-up-timeout=%s", *listen, *dataDir, *peers, *catchUp)
+	}
+
+	fs = newFlagSet(e, "sequencer")
+	fs.String("listen", "", "")
+	fs.String("replicas", "", "")
+	replicaTimeout := fs.Duration("replica-timeout", time.Second, "")
+	heartbeat := fs.Duration("heartbeat", time.Second, "")
+	if err := e.parse(fs, nil, sequencerTopology); err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if *replicaTimeout != 3*time.Second || *heartbeat != 250*time.Millisecond {
+		t.Errorf("Expected timeouts from topology, got replica-timeout=%s heartbeat=%s", *replicaTimeout, *heartbeat)
+	}
+
+	fs = newFlagSet(e, "client")
+	cf := newClientFlags(fs)
+	if err := e.parse(fs, []string{"--sequencer", "other:1"}, clientTopology); err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if *cf.sequencer != "other:1" || *cf.replicas != "localhost:7001,localhost:7002" || *cf.attempts != 4 {
+		t.Errorf("Expected flag to beat topology, got sequencer=%s replicas=%s attempts=%d", *cf.sequencer, *cf.replicas, *cf.attempts)
+	}
+
+	e, _, errOut := testEnv("", map[string]string{"DUR_TOPOLOGY": path})
+	if code := run(e, []string{"replica"}); code != exitUsage || !strings.Contains(errOut.String(), "--name is required") {
+		t.Errorf("Expected usage error without --name, got %d: %s", code, errOut)
+	}
+	os.WriteFile(path, []byte(`{"sequencer": "localhost:7000", "replicas": []}`), 0o644)
+	e, _, errOut = testEnv("", map[string]string{"DUR_TOPOLOGY": path})
+	if code := run(e, []string{"client", "get", "x"}); code != exitUsage || !strings.Contains(errOut.String(), "at least one replica") {
+		t.Errorf("Expected validation error, got %d: %s", code, errOut)
+	}
+}
+
+func TestParseConfigOverlapsTopology(t *testing.T) {
+	dir := t.TempDir()
+	topo := filepath.Join(dir, "cluster.json")
+	os.WriteFile(topo, []byte(`{"sequencer": "localhost:7000", "replicas": [{"name": "r1", "addr": "localhost:7001"}]}`), 0o644)
+	conf := filepath.Join(dir, "dur.json")
+	os.WriteFile(conf, []byte(`{"log-level": "debug", "sequencer": "localhost:9000"}`), 0o644)
+
+	e, _, _ := testEnv("", map[string]string{"DUR_TOPOLOGY": topo, "DUR_CONFIG": conf})
+	fs := newFlagSet(e, "client")
+	newClientFlags(fs)
+	err := e.parse(fs, nil, clientTopology)
+	if err == nil || !strings.Contains(err.Error(), "sequencer is described by the topology") {
+		t.Fatalf("Expected an overlap error for sequencer, got %v", err)
+	}
+
+	os.WriteFile(conf, []byte(`{"log-level": "debug"}`), 0o644)
+	fs = newFlagSet(e, "client")
+	cf := newClientFlags(fs)
+	if err := e.parse(fs, nil, clientTopology); err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if level := fs.Lookup("log-level").Value.String(); level != "debug" || *cf.sequencer != "localhost:7000" {
+		t.Errorf("Expected log-level from the config and sequencer from the topology, got %s and %s", level, *cf.sequencer)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+)
+
+// startHTTP expõe mux em addr, em segundo plano; com addr vazio não faz
+// nada. A função retornada encerra o servidor.
+func startHTTP(e *env, logger *slog.Logger, name, addr string, mux *http.ServeMux) (func(context.Context) error, error) {
+	if addr == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("http: %w", err)
+	}
+	srv := &http.Server{Handler: mux}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(e.stderr, "dur %s: http: %v\n", name, err)
+		}
+	}()
+	logger.Info("http listening", "component", name, "url", "http://"+ln.Addr().String())
+	return srv.Shutdown, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+
+	"github.com/hrodric0/dur-impl/logging"
+)
+
+// logFlags são os flags de log comuns aos subcomandos
+type logFlags struct {
+	level  *string
+	format *string
+}
+
+// newLogFlags registra --log-level, com padrão level, e --log-format
+func newLogFlags(fs *flag.FlagSet, level string) logFlags {
+	return logFlags{
+		level:  fs.String("log-level", level, "minimum log level: debug, info, warn, error or off (DUR_LOG_LEVEL)"),
+		format: fs.String("log-format", "text", "log format: text or json (DUR_LOG_FORMAT)"),
+	}
+}
+
+// logger cria o logger dos flags, que escreve na saída de erro
+func (f logFlags) logger(e *env) (*slog.Logger, error) {
+	level, err := logging.ParseLevel(*f.level)
+	if err != nil {
+		return nil, err
+	}
+	return logging.New(e.stderr, level, *f.format)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// testEnv cria um env com saídas em memória e variáveis de vars
+func testEnv(stdin string, vars map[string]string) (*env, *bytes.Buffer, *bytes.Buffer) {
+	var out, errOut bytes.Buffer
+	e := &env{
+		ctx:    context.Background(),
+		stdin:  strings.NewReader(stdin),
+		stdout: &out,
+		stderr: &errOut,
+		getenv: func(k string) string { return vars[k] },
+	}
+	return e, &out, &errOut
+}
+
+func TestRunUsage(t *testing.T) {
+	cases := []struct {
+		args []string
+		code int
+	}{
+		{nil, exitUsage},
+		{[]string{"help"}, exitOK},
+		{[]string{"bogus"}, exitUsage},
+		{[]string{"sequencer"}, exitUsage},
+		{[]string{"sequencer", "--nope"}, exitUsage},
+		{[]string{"sequencer", "-h"}, exitOK},
+		{[]string{"replica", "extra"}, exitUsage},
+		{[]string{"client"}, exitUsage},
+		{[]string{"client", "del", "x"}, exitUsage},
+		{[]string{"client", "get", "x"}, exitUsage},
+		{[]string{"client", "get", "--replicas", "r1"}, exitUsage},
+	}
+	for _, c := range cases {
+		e, _, _ := testEnv("", nil)
+		if got := run(e, c.args); got != c.code {
+			t.Errorf("dur %v: expected exit %d, got %d", c.args, c.code, got)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hrodric0/dur-impl/broadcast"
+	"github.com/hrodric0/dur-impl/config"
+	"github.com/hrodric0/dur-impl/health"
+	"github.com/hrodric0/dur-impl/server"
+)
+
+// runSequencer atende commits até SIGINT/SIGTERM, e então espera os
+// commits enfileirados serem respondidos
+func runSequencer(e *env, args []string) int {
+	fs := newFlagSet(e, "sequencer")
+	listen := fs.String("listen", "localhost:8000", "address to listen on (DUR_LISTEN)")
+	replicas := fs.String("replicas", "", "comma-separated replica addresses (DUR_REPLICAS)")
+	grace := fs.Duration("shutdown-timeout", defaultGrace, "time to drain queued commits on shutdown (DUR_SHUTDOWN_TIMEOUT)")
+	httpAddr := fs.String("http-addr", "", "address of the HTTP endpoint with /metrics, /admin, /healthz and /readyz; empty disables it (DUR_HTTP_ADDR)")
+	heartbeat := fs.Duration("heartbeat", health.DefaultInterval, "interval of the replica heartbeats; commits fail fast while a replica misses 3 or is not ready; 0 disables (DUR_HEARTBEAT)")
+	replicaTimeout := fs.Duration("replica-timeout", broadcast.DefaultReplicaTimeout, "time to wait for each replica's decision before skipping it for the commit (DUR_REPLICA_TIMEOUT)")
+	traceTo := fs.String("trace", "", traceHelp)
+	lf := newLogFlags(fs, "info")
+	if err := e.parse(fs, args, sequencerTopology); err != nil {
+		return parseCode(err)
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, e.stderr, "unexpected arguments %v", fs.Args())
+	}
+	logger, err := lf.logger(e)
+	if err != nil {
+		return usageError(fs, e.stderr, "%v", err)
+	}
+	reps := splitList(*replicas)
+	if len(reps) == 0 {
+		return usageError(fs, e.stderr, "--replicas is required")
+	}
+	tracer, closeTrace, err := openTracer(logger, "dur-sequencer", *traceTo)
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dur sequencer: %v\n", err)
+		return exitError
+	}
+	defer closeTrace()
+	seq := broadcast.NewSequencer(*listen, reps)
+	seq.Tracer = tracer
+	seq.Logger = logger
+	seq.ReplicaTimeout = *replicaTimeout
+	if *heartbeat > 0 {
+		seq.Detector = &health.Detector{Addrs: reps, Interval: *heartbeat, Logger: logger}
+		seq.Detector.Start(e.ctx)
+	}
+	if err := seq.Start(context.Background()); err != nil {
+		fmt.Fprintf(e.stderr, "dur sequencer: %v\n", err)
+		return exitError
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", seq.Metrics())
+	mux.Handle("/admin/", seq.Admin())
+	mux.Handle("GET /healthz", seq.Health())
+	mux.Handle("GET /readyz", seq.Health())
+	stopHTTP, err := startHTTP(e, logger, "sequencer", *httpAddr, mux)
+	if err != nil {
+		seq.Shutdown(context.Background())
+		fmt.Fprintf(e.stderr, "dur sequencer: %v\n", err)
+		return exitError
+	}
+	<-e.ctx.Done()
+	return shutdown(e, "sequencer", *grace, seq.Shutdown, stopHTTP)
+}
+
+// runReplica atende leituras e commits até SIGINT/SIGTERM, e então espera
+// os pedidos em andamento e fecha o log. Com --data-dir
+// os commits são gravados em disco e recuperados no próximo início; com
+// --peers a réplica só fica pronta depois de alcançar as outras.
+func runReplica(e *env, args []string) int {
+	fs := newFlagSet(e, "replica")
+	listen := fs.String("listen", "localhost:8001", "address to listen on (DUR_LISTEN)")
+	dataDir := fs.String("data-dir", "", "directory for the commit log; empty keeps state in memory (DUR_DATA_DIR)")
+	fs.String("name", "", "replica name in the --topology file (DUR_NAME)")
+	grace := fs.Duration("shutdown-timeout", defaultGrace, "time to finish in-flight requests on shutdown (DUR_SHUTDOWN_TIMEOUT)")
+	peers := fs.String("peers", "", "comma-separated addresses of the other replicas, to catch up with before reporting ready (DUR_PEERS)")
+	catchUp := fs.Duration("catch-up-timeout", server.DefaultCatchUpTimeout, "report ready at the local seq if no peer answers within this time (DUR_CATCH_UP_TIMEOUT)")
+	httpAddr := fs.String("http-addr", "", "address of the HTTP endpoint with /metrics, /admin, /healthz and /readyz; empty disables it (DUR_HTTP_ADDR)")
+	traceTo := fs.String("trace", "", traceHelp)
+	lf := newLogFlags(fs, "info")
+	if err := e.parse(fs, args, replicaTopology); err != nil {
+		return parseCode(err)
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, e.stderr, "unexpected arguments %v", fs.Args())
+	}
+	logger, err := lf.logger(e)
+	if err != nil {
+		return usageError(fs, e.stderr, "%v", err)
+	}
+	tracer, closeTrace, err := openTracer(logger, "dur-replica", *traceTo)
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dur replica: %v\n", err)
+		return exitError
+	}
+	defer closeTrace()
+	rep := server.NewReplica(*listen)
+	if *dataDir != "" {
+		var err error
+		if rep, err = server.OpenReplica(*listen, *dataDir); err != nil {
+			fmt.Fprintf(e.stderr, "dur replica: %v\n", err)
+			return exitError
+		}
+	}
+	rep.Tracer = tracer
+	rep.Logger = logger
+	rep.Peers = splitList(*peers)
+	rep.CatchUpTimeout = *catchUp
+	if err := rep.Start(context.Background()); err != nil {
+		rep.Close()
+		fmt.Fprintf(e.stderr, "dur replica: %v\n", err)
+		return exitError
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", rep.Metrics())
+	mux.Handle("/admin/", rep.Admin())
+	mux.Handle("GET /healthz", rep.Health())
+	mux.Handle("GET /readyz", rep.Health())
+	stopHTTP, err := startHTTP(e, logger, "replica", *httpAddr, mux)
+	if err != nil {
+		rep.Shutdown(context.Background())
+		fmt.Fprintf(e.stderr, "dur replica: %v\n", err)
+		return exitError
+	}
+	<-e.ctx.Done()
+	return shutdown(e, "replica", *grace, rep.Shutdown, stopHTTP)
+}
+
+// defaultGrace é o prazo padrão de --shutdown-timeout
+const defaultGrace = 10 * time.Second
+
+// shutdown encerra os servidores, em ordem, dentro do prazo grace
+func shutdown(e *env, name string, grace time.Duration, stops ...func(context.Context) error) int {
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	code := exitOK
+	for _, stop := range stops {
+		if err := stop(ctx); err != nil {
+			fmt.Fprintf(e.stderr, "dur %s: shutdown: %v\n", name, err)
+			code = exitError
+		}
+	}
+	return code
+}
+
+func sequencerTopology(t *config.Topology, _ *flag.FlagSet) (map[string]string, error) {
+	vals := map[string]string{"listen": t.Sequencer, "replicas": strings.Join(t.ReplicaAddrs(), ",")}
+	if t.Timeouts.Replica > 0 {
+		vals["replica-timeout"] = time.Duration(t.Timeouts.Replica).String()
+	}
+	if t.Timeouts.Heartbeat > 0 {
+		vals["heartbeat"] = time.Duration(t.Timeouts.Heartbeat).String()
+	}
+	return vals, nil
+}
+
+// replicaTopology usa a réplica de nome --name; as demais são os pares
+func replicaTopology(t *config.Topology, fs *flag.FlagSet) (map[string]string, error) {
+	name := fs.Lookup("name").Value.String()
+	if name == "" {
+		return nil, errors.New("--name is required with --topology")
+	}
+	r, ok := t.Replica(name)
+	if !ok {
+		return nil, fmt.Errorf("no replica named %q", name)
+	}
+	var peers []string
+	for _, p := range t.Replicas {
+		if p.Name != name {
+			peers = append(peers, p.Addr)
+		}
+	}
+	vals := map[string]string{"listen": r.Addr, "peers": strings.Join(peers, ",")}
+	if r.DataDir != "" {
+		vals["data-dir"] = r.DataDir
+	}
+	if t.Timeouts.CatchUp > 0 {
+		vals["catch-up-timeout"] = time.Duration(t.Timeouts.CatchUp).String()
+	}
+	return vals, nil
+}
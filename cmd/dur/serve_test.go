@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// freeAddr reserva e libera uma porta local
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// serve roda um subcomando de servidor até o cancelamento retornado
+func serve(t *testing.T, args ...string) (stop func() int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	e, _, errOut := testEnv("", nil)
+	e.ctx = ctx
+	code := make(chan int, 1)
+	go func() { code <- run(e, args) }()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case c := <-code:
+		t.Fatalf("dur %v exited early with %d: %s", args, c, errOut)
+	default:
+	}
+	return func() int {
+		cancel()
+		return <-code
+	}
+}
+
+func TestServeUntilSignal(t *testing.T) {
+	dir := t.TempDir()
+	rAddr, sAddr, httpAddr := freeAddr(t), freeAddr(t), freeAddr(t)
+	stopReplica := serve(t, "replica", "--listen", rAddr, "--data-dir", dir)
+	stopSequencer := serve(t, "sequencer", "--listen", sAddr, "--replicas", rAddr, "--http-addr", httpAddr)
+	vars := map[string]string{"DUR_SEQUENCER": sAddr, "DUR_REPLICAS": rAddr}
+
+	e, _, errOut := testEnv("", vars)
+	if code := run(e, []string{"client", "put", "k", "kept"}); code != exitOK {
+		t.Fatalf("Expected put to commit, got %d: %s", code, errOut)
+	}
+	resp, err := http.Get("http://" + httpAddr + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "dur_sequencer_commits_total 1\n") {
+		t.Errorf("Expected one commit in /metrics, got:\n%s", body)
+	}
+	if code := stopSequencer(); code != exitOK {
+		t.Errorf("Expected sequencer to exit 0, got %d", code)
+	}
+	if code := stopReplica(); code != exitOK {
+		t.Errorf("Expected replica to exit 0, got %d", code)
+	}
+
+	// mesma porta e mesmo diretório: a réplica volta com o commit
+	stopReplica = serve(t, "replica", "--listen", rAddr, "--data-dir", dir)
+	defer stopReplica()
+	e, out, _ := testEnv("", vars)
+	if code := run(e, []string{"client", "get", "k"}); code != exitOK || out.String() != "kept\n" {
+		t.Errorf("Expected k=kept after restart, got %d %q", code, out)
+	}
+}
+
+// waitReady espera GET /readyz em httpAddr responder 200
+func waitReady(t *testing.T, httpAddr string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get("http://" + httpAddr + "/readyz")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s never became ready: %v", httpAddr, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReplicaCatchesUpBeforeReady(t *testing.T) {
+	r1, r2, s1, s2, h1, h2 := freeAddr(t), freeAddr(t), freeAddr(t), freeAddr(t), freeAddr(t), freeAddr(t)
+	defer serve(t, "replica", "--listen", r1, "--http-addr", h1)()
+	defer serve(t, "sequencer", "--listen", s1, "--replicas", r1)()
+	waitReady(t, h1)
+	e, _, errOut := testEnv("", map[string]string{"DUR_SEQUENCER": s1, "DUR_REPLICAS": r1})
+	if code := run(e, []string{"client", "put", "k", "before"}); code != exitOK {
+		t.Fatalf("Expected put to commit, got %d: %s", code, errOut)
+	}
+
+	// r2 entra depois e copia o estado de r1 antes de ficar pronta
+	defer serve(t, "replica", "--listen", r2, "--peers", r1, "--http-addr", h2)()
+	waitReady(t, h2)
+	defer serve(t, "sequencer", "--listen", s2, "--replicas", r1+","+r2, "--heartbeat", "10ms")()
+	vars := map[string]string{"DUR_SEQUENCER": s2, "DUR_REPLICAS": r2}
+	e, _, errOut = testEnv("", vars)
+	if code := run(e, []string{"client", "put", "j", "after"}); code != exitOK {
+		t.Fatalf("Expected put through both replicas to commit, got %d: %s", code, errOut)
+	}
+	e, out, _ := testEnv("", vars)
+	if code := run(e, []string{"client", "txn", "get k", "get j"}); code != exitOK || !strings.HasPrefix(out.String(), "k=before\nj=after\n") {
+		t.Errorf("Expected both keys on r2, got %d %q", code, out)
+	}
+}
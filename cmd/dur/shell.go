@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/hrodric0/dur-impl/client"
+)
+
+const shellHelp = `commands:
+  begin [NAME]      start a transaction and make it current (default name: tx)
+  use NAME          switch to an open transaction
+  get, put, del, putif, putnx, incr, append, sadd, srem, scan, prefix
+                    same operations as 'dur client txn', on the current transaction
+  show              read set (with versions), ranges, preconditions and write set
+  commit            commit the current transaction and show the decision
+  status            ask the replicas for the decision of the current transaction
+  abort             drop the current transaction
+  list              list the open transactions
+  help              this text
+  quit              leave the shell
+`
+
+// shell guarda as transações abertas; várias transações com nomes
+// diferentes permitem reproduzir conflitos num único terminal
+type shell struct {
+	c    *client.Client
+	w    io.Writer
+	txs  map[string]*client.Transaction
+	name string // transação corrente
+}
+
+// runShell lê comandos linha a linha até quit ou fim da entrada
+func runShell(e *env, args []string) int {
+	fs := newFlagSet(e, "shell")
+	cf := newClientFlags(fs)
+	if err := e.parse(fs, args, clientTopology); err != nil {
+		return parseCode(err)
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, e.stderr, "unexpected arguments %v", fs.Args())
+	}
+	c, closeTrace, err := cf.client(e)
+	if err != nil {
+		return usageError(fs, e.stderr, "%v", err)
+	}
+	defer closeTrace()
+	sh := &shell{c: c, w: e.stdout, txs: map[string]*client.Transaction{}}
+	sc := bufio.NewScanner(e.stdin)
+	for {
+		fmt.Fprint(e.stdout, sh.prompt())
+		if !sc.Scan() {
+			break
+		}
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == "quit" || line == "exit" {
+			return exitOK
+		}
+		if err := sh.exec(line); err != nil {
+			fmt.Fprintf(e.stdout, "error: %v\n", err)
+		}
+	}
+	fmt.Fprintln(e.stdout)
+	if err := sc.Err(); err != nil {
+		fmt.Fprintf(e.stderr, "dur shell: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func (sh *shell) prompt() string {
+	if sh.name == "" {
+		return "dur> "
+	}
+	return "dur(" + sh.name + ")> "
+}
+
+// exec executa uma linha do shell
+func (sh *shell) exec(line string) error {
+	f := strings.Fields(line)
+	switch f[0] {
+	case "help":
+		fmt.Fprint(sh.w, shellHelp)
+		return nil
+	case "begin":
+		name := "tx"
+		if len(f) > 1 {
+			name = f[1]
+		}
+		if _, open := sh.txs[name]; open {
+			return fmt.Errorf("transaction %s is already open; commit or abort it first", name)
+		}
+		tx := sh.c.Begin()
+		sh.txs[name], sh.name = tx, name
+		fmt.Fprintf(sh.w, "begin %s tid=%s snapshot=%d\n", name, tx.Tid, tx.Snapshot)
+		return nil
+	case "use":
+		if len(f) != 2 {
+			return errors.New("usage: use NAME")
+		}
+		if _, open := sh.txs[f[1]]; !open {
+			return fmt.Errorf("no open transaction %s", f[1])
+		}
+		sh.name = f[1]
+		return nil
+	case "list":
+		for _, n := range sortedKeys(sh.txs) {
+			fmt.Fprintf(sh.w, "%s tid=%s rs=%d ws=%d\n", n, sh.txs[n].Tid, len(sh.txs[n].Rs), len(sh.txs[n].Ws))
+		}
+		return nil
+	}
+
+	tx, ok := sh.txs[sh.name]
+	if !ok {
+		return errors.New("no current transaction; use begin")
+	}
+	switch f[0] {
+	case "show":
+		show(sh.w, tx)
+	case "abort":
+		sh.close()
+		fmt.Fprintf(sh.w, "aborted %s\n", tx.Tid)
+	case "commit":
+		ok, err := tx.Commit()
+		if errors.Is(err, client.ErrAmbiguousCommit) {
+			fmt.Fprintf(sh.w, "unknown outcome for %s: %v; try status or commit again\n", tx.Tid, err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		printDecision(sh.w, tx, ok)
+		sh.close()
+	case "status":
+		st, err := tx.QueryStatus()
+		if err != nil {
+			return err
+		}
+		if !st.Known {
+			fmt.Fprintf(sh.w, "no replica knows %s\n", tx.Tid)
+			return nil
+		}
+		tx.Decision = st.Decision
+		printDecision(sh.w, tx, st.Decision.Commit)
+	default:
+		op, err := parseOp(line)
+		if err != nil {
+			return err
+		}
+		return apply(tx, op, sh.w, true)
+	}
+	return nil
+}
+
+// close descarta a transação corrente
+func (sh *shell) close() {
+	delete(sh.txs, sh.name)
+	sh.name = ""
+}
+
+func printDecision(w io.Writer, tx *client.Transaction, ok bool) {
+	d := tx.Decision
+	if ok {
+		fmt.Fprintf(w, "committed %s seq=%d\n", tx.Tid, d.Seq)
+		return
+	}
+	fmt.Fprintf(w, "aborted %s: %s", tx.Tid, d.Reason)
+	if d.Item != "" {
+		fmt.Fprintf(w, " on %s", d.Item)
+	}
+	if d.Detail != "" {
+		fmt.Fprintf(w, " (%s)", d.Detail)
+	}
+	fmt.Fprintln(w)
+}
+
+// show mostra o que a transação vai certificar e gravar
+func show(w io.Writer, tx *client.Transaction) {
+	fmt.Fprintf(w, "tid=%s snapshot=%d\n", tx.Tid, tx.Snapshot)
+	fmt.Fprintln(w, "rs:")
+	for _, k := range sortedKeys(tx.Rs) {
+		re := tx.Rs[k]
+		if re.Absent {
+			fmt.Fprintf(w, "  %s v%d absent\n", k, re.Version)
+		} else {
+			fmt.Fprintf(w, "  %s v%d = %s\n", k, re.Version, re.Value)
+		}
+	}
+	for _, r := range tx.Ranges {
+		end := r.End
+		if end == "" {
+			end = "∞"
+		}
+		fmt.Fprintf(w, "  range [%s, %s) @%d\n", r.Start, end, r.Seq)
+	}
+	for _, p := range tx.Pre {
+		if p.Absent {
+			fmt.Fprintf(w, "  pre %s absent\n", p.Item)
+		} else {
+			fmt.Fprintf(w, "  pre %s v%d\n", p.Item, p.Version)
+		}
+	}
+	fmt.Fprintln(w, "ws:")
+	for _, k := range sortedKeys(tx.Ws) {
+		we := tx.Ws[k]
+		switch {
+		case we.Op == "":
+			fmt.Fprintf(w, "  %s put %s\n", k, we.Value)
+		case we.Delta != 0:
+			fmt.Fprintf(w, "  %s %s %d\n", k, we.Op, we.Delta)
+		case len(we.Add) > 0 || len(we.Remove) > 0:
+			fmt.Fprintf(w, "  %s %s +%v -%v\n", k, we.Op, we.Add, we.Remove)
+		default:
+			fmt.Fprintf(w, "  %s %s %s\n", k, we.Op, we.Value)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
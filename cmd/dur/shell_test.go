@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestShellConflict reproduz TestCommitAndAbort à mão: duas transações
+// leem x, a primeira confirma e a segunda aborta por leitura obsoleta.
+func TestShellConflict(t *testing.T) {
+	vars := startCluster(t)
+	script := `begin t1
+get x
+put x one
+begin t2
+get x
+put x two
+show
+use t1
+commit
+use t2
+commit
+begin
+get x
+get nope
+incr n 3
+show
+abort
+commit
+quit
+`
+	e, out, _ := testEnv(script, vars)
+	if code := run(e, []string{"shell"}); code != exitOK {
+		t.Fatalf("Expected exit 0, got %d", code)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"x=init (v0)",
+		"  x v0 = init\n",
+		"  x put two\n",
+		"committed ",
+		"aborted ",
+		": stale-read on x",
+		"x=one (v1)",
+		"nope (not found) (v0)",
+		"  nope v0 absent\n",
+		"  n incr 3\n",
+		"error: no current transaction",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in shell output:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "committed ") > strings.Index(got, "aborted ") {
+		t.Errorf("Expected t1 to commit before t2 aborts:\n%s", got)
+	}
+}
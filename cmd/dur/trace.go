@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/hrodric0/dur-impl/trace"
+)
+
+// traceHelp é a ajuda comum dos flags --trace
+const traceHelp = "export spans: 'log' logs them at info level, any other value is a file that receives OTLP JSON lines; empty disables tracing (DUR_TRACE)"
+
+// openTracer cria o Tracer de service conforme o valor de --trace; com
+// "log", os spans vão para logger. A função retornada fecha o arquivo de
+// saída.
+func openTracer(logger *slog.Logger, service, spec string) (*trace.Tracer, func() error, error) {
+	switch spec {
+	case "":
+		return nil, func() error { return nil }, nil
+	case "log":
+		return trace.New(service, trace.LogExporter{Logger: logger}), func() error { return nil }, nil
+	}
+	f, err := os.OpenFile(spec, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("trace: %w", err)
+	}
+	return trace.New(service, trace.NewJSONExporter(f)), f.Close, nil
+}
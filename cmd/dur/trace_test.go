@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClientTrace(t *testing.T) {
+	vars := startCluster(t)
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	e, _, errOut := testEnv("", vars)
+	if code := run(e, []string{"client", "put", "--trace", path, "k", "v"}); code != exitOK {
+		t.Fatalf("Expected put to commit, got %d: %s", code, errOut)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) < 2 || !strings.Contains(string(raw), `"name":"client.commit"`) || !strings.Contains(string(raw), `"stringValue":"dur-client"`) {
+		t.Fatalf("Expected OTLP lines with the commit span, got %s", raw)
+	}
+
+	e, _, errOut = testEnv("", vars)
+	if code := run(e, []string{"client", "get", "--trace", "log", "--log-level", "info", "k"}); code != exitOK {
+		t.Fatalf("Expected get to succeed, got %d: %s", code, errOut)
+	}
+	if !strings.Contains(errOut.String(), "msg=span service=dur-client name=client.read") {
+		t.Errorf("Expected spans on stderr, got %q", errOut)
+	}
+}
@@ -0,0 +1,242 @@
+// Package config carrega a topologia de um cluster DUR de um arquivo JSON,
+// para que sequencer, réplicas e clientes sejam descritos num único lugar.
+//
+// O protocolo ordena todos os commits num único sequencer e toda réplica
+// guarda todas as chaves, então a topologia não tem vários sequencers nem
+// partições: as chaves "sequencers" e "partitions" são recusadas com um
+// erro que explica isso, em vez de ignoradas.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hrodric0/dur-impl/client"
+)
+
+// Topology descreve um cluster DUR
+type Topology struct {
+	Sequencer string    `json:"sequencer"`
+	Replicas  []Replica `json:"replicas"`
+	Timeouts  Timeouts  `json:"timeouts"`
+	Retry     Retry     `json:"retry"`
+	// Codec das mensagens; só "json" (o padrão) é suportado
+	Codec string `json:"codec,omitempty"`
+	// Sequencers e Partitions existem só para serem recusados por Validate
+	Sequencers []string        `json:"sequencers,omitempty"`
+	Partitions json.RawMessage `json:"partitions,omitempty"`
+}
+
+// Replica descreve uma réplica da topologia
+type Replica struct {
+	Name    string `json:"name"`
+	Addr    string `json:"addr"`
+	DataDir string `json:"dataDir,omitempty"` // vazio mantém o estado só em memória
+}
+
+// Timeouts limita as esperas de clientes, sequencer e réplicas; zero usa
+// o padrão de cada componente
+type Timeouts struct {
+	Read      Duration `json:"read,omitempty"`      // cada tentativa de leitura; zero não impõe prazo
+	Commit    Duration `json:"commit,omitempty"`    // espera do cliente pela decisão do sequencer
+	Replica   Duration `json:"replica,omitempty"`   // ida e volta do sequencer a cada réplica
+	CatchUp   Duration `json:"catchUp,omitempty"`   // espera das réplicas por pares que não respondem
+	Heartbeat Duration `json:"heartbeat,omitempty"` // intervalo dos heartbeats do sequencer e dos clientes
+}
+
+// Retry configura as reexecuções de transações abortadas por conflito
+type Retry struct {
+	Attempts    int      `json:"attempts,omitempty"`
+	BaseBackoff Duration `json:"baseBackoff,omitempty"`
+	MaxBackoff  Duration `json:"maxBackoff,omitempty"`
+}
+
+// Duration é um time.Duration escrito como "250ms" ou "2s"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"2s\", got %s", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Default é a topologia de demonstração: sequencer em localhost:8000 e
+// réplicas em localhost:8001 e localhost:8002
+func Default() *Topology {
+	return &Topology{
+		Sequencer: "localhost:8000",
+		Replicas: []Replica{
+			{Name: "r1", Addr: "localhost:8001"},
+			{Name: "r2", Addr: "localhost:8002"},
+		},
+	}
+}
+
+// Load lê e valida a topologia em path
+func Load(path string) (*Topology, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Parse decodifica e valida uma topologia; campos desconhecidos são erro,
+// para que um erro de digitação não passe despercebido
+func Parse(raw []byte) (*Topology, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var t Topology
+	if err := dec.Decode(&t); err != nil {
+		var syn *json.SyntaxError
+		if errors.As(err, &syn) {
+			line := bytes.Count(raw[:syn.Offset], []byte("\n")) + 1
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		return nil, err
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Validate reporta todos os problemas da topologia de uma vez, cada um
+// com o caminho do campo
+func (t *Topology) Validate() error {
+	var errs []error
+	bad := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+	if t.Sequencer == "" {
+		bad("sequencer", "address is required")
+	} else if err := checkAddr(t.Sequencer); err != nil {
+		bad("sequencer", "%v", err)
+	}
+	if len(t.Replicas) == 0 {
+		bad("replicas", "at least one replica is required")
+	}
+	names := map[string]int{}
+	addrs := map[string]string{t.Sequencer: "sequencer"}
+	dirs := map[string]int{}
+	for i, r := range t.Replicas {
+		field := "replicas[" + strconv.Itoa(i) + "]"
+		if r.Name == "" {
+			bad(field+".name", "name is required")
+		} else if j, dup := names[r.Name]; dup {
+			bad(field+".name", "duplicate name %q (also replicas[%d])", r.Name, j)
+		} else {
+			names[r.Name] = i
+		}
+		if r.Addr == "" {
+			bad(field+".addr", "address is required")
+		} else if err := checkAddr(r.Addr); err != nil {
+			bad(field+".addr", "%v", err)
+		} else if other, dup := addrs[r.Addr]; dup {
+			bad(field+".addr", "address %s already used by %s", r.Addr, other)
+		} else {
+			addrs[r.Addr] = field
+		}
+		if r.DataDir != "" {
+			if j, dup := dirs[r.DataDir]; dup {
+				bad(field+".dataDir", "data directory %s shared with replicas[%d]", r.DataDir, j)
+			}
+			dirs[r.DataDir] = i
+		}
+	}
+	for _, f := range []struct {
+		name string
+		d    Duration
+	}{{"read", t.Timeouts.Read}, {"commit", t.Timeouts.Commit}, {"replica", t.Timeouts.Replica}, {"catchUp", t.Timeouts.CatchUp}, {"heartbeat", t.Timeouts.Heartbeat}} {
+		if f.d < 0 {
+			bad("timeouts."+f.name, "must not be negative")
+		}
+	}
+	if t.Retry.Attempts < 0 {
+		bad("retry.attempts", "must not be negative")
+	}
+	if t.Retry.BaseBackoff < 0 || t.Retry.MaxBackoff < 0 {
+		bad("retry", "backoff must not be negative")
+	}
+	if t.Retry.MaxBackoff > 0 && t.Retry.BaseBackoff > t.Retry.MaxBackoff {
+		bad("retry.baseBackoff", "%v exceeds maxBackoff %v", time.Duration(t.Retry.BaseBackoff), time.Duration(t.Retry.MaxBackoff))
+	}
+	if len(t.Sequencers) > 0 {
+		bad("sequencers", "multiple sequencers are not supported: all commits are ordered by the single \"sequencer\"")
+	}
+	if len(t.Partitions) > 0 {
+		bad("partitions", "partitioning is not supported: every replica stores every key")
+	}
+	if t.Codec != "" && t.Codec != "json" {
+		bad("codec", "unsupported codec %q (only \"json\")", t.Codec)
+	}
+	return errors.Join(errs...)
+}
+
+// checkAddr exige host:porta com porta numérica
+func checkAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("address %s: bad port %q", addr, port)
+	}
+	return nil
+}
+
+// ReplicaAddrs retorna os endereços das réplicas, na ordem do arquivo
+func (t *Topology) ReplicaAddrs() []string {
+	out := make([]string, len(t.Replicas))
+	for i, r := range t.Replicas {
+		out[i] = r.Addr
+	}
+	return out
+}
+
+// Replica procura uma réplica pelo nome
+func (t *Topology) Replica(name string) (Replica, bool) {
+	for _, r := range t.Replicas {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Replica{}, false
+}
+
+// ClientConfig monta a configuração de um client.Client para a topologia
+func (t *Topology) ClientConfig(cid string) client.Config {
+	return client.Config{
+		Cid:           cid,
+		Replicas:      t.ReplicaAddrs(),
+		Sequencer:     t.Sequencer,
+		ReadTimeout:   time.Duration(t.Timeouts.Read),
+		CommitTimeout: time.Duration(t.Timeouts.Commit),
+		Retry: client.RetryPolicy{
+			MaxAttempts: t.Retry.Attempts,
+			BaseBackoff: time.Duration(t.Retry.BaseBackoff),
+			MaxBackoff:  time.Duration(t.Retry.MaxBackoff),
+		},
+	}
+}
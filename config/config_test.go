@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sample = `{
+  "sequencer": "localhost:8000",
+  "replicas": [
+    {"name": "r1", "addr": "localhost:8001", "dataDir": "/var/dur/r1"},
+    {"name": "r2", "addr": "localhost:8002"}
+  ],
+  "timeouts": {"read": "250ms", "commit": "10s", "replica": "2s", "catchUp": "30s", "heartbeat": "500ms"},
+  "retry": {"attempts": 3, "baseBackoff": "5ms", "maxBackoff": "1s"},
+  "codec": "json"
+}`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluster.json")
+	os.WriteFile(path, []byte(sample), 0o644)
+	topo, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if addrs := topo.ReplicaAddrs(); len(addrs) != 2 || addrs[1] != "localhost:8002" {
+		t.Errorf("Expected two replica addresses, got %v", addrs)
+	}
+	if r, ok := topo.Replica("r1"); !ok || r.DataDir != "/var/dur/r1" {
+		t.Errorf("Expected r1 with a data dir, got %+v", r)
+	}
+	cfg := topo.ClientConfig("c1")
+	if cfg.Sequencer != "localhost:8000" || cfg.ReadTimeout != 250*time.Millisecond || cfg.CommitTimeout != 10*time.Second || cfg.Retry.MaxAttempts != 3 || cfg.Retry.MaxBackoff != time.Second {
+		t.Errorf("Unexpected client config %+v", cfg)
+	}
+	if to := topo.Timeouts; time.Duration(to.Replica) != 2*time.Second || time.Duration(to.CatchUp) != 30*time.Second || time.Duration(to.Heartbeat) != 500*time.Millisecond {
+		t.Errorf("Unexpected timeouts %+v", to)
+	}
+	if err := Default().Validate(); err != nil {
+		t.Errorf("Expected default topology to be valid, got %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		name, json string
+		want       []string
+	}{
+		{"syntax", "{\n  \"sequencer\": \"a:1\",\n  oops\n}", []string{"line 3"}},
+		{"unknown field", `{"sequencer": "a:1", "replicas": [{"name": "r", "addr": "b:2"}], "shards": 4}`, []string{`unknown field "shards"`}},
+		{"partitions", `{"sequencer": "a:1", "replicas": [{"name": "r", "addr": "b:2"}], "partitions": [{"keys": "a-m", "replicas": ["r"]}]}`, []string{"partitions: partitioning is not supported"}},
+		{"sequencers", `{"sequencer": "a:1", "sequencers": ["a:1", "c:3"], "replicas": [{"name": "r", "addr": "b:2"}]}`, []string{"sequencers: multiple sequencers are not supported"}},
+		{"duration", `{"sequencer": "a:1", "replicas": [{"name": "r", "addr": "b:2"}], "timeouts": {"read": 5}}`, []string{"duration must be a string"}},
+		{"everything", `{
+			"sequencer": "nohost",
+			"replicas": [
+				{"name": "r1", "addr": "b:2", "dataDir": "d"},
+				{"name": "r1", "addr": "b:2", "dataDir": "d"},
+				{"addr": "c:99999"}
+			],
+			"timeouts": {"replica": "-1s", "heartbeat": "-1s"},
+			"retry": {"attempts": -1, "baseBackoff": "2s", "maxBackoff": "1s"},
+			"codec": "protobuf"
+		}`, []string{
+			"sequencer: address nohost: missing port",
+			`replicas[1].name: duplicate name "r1" (also replicas[0])`,
+			"replicas[1].addr: address b:2 already used by replicas[0]",
+			"replicas[1].dataDir: data directory d shared with replicas[0]",
+			"replicas[2].name: name is required",
+			`replicas[2].addr: address c:99999: bad port "99999"`,
+			"timeouts.replica: must not be negative",
+			"timeouts.heartbeat: must not be negative",
+			"retry.attempts: must not be negative",
+			"retry.baseBackoff: 2s exceeds maxBackoff 1s",
+			`codec: unsupported codec "protobuf"`,
+		}},
+		{"empty", `{}`, []string{"sequencer: address is required", "replicas: at least one replica is required"}},
+	}
+	for _, c := range cases {
+		_, err := Parse([]byte(c.json))
+		if err == nil {
+			t.Errorf("%s: expected error", c.name)
+			continue
+		}
+		for _, w := range c.want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("%s: expected %q in error:\n%v", c.name, w, err)
+			}
+		}
+	}
+}
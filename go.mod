@@ -1,3 +1,3 @@
 module github.com/hrodric0/dur-impl
 
-go 1.24.5
+go 1.25.0
@@ -0,0 +1,182 @@
+// Package health oferece os endpoints HTTP de liveness e readiness
+// (/healthz e /readyz) e um detector de falhas por heartbeat, usado pelo
+// sequencer e pelos clientes para evitar réplicas que não respondem.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hrodric0/dur-impl/logging"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// Handler atende GET /healthz com live e GET /readyz com ready: 200 "ok"
+// se a verificação retorna nil, 503 com o erro caso contrário
+func Handler(live, ready func() error) http.Handler {
+	mux := http.NewServeMux()
+	serve := func(check func() error) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			if err := check(); err != nil {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintf(w, "%v\n", err)
+				return
+			}
+			fmt.Fprintln(w, "ok")
+		}
+	}
+	mux.Handle("GET /healthz", serve(live))
+	mux.Handle("GET /readyz", serve(ready))
+	return mux
+}
+
+// Valores padrão do Detector
+const (
+	DefaultInterval = time.Second
+	DefaultMisses   = 3
+)
+
+// Detector é um detector de falhas por heartbeat: a cada Interval envia um
+// PingRequest a cada endereço de Addrs e suspeita dos que deixam de
+// responder Misses vezes seguidas. Uma resposta volta a marcar o endereço
+// como ativo. Endereços ainda não verificados são considerados ativos.
+type Detector struct {
+	Addrs     []string
+	Transport network.Transport // nil usa TCP
+	Interval  time.Duration     // zero usa DefaultInterval
+	Timeout   time.Duration     // prazo de cada ping; zero usa Interval
+	Misses    int               // zero usa DefaultMisses
+	Logger    *slog.Logger      // nil usa slog.Default()
+
+	mu    sync.Mutex
+	peers map[string]*Peer
+}
+
+// Peer é o estado de um endereço visto pelo Detector
+type Peer struct {
+	Addr     string    `json:"addr"`
+	Up       bool      `json:"up"`
+	Ready    bool      `json:"ready"` // da última resposta
+	Seq      uint64    `json:"seq"`   // da última resposta
+	Misses   int       `json:"misses"`
+	LastSeen time.Time `json:"lastSeen,omitzero"`
+	Error    string    `json:"error,omitempty"` // do último ping sem resposta
+}
+
+func (d *Detector) interval() time.Duration {
+	if d.Interval <= 0 {
+		return DefaultInterval
+	}
+	return d.Interval
+}
+
+// Start faz uma rodada de pings e segue pingando em segundo plano até ctx acabar
+func (d *Detector) Start(ctx context.Context) {
+	d.Probe()
+	go func() {
+		t := time.NewTicker(d.interval())
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				d.Probe()
+			}
+		}
+	}()
+}
+
+// Probe pinga todos os endereços, em paralelo, e atualiza o estado
+func (d *Detector) Probe() {
+	tr := d.Transport
+	if tr == nil {
+		tr = network.TCP
+	}
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = d.interval()
+	}
+	var wg sync.WaitGroup
+	for _, addr := range d.Addrs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var rep types.PingReply
+			err := network.RequestTimeout(tr, addr, types.PingRequest{Ping: true}, &rep, timeout)
+			d.observe(addr, rep, err)
+		}()
+	}
+	wg.Wait()
+}
+
+// observe registra o resultado de um ping
+func (d *Detector) observe(addr string, rep types.PingReply, err error) {
+	misses := d.Misses
+	if misses <= 0 {
+		misses = DefaultMisses
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.peers == nil {
+		d.peers = map[string]*Peer{}
+	}
+	p, ok := d.peers[addr]
+	if !ok {
+		p = &Peer{Addr: addr, Up: true, Ready: true}
+		d.peers[addr] = p
+	}
+	log := logging.Or(d.Logger).With("component", "health", "peer", addr)
+	if err != nil {
+		p.Misses++
+		p.Error = err.Error()
+		if p.Up && p.Misses >= misses {
+			p.Up = false
+			log.Warn("peer suspected down", "misses", p.Misses, "err", err)
+		}
+		return
+	}
+	if !p.Up {
+		log.Info("peer up again", "seq", rep.Seq)
+	}
+	p.Up, p.Ready, p.Seq, p.Misses, p.Error = true, rep.Ready, rep.Seq, 0, ""
+	p.LastSeen = time.Now()
+}
+
+// Up diz se addr responde aos pings
+func (d *Detector) Up(addr string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p, ok := d.peers[addr]
+	return !ok || p.Up
+}
+
+// Ready diz se addr responde e, na última resposta, estava pronta
+func (d *Detector) Ready(addr string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p, ok := d.peers[addr]
+	return !ok || p.Up && p.Ready
+}
+
+// Peers retorna o estado de cada endereço de Addrs, na ordem de Addrs
+func (d *Detector) Peers() []Peer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Peer, 0, len(d.Addrs))
+	for _, addr := range d.Addrs {
+		p, ok := d.peers[addr]
+		if !ok {
+			out = append(out, Peer{Addr: addr, Up: true, Ready: true})
+			continue
+		}
+		out = append(out, *p)
+	}
+	return out
+}
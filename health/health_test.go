@@ -0,0 +1,67 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/logging"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+func TestHandler(t *testing.T) {
+	h := Handler(func() error { return nil }, func() error { return errors.New("catching up") })
+	for path, want := range map[string]int{"/healthz": http.StatusOK, "/readyz": http.StatusServiceUnavailable} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if !strings.Contains(rec.Body.String(), "catching up") {
+		t.Errorf("Expected the readiness error in the body, got %q", rec.Body)
+	}
+}
+
+func TestDetector(t *testing.T) {
+	tr := network.NewMemTransport()
+	ln, err := tr.Listen("r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go network.Serve(ln, func(raw []byte, conn net.Conn) {
+		json.NewEncoder(conn).Encode(types.PingReply{Seq: 7, Ready: true})
+	})
+	d := &Detector{Addrs: []string{"r1", "r2"}, Transport: tr, Misses: 2, Logger: logging.Discard}
+	d.Probe()
+	if !d.Ready("r1") || !d.Up("r2") {
+		t.Fatalf("Expected r1 ready and r2 still up after one miss, got %+v", d.Peers())
+	}
+	d.Probe()
+	if d.Up("r2") || d.Ready("r2") {
+		t.Fatalf("Expected r2 suspected after two misses, got %+v", d.Peers())
+	}
+	if p := d.Peers()[0]; p.Seq != 7 || p.LastSeen.IsZero() {
+		t.Errorf("Expected r1 at seq 7, got %+v", p)
+	}
+
+	// r2 volta: uma resposta basta
+	ln2, _ := tr.Listen("r2")
+	go network.Serve(ln2, func(raw []byte, conn net.Conn) {
+		json.NewEncoder(conn).Encode(types.PingReply{Seq: 3})
+	})
+	d.Probe()
+	if !d.Up("r2") || d.Ready("r2") {
+		t.Errorf("Expected r2 up but not ready, got %+v", d.Peers())
+	}
+	if !d.Up("unknown") {
+		t.Errorf("Expected unknown addresses to count as up")
+	}
+}
@@ -0,0 +1,266 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Tipos de aresta do grafo de dependências (Adya)
+const (
+	EdgeWW = "ww" // From escreveu a versão anterior à escrita de To
+	EdgeWR = "wr" // To leu a versão escrita por From
+	EdgeRW = "rw" // From leu uma versão sobrescrita em seguida por To
+)
+
+// Edge é uma dependência entre duas transações, identificadas por "cid/tid"
+type Edge struct {
+	From string
+	To   string
+	Kind string
+	Item string
+}
+
+// Report é o resultado da verificação de uma história
+type Report struct {
+	Transactions int      // transações confirmadas analisadas
+	Cycle        []Edge   // menor ciclo encontrado; vazio se a história é serializável
+	Anomalies    []string // leituras de versões que nenhum commit produziu
+}
+
+// Serializable indica se não há ciclo nem leituras de versões inexistentes
+func (r Report) Serializable() bool {
+	return len(r.Cycle) == 0 && len(r.Anomalies) == 0
+}
+
+func (r Report) String() string {
+	if r.Serializable() {
+		return fmt.Sprintf("serializable (%d committed transactions)", r.Transactions)
+	}
+	var b strings.Builder
+	if len(r.Cycle) > 0 {
+		b.WriteString("cycle: " + r.Cycle[0].From)
+		for _, e := range r.Cycle {
+			fmt.Fprintf(&b, " -%s(%s)-> %s", e.Kind, e.Item, e.To)
+		}
+	}
+	for _, a := range r.Anomalies {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(a)
+	}
+	return b.String()
+}
+
+type txn struct {
+	key       string
+	reads     map[string]uint64
+	readOrder []string
+	writes    map[string]bool
+	committed bool
+	seq       uint64
+}
+
+// Check constrói o grafo de dependências das transações confirmadas e
+// procura ciclos. A ordem de versões de cada item vem do Seq de commit.
+func Check(events []Event) Report {
+	txns := map[string]*txn{}
+	var order []string
+	for _, e := range events {
+		key := e.Cid + "/" + e.Tid
+		t, ok := txns[key]
+		if !ok {
+			t = &txn{key: key, reads: map[string]uint64{}, writes: map[string]bool{}}
+			txns[key] = t
+			order = append(order, key)
+		}
+		switch e.Kind {
+		case KindRead:
+			if _, seen := t.reads[e.Item]; !seen && e.Err == "" {
+				t.reads[e.Item] = e.Version
+				t.readOrder = append(t.readOrder, e.Item)
+			}
+		case KindWrite:
+			t.writes[e.Item] = true
+		case KindCommit:
+			t.committed = e.Committed
+			t.seq = e.Version
+		}
+	}
+
+	var rep Report
+	var committed []*txn
+	writers := map[string][]*txn{} // por item, em ordem de Seq
+	for _, key := range order {
+		t := txns[key]
+		if !t.committed {
+			continue
+		}
+		committed = append(committed, t)
+		for item := range t.writes {
+			writers[item] = append(writers[item], t)
+		}
+	}
+	rep.Transactions = len(committed)
+	for _, ws := range writers {
+		sort.Slice(ws, func(i, j int) bool { return ws[i].seq < ws[j].seq })
+	}
+
+	g := newGraph()
+	items := make([]string, 0, len(writers))
+	for item := range writers {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	for _, item := range items {
+		ws := writers[item]
+		for i := 1; i < len(ws); i++ {
+			g.add(Edge{From: ws[i-1].key, To: ws[i].key, Kind: EdgeWW, Item: item})
+		}
+	}
+	for _, t := range committed {
+		for _, item := range t.readOrder {
+			v := t.reads[item]
+			ws := writers[item]
+			next := sort.Search(len(ws), func(i int) bool { return ws[i].seq > v })
+			if v != 0 {
+				if next == 0 || ws[next-1].seq != v {
+					rep.Anomalies = append(rep.Anomalies, fmt.Sprintf("%s read %s@v%d, which no committed transaction wrote", t.key, item, v))
+				} else if w := ws[next-1]; w != t {
+					g.add(Edge{From: w.key, To: t.key, Kind: EdgeWR, Item: item})
+				}
+			}
+			if next < len(ws) && ws[next] != t {
+				g.add(Edge{From: t.key, To: ws[next].key, Kind: EdgeRW, Item: item})
+			}
+		}
+	}
+	rep.Cycle = g.shortestCycle()
+	return rep
+}
+
+type graph struct {
+	nodes []string
+	index map[string]int
+	adj   [][]Edge
+	seen  map[[2]string]bool
+}
+
+func newGraph() *graph {
+	return &graph{index: map[string]int{}, seen: map[[2]string]bool{}}
+}
+
+func (g *graph) node(key string) int {
+	if i, ok := g.index[key]; ok {
+		return i
+	}
+	g.index[key] = len(g.nodes)
+	g.nodes = append(g.nodes, key)
+	g.adj = append(g.adj, nil)
+	return len(g.nodes) - 1
+}
+
+// add registra e, mantendo só a primeira aresta entre cada par de nós
+func (g *graph) add(e Edge) {
+	if g.seen[[2]string{e.From, e.To}] {
+		return
+	}
+	g.seen[[2]string{e.From, e.To}] = true
+	from := g.node(e.From)
+	g.node(e.To)
+	g.adj[from] = append(g.adj[from], e)
+}
+
+// shortestCycle acha componentes fortemente conexos e, dentro deles,
+// o menor ciclo por BFS; esse é o contraexemplo mínimo reportado.
+func (g *graph) shortestCycle() []Edge {
+	comp := g.scc()
+	var best []Edge
+	for s := range g.nodes {
+		prev := make([]*Edge, len(g.nodes))
+		visited := make([]bool, len(g.nodes))
+		queue := []int{s}
+		visited[s] = true
+		found := false
+		for len(queue) > 0 && !found {
+			u := queue[0]
+			queue = queue[1:]
+			for i := range g.adj[u] {
+				e := &g.adj[u][i]
+				v := g.index[e.To]
+				if comp[v] != comp[s] {
+					continue
+				}
+				if v == s {
+					cycle := []Edge{*e}
+					for n := u; n != s; n = g.index[prev[n].From] {
+						cycle = append([]Edge{*prev[n]}, cycle...)
+					}
+					if best == nil || len(cycle) < len(best) {
+						best = cycle
+					}
+					found = true
+					break
+				}
+				if !visited[v] {
+					visited[v] = true
+					prev[v] = e
+					queue = append(queue, v)
+				}
+			}
+		}
+		if len(best) == 1 {
+			break
+		}
+	}
+	return best
+}
+
+// scc rotula cada nó com seu componente fortemente conexo (Tarjan)
+func (g *graph) scc() []int {
+	n := len(g.nodes)
+	comp := make([]int, n)
+	low := make([]int, n)
+	idx := make([]int, n)
+	onStack := make([]bool, n)
+	for i := range idx {
+		idx[i] = -1
+	}
+	var stack []int
+	counter, label := 0, 0
+	var visit func(u int)
+	visit = func(u int) {
+		idx[u], low[u] = counter, counter
+		counter++
+		stack = append(stack, u)
+		onStack[u] = true
+		for _, e := range g.adj[u] {
+			v := g.index[e.To]
+			if idx[v] == -1 {
+				visit(v)
+				low[u] = min(low[u], low[v])
+			} else if onStack[v] {
+				low[u] = min(low[u], idx[v])
+			}
+		}
+		if low[u] == idx[u] {
+			for {
+				v := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[v] = false
+				comp[v] = label
+				if v == u {
+					break
+				}
+			}
+			label++
+		}
+	}
+	for u := 0; u < n; u++ {
+		if idx[u] == -1 {
+			visit(u)
+		}
+	}
+	return comp
+}
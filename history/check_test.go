@@ -0,0 +1,67 @@
+package history
+
+import (
+	"strings"
+	"testing"
+)
+
+func read(cid, item string, v uint64) Event {
+	return Event{Cid: cid, Tid: "t", Kind: KindRead, Item: item, Version: v}
+}
+func write(cid, item string) Event { return Event{Cid: cid, Tid: "t", Kind: KindWrite, Item: item} }
+func commit(cid string, seq uint64) Event {
+	return Event{Cid: cid, Tid: "t", Kind: KindCommit, Committed: seq > 0, Version: seq}
+}
+
+func TestCheckSerialHistory(t *testing.T) {
+	h := []Event{
+		read("a", "x", 0), write("a", "x"), commit("a", 1),
+		read("b", "x", 1), write("b", "y"), commit("b", 2),
+		read("c", "x", 1), read("c", "y", 2), commit("c", 3),
+	}
+	rep := Check(h)
+	if !rep.Serializable() || rep.Transactions != 3 {
+		t.Fatalf("Expected serializable history with 3 txns, got %v", rep)
+	}
+}
+
+func TestCheckWriteSkew(t *testing.T) {
+	// a e b leem x e y iniciais e cada um escreve um deles: rw em ambos os sentidos
+	h := []Event{
+		read("a", "x", 0), read("a", "y", 0),
+		read("b", "x", 0), read("b", "y", 0),
+		write("a", "x"), write("b", "y"),
+		commit("a", 1), commit("b", 2),
+		// transação não relacionada não deve aparecer no contraexemplo
+		read("c", "z", 0), write("c", "z"), commit("c", 3),
+	}
+	rep := Check(h)
+	if rep.Serializable() {
+		t.Fatalf("Expected write skew to be reported")
+	}
+	if len(rep.Cycle) != 2 {
+		t.Fatalf("Expected minimal 2-edge cycle, got %v", rep.Cycle)
+	}
+	for _, e := range rep.Cycle {
+		if e.Kind != EdgeRW {
+			t.Errorf("Expected rw edges, got %+v", e)
+		}
+	}
+	if s := rep.String(); !strings.Contains(s, "a/t") || !strings.Contains(s, "b/t") || strings.Contains(s, "c/t") {
+		t.Errorf("Unexpected counterexample %q", s)
+	}
+}
+
+func TestCheckIgnoresAbortedAndFlagsUnknownVersions(t *testing.T) {
+	h := []Event{
+		read("a", "x", 0), write("a", "x"), commit("a", 0),
+		read("b", "x", 7), commit("b", 1),
+	}
+	rep := Check(h)
+	if rep.Transactions != 1 || len(rep.Cycle) != 0 {
+		t.Fatalf("Expected only b analysed without cycles, got %+v", rep)
+	}
+	if len(rep.Anomalies) != 1 || !strings.Contains(rep.Anomalies[0], "x@v7") {
+		t.Errorf("Expected anomaly for x@v7, got %v", rep.Anomalies)
+	}
+}
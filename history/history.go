@@ -0,0 +1,81 @@
+// Package history registra as operações de transações (Read, Write, Commit)
+// e verifica se a execução observada é serializável.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Kind identifica o tipo de evento
+type Kind string
+
+const (
+	KindRead   Kind = "read"
+	KindWrite  Kind = "write"
+	KindCommit Kind = "commit"
+)
+
+// Event é uma operação observada por um cliente.
+// Em reads, Version é a versão lida; em commits, é o Seq em que o ws foi aplicado.
+type Event struct {
+	Cid       string `json:"cid"`
+	Tid       string `json:"tid"`
+	Kind      Kind   `json:"kind"`
+	Item      string `json:"item,omitempty"`
+	Value     []byte `json:"value,omitempty"`
+	Version   uint64 `json:"version,omitempty"`
+	Committed bool   `json:"committed,omitempty"`
+	Err       string `json:"err,omitempty"`
+}
+
+// Recorder acumula eventos de vários clientes; é seguro para uso concorrente.
+// Se Sink não for nil, cada evento também é escrito nele como uma linha JSON.
+type Recorder struct {
+	Sink io.Writer
+
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewRecorder cria um Recorder vazio
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Record registra e
+func (r *Recorder) Record(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	if r.Sink != nil {
+		json.NewEncoder(r.Sink).Encode(e)
+	}
+}
+
+// Events retorna uma cópia dos eventos registrados, em ordem
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// Load lê eventos gravados como linhas JSON, por exemplo por um Recorder com Sink
+func Load(rd io.Reader) ([]Event, error) {
+	var events []Event
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, sc.Err()
+}
@@ -0,0 +1,23 @@
+package history
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRecorderSinkAndLoad(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder()
+	r.Sink = &buf
+	r.Record(Event{Cid: "c1", Tid: "t1", Kind: KindRead, Item: "x", Value: []byte("init")})
+	r.Record(Event{Cid: "c1", Tid: "t1", Kind: KindCommit, Committed: true, Version: 3})
+
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, r.Events()) {
+		t.Errorf("Expected %+v, got %+v", r.Events(), loaded)
+	}
+}
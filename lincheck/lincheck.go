@@ -0,0 +1,253 @@
+// Package lincheck verifica se um histórico concorrente de operações sobre
+// um único registro (uma chave) é linearizável, no estilo do Porcupine:
+// busca em profundidade sobre as ordens compatíveis com o tempo real,
+// com cache de estados já visitados (algoritmo WGL).
+package lincheck
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Unknown é o Return de uma operação cujo resultado não foi observado
+// (por exemplo, commit com erro de rede): ela pode ter efeito a qualquer momento.
+const Unknown = time.Duration(math.MaxInt64)
+
+// Operation é uma leitura ou escrita observada por um cliente
+type Operation struct {
+	Client  string
+	Write   bool
+	Value   string        // valor escrito, ou valor lido
+	Call    time.Duration // início, relativo ao começo da carga
+	Return  time.Duration // fim; Unknown se o resultado é incerto
+	Replica string        // réplica consultada (informativo)
+}
+
+func (op Operation) String() string {
+	if op.Write {
+		return fmt.Sprintf("%s: write(%s)", op.Client, op.Value)
+	}
+	return fmt.Sprintf("%s: read() -> %s", op.Client, op.Value)
+}
+
+// Result é o resultado da verificação
+type Result struct {
+	Ok bool
+	// Order é uma linearização completa (índices em ops) se Ok; caso
+	// contrário, o maior prefixo linearizável encontrado.
+	Order []int
+}
+
+// step é o modelo de registro: escritas sempre valem, leituras devem ver o estado atual
+func step(state string, op Operation) (string, bool) {
+	if op.Write {
+		return op.Value, true
+	}
+	return state, op.Value == state
+}
+
+type entry struct {
+	op         int
+	call       bool
+	time       time.Duration
+	match      *entry
+	prev, next *entry
+}
+
+// Check verifica se ops é linearizável para um registro com valor inicial initial
+func Check(initial string, ops []Operation) Result {
+	n := len(ops)
+	events := make([]*entry, 0, 2*n)
+	for i, op := range ops {
+		c := &entry{op: i, call: true, time: op.Call}
+		r := &entry{op: i, time: op.Return, match: c}
+		c.match = r
+		events = append(events, c, r)
+	}
+	// em empate, chamadas vêm antes de retornos (operações se sobrepõem)
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].time != events[j].time {
+			return events[i].time < events[j].time
+		}
+		return events[i].call && !events[j].call
+	})
+	head := &entry{}
+	cur := head
+	for _, e := range events {
+		cur.next, e.prev = e, cur
+		cur = e
+	}
+
+	lift := func(e *entry) {
+		e.prev.next = e.next
+		if e.next != nil {
+			e.next.prev = e.prev
+		}
+		r := e.match
+		r.prev.next = r.next
+		if r.next != nil {
+			r.next.prev = r.prev
+		}
+	}
+	unlift := func(e *entry) {
+		r := e.match
+		r.prev.next = r
+		if r.next != nil {
+			r.next.prev = r
+		}
+		e.prev.next = e
+		if e.next != nil {
+			e.next.prev = e
+		}
+	}
+
+	type frame struct {
+		e     *entry
+		state string
+	}
+	linearized := make([]bool, n)
+	cache := map[string]bool{}
+	var stack []frame
+	var best []int
+	state := initial
+	e := head.next
+	for head.next != nil {
+		if e.call {
+			next, ok := step(state, ops[e.op])
+			if ok {
+				linearized[e.op] = true
+				key := cacheKey(linearized, next)
+				if !cache[key] {
+					cache[key] = true
+					stack = append(stack, frame{e: e, state: state})
+					state = next
+					lift(e)
+					if len(stack) > len(best) {
+						best = best[:0]
+						for _, f := range stack {
+							best = append(best, f.e.op)
+						}
+					}
+					e = head.next
+					continue
+				}
+				linearized[e.op] = false
+			}
+			e = e.next
+			continue
+		}
+		// um retorno antes de qualquer chamada viável: volta atrás
+		if len(stack) == 0 {
+			return Result{Ok: false, Order: best}
+		}
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		state = top.state
+		linearized[top.e.op] = false
+		unlift(top.e)
+		e = top.e.next
+	}
+	order := make([]int, len(stack))
+	for i, f := range stack {
+		order[i] = f.e.op
+	}
+	return Result{Ok: true, Order: order}
+}
+
+func cacheKey(linearized []bool, state string) string {
+	var b strings.Builder
+	for _, l := range linearized {
+		if l {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	b.WriteByte('|')
+	b.WriteString(state)
+	return b.String()
+}
+
+// Timeline desenha as operações em linhas de tempo por cliente. Operações
+// fora do maior prefixo linearizável são marcadas com '!'.
+func Timeline(ops []Operation, res Result) string {
+	const width = 60
+	if len(ops) == 0 {
+		return ""
+	}
+	var start, end time.Duration = math.MaxInt64, 0
+	for _, op := range ops {
+		start = min(start, op.Call)
+		if op.Return != Unknown {
+			end = max(end, op.Return)
+		}
+		end = max(end, op.Call)
+	}
+	span := max(end-start, 1)
+	col := func(t time.Duration) int {
+		if t == Unknown {
+			return width
+		}
+		return int(int64(t-start) * width / int64(span))
+	}
+	inPrefix := map[int]bool{}
+	for _, i := range res.Order {
+		inPrefix[i] = true
+	}
+	idx := make([]int, len(ops))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		if ops[idx[a]].Client != ops[idx[b]].Client {
+			return ops[idx[a]].Client < ops[idx[b]].Client
+		}
+		return ops[idx[a]].Call < ops[idx[b]].Call
+	})
+
+	var b strings.Builder
+	if res.Ok {
+		b.WriteString("linearizable\n")
+	} else {
+		fmt.Fprintf(&b, "not linearizable: longest linearizable prefix has %d of %d operations\n", len(res.Order), len(ops))
+	}
+	for _, i := range idx {
+		op := ops[i]
+		line := []byte(strings.Repeat(" ", width+1))
+		from, to := col(op.Call), col(op.Return)
+		for c := from; c <= to && c <= width; c++ {
+			line[c] = '-'
+		}
+		line[from] = '['
+		if op.Return != Unknown {
+			line[min(to, width)] = ']'
+		}
+		if from == to {
+			line[from] = '|'
+		}
+		mark := " "
+		if !inPrefix[i] && !res.Ok {
+			mark = "!"
+		}
+		where := ""
+		if op.Replica != "" {
+			where = " @" + op.Replica
+		}
+		ret := "?"
+		if op.Return != Unknown {
+			ret = op.Return.String()
+		}
+		fmt.Fprintf(&b, "%s %-8s |%s| %s%s [%v, %s]\n", mark, op.Client, line, op, where, op.Call, ret)
+	}
+	if !res.Ok && len(res.Order) > 0 {
+		b.WriteString("prefix:")
+		for _, i := range res.Order {
+			fmt.Fprintf(&b, " %s;", ops[i])
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package lincheck
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckLinearizable(t *testing.T) {
+	ops := []Operation{
+		{Client: "a", Write: true, Value: "1", Call: 0, Return: 10},
+		{Client: "b", Value: "0", Call: 1, Return: 3}, // concorrente com a escrita: vê o valor antigo
+		{Client: "c", Value: "1", Call: 5, Return: 12},
+		{Client: "b", Value: "1", Call: 13, Return: 15},
+	}
+	res := Check("0", ops)
+	if !res.Ok || len(res.Order) != len(ops) {
+		t.Fatalf("Expected linearizable history, got %+v\n%s", res, Timeline(ops, res))
+	}
+}
+
+func TestCheckStaleReadAfterNewRead(t *testing.T) {
+	// c lê o valor novo e termina; depois b lê o valor antigo, ainda durante a escrita
+	ops := []Operation{
+		{Client: "a", Write: true, Value: "1", Call: 0, Return: 100, Replica: ""},
+		{Client: "c", Value: "1", Call: 10, Return: 20, Replica: "r1"},
+		{Client: "b", Value: "0", Call: 30, Return: 40, Replica: "r2"},
+	}
+	res := Check("0", ops)
+	if res.Ok {
+		t.Fatalf("Expected non-linearizable history")
+	}
+	tl := Timeline(ops, res)
+	if !strings.Contains(tl, "not linearizable") || !strings.Contains(tl, "! b") {
+		t.Errorf("Expected counterexample marking b's read, got\n%s", tl)
+	}
+}
+
+func TestCheckUnknownWrite(t *testing.T) {
+	// escrita com resultado incerto pode ter efeito depois de qualquer coisa
+	ops := []Operation{
+		{Client: "a", Write: true, Value: "1", Call: 0, Return: Unknown},
+		{Client: "b", Value: "0", Call: 10, Return: 20},
+		{Client: "b", Value: "1", Call: 30, Return: 40},
+	}
+	if res := Check("0", ops); !res.Ok {
+		t.Fatalf("Expected linearizable history\n%s", Timeline(ops, res))
+	}
+}
@@ -0,0 +1,113 @@
+package lincheck
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hrodric0/dur-impl/client"
+	"github.com/hrodric0/dur-impl/network"
+)
+
+// Workload gera leituras e escritas concorrentes de uma única chave
+// através de client.Transaction, registrando o histórico para Check.
+type Workload struct {
+	Key          string
+	Clients      int
+	OpsPerClient int
+	WriteRatio   float64 // fração de escritas (padrão 0.5)
+	Replicas     []string
+	Sequencer    string
+	Transport    network.Transport
+	Seed         int64
+	// ReadFrom escolhe o índice da réplica lida pela op-ésima operação do
+	// cliente c; o padrão é sempre a primeira, como Transaction.Read.
+	ReadFrom func(c, op int) int
+	// CertifyReads faz cada leitura ser uma transação só de leitura com
+	// Commit; leituras abortadas são descartadas do histórico.
+	CertifyReads bool
+}
+
+// Run executa a carga e retorna as operações observadas
+func (w Workload) Run() []Operation {
+	if w.WriteRatio == 0 {
+		w.WriteRatio = 0.5
+	}
+	start := time.Now()
+	var mu sync.Mutex
+	var ops []Operation
+	var wg sync.WaitGroup
+	for c := 0; c < w.Clients; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(w.Seed + int64(c)))
+			cid := fmt.Sprintf("lc%d", c)
+			for i := 0; i < w.OpsPerClient; i++ {
+				var op Operation
+				var ok bool
+				tid := fmt.Sprintf("%s-%d", cid, i)
+				if rng.Float64() < w.WriteRatio {
+					op, ok = w.write(cid, tid, start)
+				} else {
+					r := 0
+					if w.ReadFrom != nil {
+						r = w.ReadFrom(c, i)
+					}
+					op, ok = w.read(cid, tid, w.Replicas[r%len(w.Replicas)], start)
+				}
+				if ok {
+					mu.Lock()
+					ops = append(ops, op)
+					mu.Unlock()
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+	return ops
+}
+
+func (w Workload) tx(cid, tid string, replicas []string) *client.Transaction {
+	tx := client.NewTransaction(cid, tid, replicas, w.Sequencer)
+	tx.Transport = w.Transport
+	return tx
+}
+
+// write executa uma escrita isolada e a registra em relação a start
+func (w Workload) write(cid, tid string, start time.Time) (Operation, bool) {
+	val := tid
+	op := Operation{Client: cid, Write: true, Value: val, Call: time.Since(start)}
+	tx := w.tx(cid, tid, w.Replicas)
+	tx.Write(w.Key, []byte(val))
+	committed, err := tx.Commit()
+	switch {
+	case err != nil:
+		op.Return = Unknown
+	case !committed:
+		return op, false
+	default:
+		op.Return = time.Since(start)
+	}
+	return op, true
+}
+
+// read lê a chave de uma única réplica e registra a operação em relação a start
+func (w Workload) read(cid, tid, replica string, start time.Time) (Operation, bool) {
+	op := Operation{Client: cid, Replica: replica, Call: time.Since(start)}
+	tx := w.tx(cid, tid, []string{replica})
+	// chave ausente conta como o valor inicial vazio do registro
+	val, _, err := tx.Get(w.Key)
+	if err != nil {
+		return op, false
+	}
+	if w.CertifyReads {
+		if ok, err := tx.Commit(); err != nil || !ok {
+			return op, false
+		}
+	}
+	op.Value = string(val)
+	op.Return = time.Since(start)
+	return op, true
+}
@@ -0,0 +1,59 @@
+// Package logging reúne o que os componentes compartilham sobre log/slog:
+// o logger padrão de cada componente, um logger mudo para benchmarks e
+// simulações, e a redação de valores gravados pelos clientes.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Discard descarta tudo; use como Logger para silenciar um componente
+var Discard = slog.New(slog.DiscardHandler)
+
+// Or retorna l, ou slog.Default() se l for nil
+func Or(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return slog.Default()
+	}
+	return l
+}
+
+// Redacted é um valor de item que não deve aparecer nos logs: só o
+// tamanho é registrado
+type Redacted []byte
+
+// LogValue implementa slog.LogValuer
+func (v Redacted) LogValue() slog.Value {
+	return slog.StringValue(fmt.Sprintf("<%d bytes>", len(v)))
+}
+
+// LevelOff é um nível acima de todos os outros: nada é registrado
+const LevelOff = slog.Level(100)
+
+// ParseLevel interpreta debug, info, warn, error ou off
+func ParseLevel(s string) (slog.Level, error) {
+	if strings.EqualFold(s, "off") {
+		return LevelOff, nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (want debug, info, warn, error or off)", s)
+	}
+	return l, nil
+}
+
+// New cria um logger que escreve em w a partir de level, no formato
+// "text" (chave=valor) ou "json"
+func New(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "text", "":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (want text or json)", format)
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError, "off": LevelOff} {
+		if got, err := ParseLevel(in); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("Expected error for an unknown level")
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, slog.LevelInfo, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Debug("hidden")
+	l.Error("boom", "cid", "c1", "value", Redacted("secret"))
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(strings.SplitN(buf.String(), "\n", 2)[0]), &rec); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if rec["msg"] != "boom" || rec["cid"] != "c1" || rec["value"] != "<6 bytes>" {
+		t.Fatalf("record = %v", rec)
+	}
+	if strings.Contains(buf.String(), "hidden") || strings.Contains(buf.String(), "secret") {
+		t.Fatalf("log = %s", buf.String())
+	}
+
+	buf.Reset()
+	off, _ := New(&buf, LevelOff, "text")
+	off.Error("nothing")
+	if buf.Len() != 0 {
+		t.Fatalf("level off wrote %q", buf.String())
+	}
+	if _, err := New(&buf, slog.LevelInfo, "xml"); err == nil {
+		t.Error("Expected error for an unknown format")
+	}
+}
+
+func TestOr(t *testing.T) {
+	if Or(nil) != slog.Default() || Or(Discard) != Discard {
+		t.Fatal("Or did not pick the logger")
+	}
+}
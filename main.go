@@ -1,61 +1,87 @@
 package main
 
 import (
-	"log"
+	"log/slog"
+	"os"
+	"time"
 
 	"github.com/hrodric0/dur-impl/broadcast"
 	"github.com/hrodric0/dur-impl/client"
+	"github.com/hrodric0/dur-impl/config"
+	"github.com/hrodric0/dur-impl/logging"
+	"github.com/hrodric0/dur-impl/network"
 	"github.com/hrodric0/dur-impl/server"
 )
 
+// main sobe a topologia de config.Default, ou a do arquivo passado como argumento
 func main() {
-	sequencerAddr := "localhost:8000"
-	replicas := []string{"localhost:8001", "localhost:8002"}
+	// o exemplo mostra cada passo do protocolo
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	topo := config.Default()
+	if len(os.Args) > 1 {
+		var err error
+		if topo, err = config.Load(os.Args[1]); err != nil {
+			fatal("invalid topology", err)
+		}
+	}
+	sequencerAddr := topo.Sequencer
+	replicas := topo.ReplicaAddrs()
 
-	log.Println("[Main] Iniciando sistema DUR")
+	slog.Info("starting DUR system", "sequencer", sequencerAddr, "replicas", replicas)
 	// Inicia Sequencer
 	go func() {
-		log.Printf("[Sequencer] Escutando em %s", sequencerAddr)
 		if err := broadcast.StartSequencer(sequencerAddr, replicas); err != nil {
-			log.Fatalf("[Sequencer] erro: %v", err)
+			fatal("sequencer", err)
 		}
 	}()
 
 	// Inicia Réplicas
-	for _, addr := range replicas {
-		a := addr
+	for _, spec := range topo.Replicas {
 		go func() {
-			log.Printf("[Replica %s] Inicializando", a)
-			if err := server.StartReplica(a); err != nil {
-				log.Fatalf("[Replica %s] erro: %v", a, err)
+			a := spec.Addr
+			rep := server.NewReplica(a)
+			if spec.DataDir != "" {
+				var err error
+				if rep, err = server.OpenReplica(a, spec.DataDir); err != nil {
+					fatal("replica "+a, err)
+				}
+			}
+			ln, err := network.TCP.Listen(a)
+			if err != nil {
+				fatal("replica "+a, err)
 			}
+			rep.Serve(ln)
 		}()
 	}
 
 	// Exemplo de transação
 	cli := client.NewTransaction("cid1", "tid1", replicas, sequencerAddr)
-	log.Println("[Client cid1] Iniciando transação tid1")
+	cli.ReadTimeout = time.Duration(topo.Timeouts.Read)
 
 	// Read
 	val, err := cli.Read("x")
 	if err != nil {
-		log.Printf("[Client cid1] Erro em Read(x): %v", err)
+		slog.Error("read x", "err", err)
 	} else {
-		log.Printf("[Client cid1] Read(x) -> %s", string(val))
+		slog.Info("read x", "value", logging.Redacted(val))
 	}
 
 	// Write
-	log.Println("[Client cid1] Write(x, new)")
 	cli.Write("x", []byte("new"))
 
 	// Commit
-	log.Println("[Client cid1] Enviando Commit")
 	committed, err := cli.Commit()
 	if err != nil {
-		log.Printf("[Client cid1] Erro em Commit: %v", err)
+		slog.Error("commit", "err", err)
 	} else {
-		log.Printf("[Client cid1] Commit result -> %v", committed)
+		slog.Info("commit", "committed", committed)
 	}
 
 	select {}
 }
+
+// fatal registra err e encerra o processo
+func fatal(what string, err error) {
+	slog.Error(what, "err", err)
+	os.Exit(1)
+}
@@ -0,0 +1,239 @@
+// Package metrics implementa contadores, gauges e histogramas com rótulos,
+// expostos no formato texto do Prometheus, sem dependências externas.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets são os limites padrão, em segundos, dos histogramas de latência
+var DefBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+// Registry guarda as métricas de um componente
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry cria um registro vazio
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// family é uma métrica com todas as suas séries, uma por combinação de rótulos
+type family struct {
+	name, help, kind string
+	labels           []string
+	buckets          []float64
+	fn               func() float64 // gauges calculados na coleta
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	value  float64  // contador ou gauge; soma no histograma
+	counts []uint64 // por bucket, não cumulativo; o último é +Inf
+	count  uint64
+}
+
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.families[f.name]; dup {
+		panic("metrics: duplicate metric " + f.name)
+	}
+	f.series = make(map[string]*series)
+	r.families[f.name] = f
+	return f
+}
+
+// get retorna a série dos valores de rótulo lv, criando-a se preciso;
+// chamado com f.mu travado
+func (f *family) get(lv []string) *series {
+	if len(lv) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", f.name, len(f.labels), len(lv)))
+	}
+	key := strings.Join(lv, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: slices.Clone(lv)}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter é um valor que só cresce
+type Counter struct{ f *family }
+
+// Counter registra um contador com os rótulos labels
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(&family{name: name, help: help, kind: "counter", labels: labels})}
+}
+
+// Inc soma um à série dos valores de rótulo lv
+func (c *Counter) Inc(lv ...string) { c.Add(1, lv...) }
+
+// Add soma v (não negativo) à série dos valores de rótulo lv
+func (c *Counter) Add(v float64, lv ...string) {
+	if v < 0 {
+		panic("metrics: counter " + c.f.name + " cannot decrease")
+	}
+	c.f.mu.Lock()
+	c.f.get(lv).value += v
+	c.f.mu.Unlock()
+}
+
+// Gauge é um valor que sobe e desce
+type Gauge struct{ f *family }
+
+// Gauge registra um gauge com os rótulos labels
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(&family{name: name, help: help, kind: "gauge", labels: labels})}
+}
+
+// Set define a série dos valores de rótulo lv
+func (g *Gauge) Set(v float64, lv ...string) {
+	g.f.mu.Lock()
+	g.f.get(lv).value = v
+	g.f.mu.Unlock()
+}
+
+// GaugeFunc registra um gauge sem rótulos calculado por fn a cada coleta
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(&family{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+// Histogram conta observações em buckets
+type Histogram struct{ f *family }
+
+// Histogram registra um histograma com os limites buckets, em ordem crescente
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !slices.IsSorted(buckets) {
+		panic("metrics: buckets of " + name + " are not sorted")
+	}
+	return &Histogram{r.register(&family{name: name, help: help, kind: "histogram", labels: labels, buckets: buckets})}
+}
+
+// Observe registra v na série dos valores de rótulo lv
+func (h *Histogram) Observe(v float64, lv ...string) {
+	i, _ := slices.BinarySearch(h.f.buckets, v)
+	h.f.mu.Lock()
+	s := h.f.get(lv)
+	s.counts[i]++
+	s.count++
+	s.value += v
+	h.f.mu.Unlock()
+}
+
+// WriteText escreve todas as métricas no formato texto do Prometheus,
+// em ordem de nome e de rótulos
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.families))
+	for n := range r.families {
+		names = append(names, n)
+	}
+	fams := r.families
+	r.mu.Unlock()
+	slices.Sort(names)
+
+	bw := bufio.NewWriter(w)
+	for _, n := range names {
+		fams[n].write(bw)
+	}
+	return bw.Flush()
+}
+
+func (f *family) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escape(f.help, false), f.name, f.kind)
+	if f.fn != nil {
+		fmt.Fprintf(w, "%s %s\n", f.name, formatFloat(f.fn()))
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		s := f.series[k]
+		if f.kind != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, labelSet(f.labels, s.values, ""), formatFloat(s.value))
+			continue
+		}
+		var cum uint64
+		for i, c := range s.counts {
+			cum += c
+			le := math.Inf(1)
+			if i < len(f.buckets) {
+				le = f.buckets[i]
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, labelSet(f.labels, s.values, formatFloat(le)), cum)
+		}
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, labelSet(f.labels, s.values, ""), formatFloat(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, labelSet(f.labels, s.values, ""), s.count)
+	}
+}
+
+// labelSet formata {a="x",b="y"}, com le ao fim se não for vazio
+func labelSet(names, values []string, le string) string {
+	if len(names) == 0 && le == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", n, escape(values[i], true))
+	}
+	if le != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "le=\"%s\"", le)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func escape(s string, quote bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quote {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// ServeHTTP responde a coleta de um scraper
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
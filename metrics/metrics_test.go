@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	aborts := r.Counter("dur_aborts_total", "Aborts by reason.", "reason")
+	aborts.Inc("stale-read")
+	aborts.Add(2, "phantom")
+	aborts.Inc("stale-read")
+	lag := r.Gauge("dur_lag", "Lag.", "replica")
+	lag.Set(3, `r"1`)
+	r.GaugeFunc("dur_keys", "Live keys.", func() float64 { return 42 })
+	lat := r.Histogram("dur_latency_seconds", "Latency.", []float64{0.1, 1})
+	lat.Observe(0.05)
+	lat.Observe(0.1)
+	lat.Observe(3)
+
+	var b strings.Builder
+	r.WriteText(&b)
+	want := `# HELP dur_aborts_total Aborts by reason.
+# TYPE dur_aborts_total counter
+dur_aborts_total{reason="phantom"} 2
+dur_aborts_total{reason="stale-read"} 2
+# HELP dur_keys Live keys.
+# TYPE dur_keys gauge
+dur_keys 42
+# HELP dur_lag Lag.
+# TYPE dur_lag gauge
+dur_lag{replica="r\"1"} 3
+# HELP dur_latency_seconds Latency.
+# TYPE dur_latency_seconds histogram
+dur_latency_seconds_bucket{le="0.1"} 2
+dur_latency_seconds_bucket{le="1"} 2
+dur_latency_seconds_bucket{le="+Inf"} 3
+dur_latency_seconds_sum 3.15
+dur_latency_seconds_count 3
+`
+	if b.String() != want {
+		t.Errorf("Unexpected exposition:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") || rec.Body.String() != want {
+		t.Errorf("Unexpected HTTP response %q:\n%s", ct, rec.Body)
+	}
+}
+
+func TestLabelMismatchPanics(t *testing.T) {
+	c := NewRegistry().Counter("c", "c", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic for missing label value")
+		}
+	}()
+	c.Inc("only-one")
+}
@@ -0,0 +1,25 @@
+package network
+
+import "time"
+
+// Clock é a hora vista por quem usa um Transport: os prazos das conexões e
+// as esperas dos componentes são medidos nele. Um Transport que também é um
+// Clock, como a rede da simulação (sim/), impõe o seu tempo virtual; os
+// demais usam o relógio real.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// ClockOf retorna o Clock de tr, ou o relógio real se tr não tiver um
+func ClockOf(tr Transport) Clock {
+	if c, ok := tr.(Clock); ok {
+		return c
+	}
+	return realClock{}
+}
@@ -0,0 +1,214 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrPartitioned é retornado ao falar com um nó do outro lado de uma partição
+var ErrPartitioned = errors.New("network partitioned")
+
+// Faults descreve as falhas aplicadas a cada mensagem de um enlace.
+// Cada Write na conexão discada é uma mensagem de ida; a primeira
+// leitura é a resposta. Descartar uma mensagem fecha a conexão, como
+// um reset TCP, para que o outro lado veja o erro em vez de travar.
+type Faults struct {
+	Drop        float64       // probabilidade de descartar a mensagem
+	Duplicate   float64       // probabilidade de entregar a mensagem duas vezes
+	Reorder     float64       // probabilidade de segurar a mensagem por ReorderHold
+	ReorderHold time.Duration // atraso extra que deixa mensagens posteriores passarem à frente
+	MinDelay    time.Duration // atraso mínimo por mensagem
+	MaxDelay    time.Duration // atraso máximo por mensagem
+}
+
+// FaultTransport envolve outro Transport e injeta falhas controladas pelo teste.
+// Use Node para obter a visão de cada componente, de modo que partições
+// e falhas por enlace saibam quem está falando com quem.
+type FaultTransport struct {
+	inner Transport
+
+	mu     sync.Mutex
+	rng    *rand.Rand
+	all    Faults
+	links  map[[2]string]Faults
+	cut    map[[2]string]bool
+	owners map[string]string
+}
+
+// NewFaultTransport cria um transporte com falhas sobre inner; seed fixa as escolhas aleatórias
+func NewFaultTransport(inner Transport, seed int64) *FaultTransport {
+	return &FaultTransport{
+		inner:  inner,
+		rng:    rand.New(rand.NewSource(seed)),
+		links:  make(map[[2]string]Faults),
+		cut:    make(map[[2]string]bool),
+		owners: make(map[string]string),
+	}
+}
+
+// Set define as falhas aplicadas a todos os enlaces sem configuração própria
+func (f *FaultTransport) Set(faults Faults) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.all = faults
+}
+
+// SetLink define as falhas do enlace direcionado from -> to
+func (f *FaultTransport) SetLink(from, to string, faults Faults) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.links[[2]string{from, to}] = faults
+}
+
+// Partition impede qualquer comunicação entre os nós de a e os de b
+func (f *FaultTransport) Partition(a, b []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, x := range a {
+		for _, y := range b {
+			f.cut[[2]string{x, y}] = true
+			f.cut[[2]string{y, x}] = true
+		}
+	}
+}
+
+// Heal remove todas as partições e falhas configuradas
+func (f *FaultTransport) Heal() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.all = Faults{}
+	f.links = make(map[[2]string]Faults)
+	f.cut = make(map[[2]string]bool)
+}
+
+// Node retorna a visão do transporte para o nó name
+func (f *FaultTransport) Node(name string) Transport {
+	return faultNode{f: f, name: name}
+}
+
+// Listen e Dial sem Node atuam como um nó anônimo
+func (f *FaultTransport) Listen(addr string) (net.Listener, error) { return f.Node("").Listen(addr) }
+func (f *FaultTransport) Dial(addr string) (net.Conn, error)       { return f.Node("").Dial(addr) }
+
+type faultNode struct {
+	f    *FaultTransport
+	name string
+}
+
+func (n faultNode) Listen(addr string) (net.Listener, error) {
+	ln, err := n.f.inner.Listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	if n.name != "" {
+		n.f.mu.Lock()
+		n.f.owners[ln.Addr().String()] = n.name
+		n.f.mu.Unlock()
+	}
+	return ln, nil
+}
+
+func (n faultNode) Dial(addr string) (net.Conn, error) {
+	to := n.f.owner(addr)
+	if n.f.partitioned(n.name, to) {
+		return nil, fmt.Errorf("dial %s from %s: %w", to, n.name, ErrPartitioned)
+	}
+	c, err := n.f.inner.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &faultConn{Conn: c, f: n.f, from: n.name, to: to}, nil
+}
+
+func (f *FaultTransport) owner(addr string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if name, ok := f.owners[addr]; ok {
+		return name
+	}
+	return addr
+}
+
+func (f *FaultTransport) partitioned(from, to string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cut[[2]string{from, to}]
+}
+
+// fate sorteia o destino de uma mensagem from -> to
+type fate struct {
+	cut, drop, dup bool
+	delay          time.Duration
+}
+
+func (f *FaultTransport) fate(from, to string) fate {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cut[[2]string{from, to}] {
+		return fate{cut: true}
+	}
+	fs, ok := f.links[[2]string{from, to}]
+	if !ok {
+		fs = f.all
+	}
+	var out fate
+	out.drop = fs.Drop > 0 && f.rng.Float64() < fs.Drop
+	out.dup = fs.Duplicate > 0 && f.rng.Float64() < fs.Duplicate
+	out.delay = fs.MinDelay
+	if fs.MaxDelay > fs.MinDelay {
+		out.delay += time.Duration(f.rng.Int63n(int64(fs.MaxDelay - fs.MinDelay)))
+	}
+	if fs.Reorder > 0 && f.rng.Float64() < fs.Reorder {
+		out.delay += fs.ReorderHold
+	}
+	return out
+}
+
+// faultConn aplica as falhas do lado de quem discou, nos dois sentidos
+type faultConn struct {
+	net.Conn
+	f        *FaultTransport
+	from, to string
+	replied  bool
+}
+
+func (c *faultConn) Write(p []byte) (int, error) {
+	ft := c.f.fate(c.from, c.to)
+	if ft.cut {
+		c.Conn.Close()
+		return 0, fmt.Errorf("write %s -> %s: %w", c.from, c.to, ErrPartitioned)
+	}
+	time.Sleep(ft.delay)
+	if ft.drop {
+		c.Conn.Close()
+		return len(p), nil
+	}
+	n, err := c.Conn.Write(p)
+	if err == nil && ft.dup {
+		c.Conn.Write(p)
+	}
+	return n, err
+}
+
+func (c *faultConn) Read(p []byte) (int, error) {
+	if !c.replied {
+		c.replied = true
+		ft := c.f.fate(c.to, c.from)
+		if ft.cut {
+			c.Conn.Close()
+			return 0, fmt.Errorf("read %s <- %s: %w", c.from, c.to, ErrPartitioned)
+		}
+		time.Sleep(ft.delay)
+		if ft.drop {
+			// espera a resposta ser enviada e então a descarta
+			c.Conn.Read(p)
+			c.Conn.Close()
+			return 0, net.ErrClosed
+		}
+	}
+	return c.Conn.Read(p)
+}
@@ -0,0 +1,69 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func startEcho(t *testing.T, tr Transport, addr string) {
+	ln, err := tr.Listen(addr)
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	go Serve(ln, func(raw []byte, conn net.Conn) {
+		var msg map[string]string
+		json.Unmarshal(raw, &msg)
+		json.NewEncoder(conn).Encode(map[string]string{"echo": msg["ping"]})
+	})
+}
+
+func TestFaultTransportPartition(t *testing.T) {
+	ft := NewFaultTransport(NewMemTransport(), 1)
+	startEcho(t, ft.Node("srv"), "srv:1")
+	cli := ft.Node("cli")
+
+	var resp map[string]string
+	if err := RequestWith(cli, "srv:1", map[string]string{"ping": "a"}, &resp); err != nil {
+		t.Fatalf("Request error before partition: %v", err)
+	}
+	ft.Partition([]string{"cli"}, []string{"srv"})
+	if err := RequestWith(cli, "srv:1", map[string]string{"ping": "b"}, &resp); !errors.Is(err, ErrPartitioned) {
+		t.Errorf("Expected ErrPartitioned, got %v", err)
+	}
+	if err := RequestWith(ft.Node("other"), "srv:1", map[string]string{"ping": "c"}, &resp); err != nil {
+		t.Errorf("Expected other node unaffected, got %v", err)
+	}
+	ft.Heal()
+	if err := RequestWith(cli, "srv:1", map[string]string{"ping": "d"}, &resp); err != nil || resp["echo"] != "d" {
+		t.Errorf("Expected echo after Heal, got %v err=%v", resp, err)
+	}
+}
+
+func TestFaultTransportDropAndDelay(t *testing.T) {
+	ft := NewFaultTransport(NewMemTransport(), 1)
+	startEcho(t, ft.Node("srv"), "srv:1")
+	cli := ft.Node("cli")
+
+	ft.SetLink("cli", "srv", Faults{Drop: 1})
+	var resp map[string]string
+	if err := RequestWith(cli, "srv:1", map[string]string{"ping": "a"}, &resp); err == nil {
+		t.Errorf("Expected error on dropped request")
+	}
+	ft.Heal()
+	ft.SetLink("srv", "cli", Faults{Drop: 1})
+	if err := RequestWith(cli, "srv:1", map[string]string{"ping": "a"}, &resp); err == nil {
+		t.Errorf("Expected error on dropped reply")
+	}
+	ft.Heal()
+	ft.Set(Faults{MinDelay: 20 * time.Millisecond, MaxDelay: 30 * time.Millisecond, Duplicate: 1})
+	start := time.Now()
+	if err := RequestWith(cli, "srv:1", map[string]string{"ping": "b"}, &resp); err != nil || resp["echo"] != "b" {
+		t.Fatalf("Expected delayed echo, got %v err=%v", resp, err)
+	}
+	if el := time.Since(start); el < 40*time.Millisecond {
+		t.Errorf("Expected request and reply delays, took %v", el)
+	}
+}
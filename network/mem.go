@@ -0,0 +1,226 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrConnRefused é retornado ao discar um endereço sem listener
+var ErrConnRefused = errors.New("connection refused")
+
+// MemTransport conecta componentes no mesmo processo, sem sockets.
+// Cada Dial cria um par de conexões com buffer, entregue ao listener do
+// endereço. Ao contrário de net.Pipe, Write não espera o outro lado ler,
+// como num socket TCP: quem responde antes de consumir todo o pedido não trava.
+type MemTransport struct {
+	mu        sync.Mutex
+	listeners map[string]*memListener
+}
+
+// NewMemTransport cria um transporte em memória vazio
+func NewMemTransport() *MemTransport {
+	return &MemTransport{listeners: make(map[string]*memListener)}
+}
+
+// Listen registra addr; falha se o endereço já estiver em uso
+func (m *MemTransport) Listen(addr string) (net.Listener, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.listeners[addr]; ok {
+		return nil, fmt.Errorf("mem listen %s: address already in use", addr)
+	}
+	ln := &memListener{owner: m, addr: memAddr(addr), conns: make(chan net.Conn), done: make(chan struct{})}
+	m.listeners[addr] = ln
+	return ln, nil
+}
+
+// Dial conecta ao listener registrado em addr
+func (m *MemTransport) Dial(addr string) (net.Conn, error) {
+	m.mu.Lock()
+	ln, ok := m.listeners[addr]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("mem dial %s: %w", addr, ErrConnRefused)
+	}
+	client, server := memPipe(memAddr("dial:"+addr), ln.addr)
+	select {
+	case ln.conns <- server:
+		return client, nil
+	case <-ln.done:
+		client.Close()
+		server.Close()
+		return nil, fmt.Errorf("mem dial %s: %w", addr, ErrConnRefused)
+	}
+}
+
+type memAddr string
+
+func (a memAddr) Network() string { return "mem" }
+func (a memAddr) String() string  { return string(a) }
+
+type memListener struct {
+	owner *MemTransport
+	addr  memAddr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func (l *memListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *memListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+		l.owner.mu.Lock()
+		if l.owner.listeners[string(l.addr)] == l {
+			delete(l.owner.listeners, string(l.addr))
+		}
+		l.owner.mu.Unlock()
+	})
+	return nil
+}
+
+func (l *memListener) Addr() net.Addr { return l.addr }
+
+// memPipe cria duas pontas ligadas por um buffer em cada sentido
+func memPipe(local, remote memAddr) (*memConn, *memConn) {
+	ab, ba := newMemBuffer(), newMemBuffer()
+	a := &memConn{r: ba, w: ab, local: local, remote: remote, done: make(chan struct{})}
+	b := &memConn{r: ab, w: ba, local: remote, remote: local, done: make(chan struct{})}
+	return a, b
+}
+
+// memBuffer é um sentido da conexão: bytes escritos e ainda não lidos
+type memBuffer struct {
+	mu         sync.Mutex
+	data       []byte
+	eof        bool // quem escreve fechou
+	readerGone bool // quem lê fechou
+	notify     chan struct{}
+}
+
+func newMemBuffer() *memBuffer { return &memBuffer{notify: make(chan struct{}, 1)} }
+
+func (b *memBuffer) wake() {
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+type memConn struct {
+	r, w          *memBuffer
+	local, remote memAddr
+
+	mu       sync.Mutex
+	deadline time.Time // prazo de leitura
+	once     sync.Once
+	done     chan struct{}
+}
+
+func (c *memConn) Read(p []byte) (int, error) {
+	for {
+		select {
+		case <-c.done:
+			return 0, net.ErrClosed
+		default:
+		}
+		c.r.mu.Lock()
+		if len(c.r.data) > 0 {
+			n := copy(p, c.r.data)
+			c.r.data = c.r.data[n:]
+			if len(c.r.data) > 0 {
+				c.r.wake()
+			}
+			c.r.mu.Unlock()
+			return n, nil
+		}
+		eof := c.r.eof
+		c.r.mu.Unlock()
+		if eof {
+			return 0, io.EOF
+		}
+
+		c.mu.Lock()
+		deadline := c.deadline
+		c.mu.Unlock()
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			wait := time.Until(deadline)
+			if wait <= 0 {
+				return 0, os.ErrDeadlineExceeded
+			}
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-c.r.notify:
+		case <-c.done:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (c *memConn) Write(p []byte) (int, error) {
+	select {
+	case <-c.done:
+		return 0, net.ErrClosed
+	default:
+	}
+	c.w.mu.Lock()
+	defer c.w.mu.Unlock()
+	if c.w.readerGone {
+		return 0, io.ErrClosedPipe
+	}
+	c.w.data = append(c.w.data, p...)
+	c.w.wake()
+	return len(p), nil
+}
+
+// Close entrega EOF ao outro lado depois dos bytes já escritos
+func (c *memConn) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+		c.w.mu.Lock()
+		c.w.eof = true
+		c.w.wake()
+		c.w.mu.Unlock()
+		c.r.mu.Lock()
+		c.r.readerGone = true
+		c.r.data = nil
+		c.r.mu.Unlock()
+	})
+	return nil
+}
+
+func (c *memConn) LocalAddr() net.Addr  { return c.local }
+func (c *memConn) RemoteAddr() net.Addr { return c.remote }
+
+func (c *memConn) SetDeadline(t time.Time) error { return c.SetReadDeadline(t) }
+
+func (c *memConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	c.r.wake()
+	return nil
+}
+
+// SetWriteDeadline não tem efeito: Write nunca bloqueia
+func (c *memConn) SetWriteDeadline(time.Time) error { return nil }
@@ -0,0 +1,76 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMemTransportRequest(t *testing.T) {
+	tr := NewMemTransport()
+	ln, err := tr.Listen("echo")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	go Serve(ln, func(raw []byte, conn net.Conn) {
+		var msg map[string]string
+		json.Unmarshal(raw, &msg)
+		json.NewEncoder(conn).Encode(map[string]string{"echo": msg["ping"]})
+	})
+
+	var resp map[string]string
+	if err := RequestWith(tr, "echo", map[string]string{"ping": "pong"}, &resp); err != nil {
+		t.Fatalf("Request error: %v", err)
+	}
+	if resp["echo"] != "pong" {
+		t.Errorf("Expected echo=pong, got %v", resp)
+	}
+}
+
+func TestMemTransportRefused(t *testing.T) {
+	tr := NewMemTransport()
+	if _, err := tr.Dial("nowhere"); !errors.Is(err, ErrConnRefused) {
+		t.Errorf("Expected ErrConnRefused, got %v", err)
+	}
+	ln, _ := tr.Listen("a")
+	if _, err := tr.Listen("a"); err == nil {
+		t.Errorf("Expected address in use error")
+	}
+	ln.Close()
+	if _, err := tr.Dial("a"); !errors.Is(err, ErrConnRefused) {
+		t.Errorf("Expected ErrConnRefused after Close, got %v", err)
+	}
+	if _, err := tr.Listen("a"); err != nil {
+		t.Errorf("Expected address reusable after Close, got %v", err)
+	}
+}
+
+// TestMemConnBuffered: Write não espera leitura, e Close entrega os bytes
+// pendentes antes do EOF
+func TestMemConnBuffered(t *testing.T) {
+	a, b := memPipe("a", "b")
+	if _, err := a.Write([]byte("hello ")); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	a.Write([]byte("world"))
+	a.Close()
+	got, err := io.ReadAll(b)
+	if err != nil || string(got) != "hello world" {
+		t.Fatalf("Expected 'hello world', got %q err=%v", got, err)
+	}
+	if _, err := b.Write([]byte("x")); err == nil {
+		t.Errorf("Expected write to closed peer to fail")
+	}
+}
+
+func TestMemConnReadDeadline(t *testing.T) {
+	a, _ := memPipe("a", "b")
+	a.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := a.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
@@ -2,45 +2,103 @@ package network
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net"
+	"os"
+	"syscall"
+	"time"
 )
 
+// Transport abstrai o meio pelo qual os componentes se conectam
+type Transport interface {
+	Listen(addr string) (net.Listener, error)
+	Dial(addr string) (net.Conn, error)
+}
+
+type tcpTransport struct{}
+
+func (tcpTransport) Listen(addr string) (net.Listener, error) { return net.Listen("tcp", addr) }
+func (tcpTransport) Dial(addr string) (net.Conn, error)       { return net.Dial("tcp", addr) }
+
+// TCP é o transporte padrão, sobre sockets reais
+var TCP Transport = tcpTransport{}
+
+// ErrNoReply indica que a conexão foi aberta e o pedido pode ter sido
+// entregue, mas a resposta não chegou: a conexão foi fechada ou resetada,
+// ou o prazo acabou. Uma resposta malformada não é ErrNoReply.
+var ErrNoReply = errors.New("no reply")
+
+// replyError classifica o erro ao decodificar a resposta de addr
+func replyError(addr string, err error) error {
+	var ne net.Error
+	lost := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout())
+	if lost {
+		return fmt.Errorf("%w from %s: %w", ErrNoReply, addr, err)
+	}
+	return fmt.Errorf("bad reply from %s: %w", addr, err)
+}
+
 // Listen decodifica JSON e delega ao handler
 func Listen(addr string, handler func(raw []byte, conn net.Conn)) error {
-	ln, err := net.Listen("tcp", addr)
+	return ListenWith(TCP, addr, handler)
+}
+
+// ListenWith escuta addr no transporte tr e delega ao handler
+func ListenWith(tr Transport, addr string, handler func(raw []byte, conn net.Conn)) error {
+	ln, err := tr.Listen(addr)
 	if err != nil {
 		return err
 	}
-	for {
-		conn, err := ln.Accept()
-		if err != nil {
-			continue
-		}
-		go func(c net.Conn) {
-			defer c.Close()
-			var raw json.RawMessage
-			if err := json.NewDecoder(c).Decode(&raw); err != nil {
-				return
-			}
-			handler(raw, c)
-		}(conn)
-	}
+	return Serve(ln, handler)
+}
+
+// Serve aceita conexões de ln, decodifica JSON e delega ao handler;
+// retorna quando ln é fechado. Use Server para encerrar com Shutdown.
+func Serve(ln net.Listener, handler func(raw []byte, conn net.Conn)) error {
+	return (&Server{Handler: handler}).Serve(ln)
 }
 
 // Request envia req e espera resp (JSON)
 func Request(addr string, req, resp any) error {
-	conn, err := net.Dial("tcp", addr)
+	return RequestWith(TCP, addr, req, resp)
+}
+
+// RequestWith envia req por tr e espera resp (JSON)
+func RequestWith(tr Transport, addr string, req, resp any) error {
+	return RequestTimeout(tr, addr, req, resp, 0)
+}
+
+// RequestTimeout é RequestWith com prazo total timeout; zero não impõe prazo
+func RequestTimeout(tr Transport, addr string, req, resp any, timeout time.Duration) error {
+	conn, err := tr.Dial(addr)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
-	json.NewEncoder(conn).Encode(req)
-	return json.NewDecoder(conn).Decode(resp)
+	if timeout > 0 {
+		conn.SetDeadline(ClockOf(tr).Now().Add(timeout))
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(conn).Decode(resp); err != nil {
+		return replyError(addr, err)
+	}
+	return nil
 }
 
 // Send envia msg (JSON) sem resposta
 func Send(addr string, msg any) error {
-	conn, err := net.Dial("tcp", addr)
+	return SendWith(TCP, addr, msg)
+}
+
+// SendWith envia msg (JSON) por tr sem resposta
+func SendWith(tr Transport, addr string, msg any) error {
+	conn, err := tr.Dial(addr)
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package network
 
 import (
 	"encoding/json"
+	"errors"
 	"net"
 	"testing"
 	"time"
@@ -20,7 +21,7 @@ func TestRequestAndListen(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Listen error: %v", err)
 	}
-	go Listen(ln.Addr().String(), handler)
+	go Serve(ln, handler)
 	time.Sleep(10 * time.Millisecond)
 
 	// perform Request
@@ -33,3 +34,85 @@ func TestRequestAndListen(t *testing.T) {
 		t.Errorf("Expected echo=pong, got %v", resp)
 	}
 }
+
+func TestRequestNoReply(t *testing.T) {
+	tr := NewMemTransport()
+	ln, _ := tr.Listen("mute")
+	go Serve(ln, func(raw []byte, conn net.Conn) {})
+
+	var resp map[string]string
+	if err := RequestWith(tr, "mute", map[string]string{"ping": "x"}, &resp); !errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected ErrNoReply, got %v", err)
+	}
+	if err := RequestWith(tr, "nowhere", nil, &resp); err == nil || errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected a dial error without ErrNoReply, got %v", err)
+	}
+}
+
+func TestRequestBadReplyIsNotNoReply(t *testing.T) {
+	tr := NewMemTransport()
+	ln, _ := tr.Listen("garbled")
+	go Serve(ln, func(raw []byte, conn net.Conn) { conn.Write([]byte("{not json}\n")) })
+
+	var resp map[string]string
+	err := RequestWith(tr, "garbled", map[string]string{"ping": "x"}, &resp)
+	if err == nil || errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected a decode error without ErrNoReply, got %v", err)
+	}
+	err = RequestTimeout(tr, "garbled", map[string]string{"ping": "x"}, &resp, time.Second)
+	if err == nil || errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected a decode error without ErrNoReply, got %v", err)
+	}
+}
+
+func TestRequestTimeoutIsNoReply(t *testing.T) {
+	tr := NewMemTransport()
+	ln, _ := tr.Listen("slow")
+	block := make(chan struct{})
+	defer close(block)
+	go Serve(ln, func(raw []byte, conn net.Conn) { <-block })
+
+	var resp map[string]string
+	if err := RequestTimeout(tr, "slow", map[string]string{"ping": "x"}, &resp, 10*time.Millisecond); !errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected ErrNoReply after the deadline, got %v", err)
+	}
+}
+
+func TestRequestReturnsEncodeError(t *testing.T) {
+	tr := NewMemTransport()
+	ln, _ := tr.Listen("echo")
+	go Serve(ln, func(raw []byte, conn net.Conn) {})
+
+	var resp map[string]string
+	var unsupported *json.UnsupportedTypeError
+	if err := RequestWith(tr, "echo", make(chan int), &resp); !errors.As(err, &unsupported) || errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected the encode error, got %v", err)
+	}
+	if err := RequestTimeout(tr, "echo", make(chan int), &resp, time.Second); !errors.As(err, &unsupported) || errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected the encode error, got %v", err)
+	}
+}
+
+// lateClock é um MemTransport cujo relógio está uma hora atrasado
+type lateClock struct{ *MemTransport }
+
+func (lateClock) Now() time.Time                         { return time.Now().Add(-time.Hour) }
+func (lateClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func TestRequestTimeoutUsesTransportClock(t *testing.T) {
+	tr := lateClock{NewMemTransport()}
+	ln, _ := tr.Listen("slow")
+	block := make(chan struct{})
+	defer close(block)
+	go Serve(ln, func(raw []byte, conn net.Conn) { <-block })
+
+	// no relógio do transporte, o prazo de um minuto já passou
+	var resp map[string]string
+	start := time.Now()
+	if err := RequestTimeout(tr, "slow", map[string]string{"ping": "x"}, &resp, time.Minute); !errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected ErrNoReply from a deadline on the transport's clock, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Expected the deadline to have passed already, waited %v", d)
+	}
+}
@@ -0,0 +1,151 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrServerClosed é retornado por Serve depois de Shutdown
+var ErrServerClosed = errors.New("server closed")
+
+// Server atende conexões com uma mensagem JSON cada, entregue ao Handler,
+// e pode ser encerrado com Shutdown. O valor zero, com Handler, está pronto.
+type Server struct {
+	Handler func(raw []byte, conn net.Conn)
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]bool // true enquanto o Handler roda
+	active    sync.WaitGroup    // handlers em andamento
+	closing   bool
+}
+
+// Serve aceita conexões de ln até Shutdown, quando retorna ErrServerClosed,
+// ou até ln ser fechado. Outros erros de Accept (como falta de descritores)
+// esperam um backoff crescente em vez de repetir em laço.
+func (s *Server) Serve(ln net.Listener) error {
+	if !s.track(ln) {
+		ln.Close()
+		return ErrServerClosed
+	}
+	var backoff time.Duration
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.closed() {
+				return ErrServerClosed
+			}
+			if errors.Is(err, net.ErrClosed) {
+				s.untrack(ln)
+				return err
+			}
+			backoff = min(max(2*backoff, 5*time.Millisecond), time.Second)
+			time.Sleep(backoff)
+			continue
+		}
+		backoff = 0
+		if !s.setConn(conn, false) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(c net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+	var raw json.RawMessage
+	if err := json.NewDecoder(c).Decode(&raw); err != nil {
+		return
+	}
+	if !s.setConn(c, true) {
+		return
+	}
+	defer s.active.Done()
+	s.Handler(raw, c)
+}
+
+// setConn registra c como ociosa ou ativa; falha se o servidor está fechando
+func (s *Server) setConn(c net.Conn, active bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]bool)
+	}
+	if active {
+		s.active.Add(1)
+	}
+	s.conns[c] = active
+	return true
+}
+
+func (s *Server) track(ln net.Listener) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return false
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[ln] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(ln net.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.listeners, ln)
+}
+
+func (s *Server) closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+// Shutdown para de aceitar conexões, fecha as ociosas e espera os
+// handlers em andamento. Se ctx acabar antes, fecha as conexões restantes
+// e retorna o erro de ctx.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	for ln := range s.listeners {
+		ln.Close()
+	}
+	for c, active := range s.conns {
+		if !active {
+			c.Close()
+		}
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for c := range s.conns {
+			c.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
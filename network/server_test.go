@@ -0,0 +1,96 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServeReturnsWhenListenerCloses(t *testing.T) {
+	tr := NewMemTransport()
+	ln, _ := tr.Listen("s")
+	done := make(chan error, 1)
+	go func() { done <- Serve(ln, func([]byte, net.Conn) {}) }()
+	ln.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("Expected net.ErrClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Serve kept running after the listener closed")
+	}
+}
+
+func TestServerShutdownDrains(t *testing.T) {
+	tr := NewMemTransport()
+	ln, _ := tr.Listen("s")
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	srv := &Server{Handler: func(raw []byte, c net.Conn) {
+		started <- struct{}{}
+		<-release
+		c.Write([]byte("\"done\"\n"))
+	}}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+
+	idle, _ := tr.Dial("s") // conectada, mas sem pedido
+	reply := make(chan error, 1)
+	go func() {
+		var out string
+		reply <- RequestWith(tr, "s", "ping", &out)
+	}()
+	<-started
+
+	shut := make(chan error, 1)
+	go func() { shut <- srv.Shutdown(context.Background()) }()
+	if err := <-served; !errors.Is(err, ErrServerClosed) {
+		t.Fatalf("Expected ErrServerClosed from Serve, got %v", err)
+	}
+	if _, err := tr.Dial("s"); !errors.Is(err, ErrConnRefused) {
+		t.Errorf("Expected new connections refused, got %v", err)
+	}
+	idle.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := idle.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected idle connection closed, got %v", err)
+	}
+	select {
+	case err := <-shut:
+		t.Fatalf("Shutdown returned %v with a handler in flight", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-reply; err != nil {
+		t.Errorf("Expected in-flight request answered, got %v", err)
+	}
+	if err := <-shut; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+}
+
+func TestServerShutdownDeadline(t *testing.T) {
+	tr := NewMemTransport()
+	ln, _ := tr.Listen("s")
+	stuck := make(chan struct{})
+	defer close(stuck)
+	srv := &Server{Handler: func(raw []byte, c net.Conn) { <-stuck }}
+	go srv.Serve(ln)
+	reply := make(chan error, 1)
+	go func() {
+		var out string
+		reply <- RequestWith(tr, "s", "ping", &out)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected DeadlineExceeded, got %v", err)
+	}
+	if err := <-reply; !errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected the stuck request cut off, got %v", err)
+	}
+}
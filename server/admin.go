@@ -0,0 +1,115 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/hrodric0/dur-impl/admin"
+)
+
+// record guarda v no histórico de item, até KeyHistory versões; chamado
+// com mu travado
+func (rep *Replica) record(item string, v admin.Version) {
+	if rep.KeyHistory <= 0 {
+		return
+	}
+	if rep.history == nil {
+		rep.history = make(map[string][]admin.Version)
+		rep.truncated = make(map[string]bool)
+	}
+	h := append(rep.history[item], v)
+	if len(h) > rep.KeyHistory {
+		h = append([]admin.Version(nil), h[len(h)-rep.KeyHistory:]...)
+		rep.truncated[item] = true
+	}
+	rep.history[item] = h
+}
+
+// AdminStatus resume o estado da réplica para GET /admin/status
+func (rep *Replica) AdminStatus() admin.Status {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	st := admin.Status{Role: "replica", Addr: rep.Addr, LastCommitted: rep.LastCommitted, LastApplied: rep.LastCommitted}
+	for _, vv := range rep.Db {
+		if vv.Deleted {
+			st.Tombstones++
+		} else {
+			st.Keys++
+		}
+	}
+	return st
+}
+
+// Key retorna a versão atual de item e as versões guardadas; ok é falso
+// se a réplica não conhece a chave
+func (rep *Replica) Key(item string) (k admin.Key, ok bool) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	vv, ok := rep.Db[item]
+	if !ok {
+		return admin.Key{Item: item}, false
+	}
+	k = admin.Key{
+		Item:      item,
+		Found:     !vv.Deleted,
+		Current:   admin.Version{Version: vv.Version, Value: vv.Value, Deleted: vv.Deleted},
+		History:   append([]admin.Version{}, rep.history[item]...),
+		Truncated: rep.truncated[item],
+	}
+	if n := len(k.History); n > 0 && k.History[n-1].Version == vv.Version {
+		k.Current = k.History[n-1]
+	}
+	return k, true
+}
+
+// Aborts retorna os aborts recentes, do mais novo ao mais antigo
+func (rep *Replica) Aborts() []admin.Abort { return rep.aborts.List() }
+
+// Admin é o handler da API de administração:
+//
+//	GET /admin/status      estado e última sequência aplicada
+//	GET /admin/keys/{key}  versão atual e histórico de uma chave
+//	GET /admin/aborts      aborts recentes, com o motivo
+//	GET /admin/config      configuração da réplica
+func (rep *Replica) Admin() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/status", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, rep.AdminStatus())
+	})
+	mux.HandleFunc("GET /admin/keys/{key...}", func(w http.ResponseWriter, r *http.Request) {
+		k, ok := rep.Key(r.PathValue("key"))
+		if !ok {
+			admin.WriteJSON(w, http.StatusNotFound, admin.Error{Error: "key " + k.Item + " not found"})
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, k)
+	})
+	mux.HandleFunc("GET /admin/aborts", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, rep.Aborts())
+	})
+	mux.HandleFunc("GET /admin/config", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, rep.config())
+	})
+	return mux
+}
+
+// replicaConfig é a resposta de GET /admin/config
+type replicaConfig struct {
+	Addr               string   `json:"addr"`
+	DataDir            string   `json:"dataDir,omitempty"`
+	Peers              []string `json:"peers,omitempty"`
+	TombstoneRetention uint64   `json:"tombstoneRetention"`
+	DecisionWindow     int      `json:"decisionWindow"`
+	KeyHistory         int      `json:"keyHistory"`
+	Tracing            bool     `json:"tracing"`
+}
+
+func (rep *Replica) config() replicaConfig {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	c := replicaConfig{Addr: rep.Addr, TombstoneRetention: rep.TombstoneRetention, DecisionWindow: rep.DecisionWindow, KeyHistory: rep.KeyHistory, Tracing: rep.Tracer != nil, Peers: rep.Peers}
+	if rep.wal != nil {
+		c.DataDir = filepath.Dir(rep.wal.Name())
+	}
+	return c
+}
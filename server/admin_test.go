@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/admin"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+func TestReplicaAdmin(t *testing.T) {
+	rep := NewReplica("r")
+	rep.KeyHistory = 2
+	for _, v := range []string{"a", "b", "c"} {
+		rep.Certify(types.CommitRequest{Cid: "c", Tid: v, Ws: []types.WriteEntry{{Item: "k", Value: []byte(v)}}})
+	}
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "del", Ws: []types.WriteEntry{{Item: "x", Op: types.OpDelete}}})
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "stale", Rs: []types.ReadEntry{{Item: "k", Version: 1}}})
+	srv := httptest.NewServer(rep.Admin())
+	defer srv.Close()
+
+	var k admin.Key
+	if err := admin.Get(srv.Client(), srv.URL, "/admin/keys/k", &k); err != nil {
+		t.Fatal(err)
+	}
+	if !k.Found || !k.Truncated || len(k.History) != 2 || k.History[0].Tid != "b" || k.Current.Tid != "c" || string(k.Current.Value) != "c" || k.Current.Version != 3 {
+		t.Fatalf("key = %+v", k)
+	}
+	if err := admin.Get(srv.Client(), srv.URL, "/admin/keys/x", &k); err != nil || k.Found || !k.Current.Deleted {
+		t.Fatalf("Expected the tombstone of x, got %+v, %v", k, err)
+	}
+	if err := admin.Get(srv.Client(), srv.URL, "/admin/keys/nope", &k); err == nil {
+		t.Fatal("Expected not found for an unknown key")
+	}
+
+	var st admin.Status
+	if err := admin.Get(srv.Client(), srv.URL, "/admin/status", &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Role != "replica" || st.LastCommitted != 4 || st.LastApplied != 4 || st.Keys != 1 || st.Tombstones != 1 {
+		t.Fatalf("status = %+v", st)
+	}
+	var aborts []admin.Abort
+	if err := admin.Get(srv.Client(), srv.URL, "/admin/aborts", &aborts); err != nil {
+		t.Fatal(err)
+	}
+	if len(aborts) != 1 || aborts[0].Tid != "stale" || aborts[0].Reason != types.ReasonStaleRead {
+		t.Fatalf("aborts = %+v", aborts)
+	}
+	var cfg map[string]any
+	if err := admin.Get(srv.Client(), srv.URL, "/admin/config", &cfg); err != nil || cfg["keyHistory"] != float64(2) {
+		t.Fatalf("config = %v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"slices"
+
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// DigestBuckets é o número de folhas da árvore de hashes do Db; a chave k
+// fica no bucket dado pelo primeiro byte de sha256(k)
+const DigestBuckets = 256
+
+// chainWindow é por quantos commits a réplica lembra o hash encadeado,
+// para comparar réplicas que estão em sequências diferentes
+const chainWindow = 1024
+
+type chainPoint struct {
+	seq   uint64
+	chain [32]byte
+}
+
+// BucketOf é o bucket do digest em que item fica
+func BucketOf(item string) int {
+	sum := sha256.Sum256([]byte(item))
+	return int(sum[0])
+}
+
+func writeBytes(h hash.Hash, b []byte) {
+	h.Write(binary.AppendUvarint(nil, uint64(len(b))))
+	h.Write(b)
+}
+
+// chainCommit encadeia o commit recém-aplicado, com os valores resultantes
+// next; chamado com mu travado
+func (rep *Replica) chainCommit(req types.CommitRequest, next []VersionedValue) {
+	h := sha256.New()
+	h.Write(rep.chain[:])
+	h.Write(binary.AppendUvarint(nil, rep.LastCommitted))
+	writeBytes(h, []byte(req.Cid))
+	writeBytes(h, []byte(req.Tid))
+	for i, we := range req.Ws {
+		writeBytes(h, []byte(we.Item))
+		if next[i].Deleted {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+		writeBytes(h, next[i].Value)
+	}
+	h.Sum(rep.chain[:0])
+	rep.pushChain()
+}
+
+// pushChain guarda o chain corrente como o de LastCommitted
+func (rep *Replica) pushChain() {
+	rep.chains = append(rep.chains, chainPoint{rep.LastCommitted, rep.chain})
+	if len(rep.chains) > chainWindow {
+		rep.chains = slices.Clone(rep.chains[len(rep.chains)-chainWindow:])
+	}
+}
+
+// chainAt retorna o chain depois do commit seq, se ainda lembrado
+func (rep *Replica) chainAt(seq uint64) ([32]byte, bool) {
+	if seq == rep.LastCommitted {
+		return rep.chain, true
+	}
+	i, ok := slices.BinarySearchFunc(rep.chains, seq, func(p chainPoint, s uint64) int {
+		switch {
+		case p.seq < s:
+			return -1
+		case p.seq > s:
+			return 1
+		}
+		return 0
+	})
+	if !ok {
+		return [32]byte{}, false
+	}
+	return rep.chains[i].chain, true
+}
+
+// leaves calcula o hash de cada bucket sobre as chaves, em ordem; os
+// tombstones entram porque mudam a certificação de leituras de ausência.
+// Chamado com mu travado.
+func (rep *Replica) leaves() [DigestBuckets][32]byte {
+	var hs [DigestBuckets]hash.Hash
+	for i := range hs {
+		hs[i] = sha256.New()
+	}
+	for _, k := range rep.index.between("", "") {
+		vv := rep.Db[k]
+		h := hs[BucketOf(k)]
+		writeBytes(h, []byte(k))
+		h.Write(binary.AppendUvarint(nil, vv.Version))
+		if vv.Deleted {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+		writeBytes(h, vv.Value)
+	}
+	var out [DigestBuckets][32]byte
+	for i, h := range hs {
+		h.Sum(out[i][:0])
+	}
+	return out
+}
+
+// Digest resume o estado da réplica: o hash encadeado em req.Seq (ou na
+// sequência atual), a árvore de hashes do Db, as chaves de req.Buckets e,
+// com req.Decisions, a janela de decisões
+func (rep *Replica) Digest(req types.DigestRequest) types.DigestReply {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	out := types.DigestReply{Seq: rep.LastCommitted, ChainSeq: req.Seq}
+	if req.Seq == 0 {
+		out.ChainSeq = rep.LastCommitted
+	}
+	if c, ok := rep.chainAt(out.ChainSeq); ok {
+		out.Chain = hex.EncodeToString(c[:])
+	}
+	leaves := rep.leaves()
+	root := sha256.New()
+	out.Buckets = make([]string, DigestBuckets)
+	for i, l := range leaves {
+		root.Write(l[:])
+		out.Buckets[i] = hex.EncodeToString(l[:])
+	}
+	out.Root = hex.EncodeToString(root.Sum(nil))
+	if len(req.Buckets) > 0 {
+		want := map[int]bool{}
+		for _, b := range req.Buckets {
+			want[b] = true
+		}
+		for _, k := range rep.index.between("", "") {
+			if vv := rep.Db[k]; want[BucketOf(k)] {
+				out.Items = append(out.Items, types.DigestItem{Item: k, Value: vv.Value, Version: vv.Version, Deleted: vv.Deleted})
+			}
+		}
+	}
+	if req.Decisions {
+		for _, key := range rep.decided {
+			out.Decisions = append(out.Decisions, rep.decisions[key])
+		}
+	}
+	rep.logger().Debug("digest request", "seq", out.Seq, "chain_seq", out.ChainSeq, "buckets", len(req.Buckets))
+	return out
+}
+
+// Repair instala req na réplica, se ela ainda estiver em req.FromSeq: as
+// chaves dos buckets de req, tombstones incluídos, passam a ser exatamente
+// req.Items, e a
+// sequência e o hash encadeado passam a ser os da referência. O reparo é
+// gravado no log antes de aplicado.
+func (rep *Replica) Repair(req types.RepairRequest) types.RepairReply {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	if rep.LastCommitted != req.FromSeq {
+		return types.RepairReply{Seq: rep.LastCommitted, Error: fmt.Sprintf("replica at seq %d, not %d", rep.LastCommitted, req.FromSeq)}
+	}
+	if err := rep.persist(req); err != nil {
+		rep.logger().Error("wal write failed", "repair", true, "err", err)
+		return types.RepairReply{Seq: rep.LastCommitted, Error: "wal: " + err.Error()}
+	}
+	if err := rep.applyRepair(req); err != nil {
+		return types.RepairReply{Seq: rep.LastCommitted, Error: err.Error()}
+	}
+	rep.logger().Warn("replica repaired", "from_seq", req.FromSeq, "seq", req.Seq, "buckets", len(req.Buckets), "items", len(req.Items))
+	return types.RepairReply{Repaired: true, Seq: rep.LastCommitted}
+}
+
+// applyRepair aplica req; chamado com mu travado, também na reaplicação do log
+func (rep *Replica) applyRepair(req types.RepairRequest) error {
+	chain, err := hex.DecodeString(req.Chain)
+	if err != nil || len(chain) != len(rep.chain) {
+		return fmt.Errorf("bad chain %q", req.Chain)
+	}
+	buckets := map[int]bool{}
+	for _, b := range req.Buckets {
+		buckets[b] = true
+	}
+	keep := map[string]bool{}
+	for _, it := range req.Items {
+		keep[it.Item] = true
+	}
+	for _, k := range slices.Clone(rep.index.between("", "")) {
+		if buckets[BucketOf(k)] && !keep[k] {
+			// a referência não tem nem tombstone; varreduras anteriores ao
+			// reparo abortam por purged
+			delete(rep.Db, k)
+			delete(rep.history, k)
+			delete(rep.truncated, k)
+			rep.index.remove(k)
+		}
+	}
+	for _, it := range req.Items {
+		rep.put(it.Item, VersionedValue{Value: it.Value, Version: it.Version, Deleted: it.Deleted})
+	}
+	// refaz a fila da coleta com os tombstones que ficaram, em ordem de versão
+	rep.tombstones = rep.tombstones[:0]
+	for _, k := range rep.index.between("", "") {
+		if vv := rep.Db[k]; vv.Deleted {
+			rep.tombstones = append(rep.tombstones, tombstone{item: k, version: vv.Version})
+		}
+	}
+	slices.SortStableFunc(rep.tombstones, func(a, b tombstone) int { return cmp.Compare(a.version, b.version) })
+	if req.Decisions != nil {
+		// as decisões locais podem ser de commits que o reparo desfez
+		rep.decisions, rep.decided = nil, nil
+		for _, dec := range req.Decisions {
+			rep.remember(dec.Cid+"/"+dec.Tid, dec)
+		}
+	}
+	rep.LastCommitted = req.Seq
+	rep.purged = max(rep.purged, req.Seq)
+	copy(rep.chain[:], chain)
+	rep.chains = nil
+	rep.pushChain()
+	return nil
+}
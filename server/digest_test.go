@@ -0,0 +1,209 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/types"
+)
+
+func put(rep *Replica, tid, item, val string) types.CommitDecision {
+	return rep.Certify(types.CommitRequest{Cid: "c", Tid: tid, Ws: []types.WriteEntry{{Item: item, Value: []byte(val)}}})
+}
+
+func TestDigestDetectsDivergence(t *testing.T) {
+	a, b := NewReplica("a"), NewReplica("b")
+	if da, db := a.Digest(types.DigestRequest{}), b.Digest(types.DigestRequest{}); da.Root != db.Root || da.Chain != db.Chain {
+		t.Fatalf("Expected equal digests for fresh replicas")
+	}
+	put(a, "t1", "k", "1")
+	put(b, "t1", "k", "1")
+	before := a.Digest(types.DigestRequest{})
+	if d := b.Digest(types.DigestRequest{}); d.Root != before.Root || d.Chain != before.Chain || d.Seq != 1 {
+		t.Fatalf("Expected equal digests after the same commit, got %+v and %+v", before, d)
+	}
+
+	// b perde t2: na sequência 2 os hashes encadeados diferem
+	put(a, "t2", "j", "2")
+	put(a, "t3", "k", "3")
+	put(b, "t3", "k", "3")
+	da, db := a.Digest(types.DigestRequest{Seq: 2}), b.Digest(types.DigestRequest{})
+	if db.Seq != 2 || da.ChainSeq != 2 || da.Chain == db.Chain {
+		t.Fatalf("Expected chains to differ at seq 2, got %+v and %+v", da, db)
+	}
+	if a.Digest(types.DigestRequest{Seq: 1}).Chain != before.Chain {
+		t.Errorf("Expected a to remember the chain at seq 1")
+	}
+
+	// as folhas apontam os buckets das chaves diferentes
+	full := a.Digest(types.DigestRequest{})
+	var diff []int
+	for i := range full.Buckets {
+		if full.Buckets[i] != db.Buckets[i] {
+			diff = append(diff, i)
+		}
+	}
+	want := map[int]bool{BucketOf("j"): true, BucketOf("k"): true}
+	if len(diff) != len(want) || !want[diff[0]] {
+		t.Fatalf("Expected buckets %v to differ, got %v", want, diff)
+	}
+	items := a.Digest(types.DigestRequest{Buckets: diff}).Items
+	if len(items) != 2 {
+		t.Fatalf("Expected j and k in the differing buckets, got %+v", items)
+	}
+
+	r := b.Repair(types.RepairRequest{Repair: true, FromSeq: 1, Seq: full.Seq, Chain: full.Chain, Buckets: diff, Items: items})
+	if r.Repaired {
+		t.Fatalf("Expected repair from a stale seq to be refused, got %+v", r)
+	}
+	r = b.Repair(types.RepairRequest{Repair: true, FromSeq: db.Seq, Seq: full.Seq, Chain: full.Chain, Buckets: diff, Items: items})
+	if !r.Repaired || r.Seq != 3 {
+		t.Fatalf("Expected repair, got %+v", r)
+	}
+	if d := b.Digest(types.DigestRequest{}); d.Root != full.Root || d.Chain != full.Chain {
+		t.Fatalf("Expected b to match a after repair")
+	}
+	// os próximos commits seguem iguais nas duas
+	put(a, "t4", "k", "4")
+	put(b, "t4", "k", "4")
+	if da, db := a.Digest(types.DigestRequest{}), b.Digest(types.DigestRequest{}); da.Root != db.Root || da.Chain != db.Chain {
+		t.Fatalf("Expected equal digests after repair and a new commit")
+	}
+}
+
+func TestDigestChainWindow(t *testing.T) {
+	rep := NewReplica("r")
+	for i := 0; i < chainWindow+10; i++ {
+		put(rep, fmt.Sprint(i), "k", fmt.Sprint(i))
+	}
+	if d := rep.Digest(types.DigestRequest{Seq: 5}); d.Chain != "" {
+		t.Errorf("Expected the chain at seq 5 to be forgotten, got %q", d.Chain)
+	}
+	if d := rep.Digest(types.DigestRequest{Seq: 20}); d.Chain == "" || d.ChainSeq != 20 {
+		t.Errorf("Expected the chain at seq 20, got %+v", d)
+	}
+}
+
+func TestRepairReplayed(t *testing.T) {
+	ref := NewReplica("ref")
+	put(ref, "t1", "k", "1")
+	put(ref, "t2", "j", "2")
+	want := ref.Digest(types.DigestRequest{})
+
+	dir := t.TempDir()
+	rep, _ := OpenReplica("r", dir)
+	put(rep, "t1", "k", "1")
+	put(rep, "t9", "stray", "x")
+	var buckets []int
+	for i := range want.Buckets {
+		buckets = append(buckets, i)
+	}
+	items := ref.Digest(types.DigestRequest{Buckets: buckets}).Items
+	if r := rep.Repair(types.RepairRequest{Repair: true, FromSeq: 2, Seq: want.Seq, Chain: want.Chain, Buckets: buckets, Items: items}); !r.Repaired {
+		t.Fatalf("Expected repair, got %+v", r)
+	}
+	put(rep, "t3", "k", "3")
+	put(ref, "t3", "k", "3")
+	rep.Close()
+
+	again, err := OpenReplica("r", dir)
+	if err != nil {
+		t.Fatalf("Reopen error: %v", err)
+	}
+	defer again.Close()
+	got, exp := again.Digest(types.DigestRequest{}), ref.Digest(types.DigestRequest{})
+	if got.Root != exp.Root || got.Chain != exp.Chain || got.Seq != 3 {
+		t.Fatalf("Expected the replayed repair to match the reference, got %+v want %+v", got, exp)
+	}
+	if rr := again.Read(types.ReadRequest{Item: "stray"}); rr.Found {
+		t.Errorf("Expected stray removed by the repair, got %+v", rr)
+	}
+}
+
+func TestRepairCopiesDecisions(t *testing.T) {
+	ref := NewReplica("ref")
+	put(ref, "t1", "k", "1")
+	put(ref, "t2", "j", "2")
+	dir := t.TempDir()
+	rep, _ := OpenReplica("r", dir)
+	put(rep, "t1", "k", "1")
+	put(rep, "t9", "stray", "x")
+
+	var buckets []int
+	for i := 0; i < DigestBuckets; i++ {
+		buckets = append(buckets, i)
+	}
+	d := ref.Digest(types.DigestRequest{Buckets: buckets, Decisions: true})
+	if len(d.Decisions) != 2 || d.Decisions[1].Tid != "t2" {
+		t.Fatalf("Expected t1 and t2 in the digest, got %+v", d.Decisions)
+	}
+	if r := rep.Repair(types.RepairRequest{Repair: true, FromSeq: 2, Seq: d.Seq, Chain: d.Chain, Buckets: buckets, Items: d.Items, Decisions: d.Decisions}); !r.Repaired {
+		t.Fatalf("Expected repair, got %+v", r)
+	}
+	check := func(rep *Replica) {
+		t.Helper()
+		if st := rep.Status(types.StatusRequest{Cid: "c", Tid: "t2"}); !st.Known || st.Decision.Seq != 2 {
+			t.Errorf("Expected t2 known from the reference, got %+v", st)
+		}
+		if st := rep.Status(types.StatusRequest{Cid: "c", Tid: "t9"}); st.Known {
+			t.Errorf("Expected the undone t9 forgotten, got %+v", st)
+		}
+	}
+	check(rep)
+	if dec := put(rep, "t2", "j", "2"); dec.Seq != 2 || rep.LastCommitted != 2 {
+		t.Fatalf("Expected the resubmitted t2 not to be reapplied, got %+v at seq %d", dec, rep.LastCommitted)
+	}
+	rep.Close()
+
+	again, err := OpenReplica("r", dir)
+	if err != nil {
+		t.Fatalf("Reopen error: %v", err)
+	}
+	defer again.Close()
+	check(again)
+}
+
+func TestRepairCopiesTombstones(t *testing.T) {
+	ref := NewReplica("ref")
+	put(ref, "t1", "k", "1")
+	ref.Certify(types.CommitRequest{Cid: "c", Tid: "t2", Ws: []types.WriteEntry{{Item: "k", Op: types.OpDelete}}})
+	dir := t.TempDir()
+	rep, _ := OpenReplica("r", dir)
+	if a, b := ref.Digest(types.DigestRequest{}), rep.Digest(types.DigestRequest{}); a.Root == b.Root {
+		t.Fatalf("Expected the tombstone of k to change the digest")
+	}
+
+	var buckets []int
+	for i := 0; i < DigestBuckets; i++ {
+		buckets = append(buckets, i)
+	}
+	d := ref.Digest(types.DigestRequest{Buckets: buckets})
+	if i := slices.IndexFunc(d.Items, func(it types.DigestItem) bool { return it.Item == "k" }); i < 0 || !d.Items[i].Deleted || d.Items[i].Version != 2 {
+		t.Fatalf("Expected the tombstone of k in the digest, got %+v", d.Items)
+	}
+	if r := rep.Repair(types.RepairRequest{Repair: true, Seq: d.Seq, Chain: d.Chain, Buckets: buckets, Items: d.Items}); !r.Repaired {
+		t.Fatalf("Expected repair, got %+v", r)
+	}
+	rep.Close()
+	rep, err := OpenReplica("r", dir)
+	if err != nil {
+		t.Fatalf("Reopen error: %v", err)
+	}
+	defer rep.Close()
+	if a, b := ref.Digest(types.DigestRequest{}), rep.Digest(types.DigestRequest{}); a.Root != b.Root {
+		t.Errorf("Expected equal digests after repair")
+	}
+
+	// ler k como ausente antes da remoção aborta nas duas réplicas
+	absent := types.CommitRequest{Cid: "c", Tid: "t3", Rs: []types.ReadEntry{{Item: "k", Absent: true}}, Ws: []types.WriteEntry{{Item: "k", Value: []byte("x")}}}
+	for _, r := range []*Replica{ref, rep} {
+		if dec := r.Certify(absent); dec.Commit || dec.Reason != types.ReasonStaleRead {
+			t.Errorf("Expected %s to abort the stale absent read, got %+v", r.Addr, dec)
+		}
+	}
+	// e o tombstone copiado é coletado como os demais
+	if n := rep.CollectTombstones(2); n != 1 {
+		t.Errorf("Expected the copied tombstone to be collected, got %d", n)
+	}
+}
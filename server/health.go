@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/hrodric0/dur-impl/health"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// Prazos do alcance dos Peers
+const (
+	peerTimeout  = time.Second            // prazo de cada pedido a um par
+	catchUpRetry = 200 * time.Millisecond // espera entre tentativas de alcance
+)
+
+// DefaultCatchUpTimeout é o CatchUpTimeout padrão
+const DefaultCatchUpTimeout = 10 * time.Second
+
+var (
+	errNotServing = errors.New("replica not serving")
+	errCatchingUp = errors.New("replica catching up with its peers")
+)
+
+// Ping responde o heartbeat do detector de falhas
+func (rep *Replica) Ping() types.PingReply {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	return types.PingReply{Seq: rep.LastCommitted, Ready: rep.notReady() == nil}
+}
+
+// live retorna nil enquanto a réplica atende; chamado com mu travado
+func (rep *Replica) live() error {
+	if !rep.serving || rep.closed {
+		return errNotServing
+	}
+	return nil
+}
+
+// notReady retorna nil se a réplica atende e já alcançou os Peers;
+// chamado com mu travado
+func (rep *Replica) notReady() error {
+	if err := rep.live(); err != nil {
+		return err
+	}
+	if rep.catchingUp {
+		return errCatchingUp
+	}
+	return nil
+}
+
+// Health atende GET /healthz, que responde 200 enquanto a réplica atende,
+// e GET /readyz, que responde 200 depois da recuperação do log e do
+// alcance dos Peers
+func (rep *Replica) Health() http.Handler {
+	locked := func(check func() error) func() error {
+		return func() error {
+			rep.mu.Lock()
+			defer rep.mu.Unlock()
+			return check()
+		}
+	}
+	return health.Handler(locked(rep.live), locked(rep.notReady))
+}
+
+// startCatchUp passa a alcançar os Peers em segundo plano, se há Peers e
+// ainda não está alcançando; chamado com mu travado, depois de Start
+func (rep *Replica) startCatchUp() {
+	if len(rep.Peers) == 0 || rep.catchingUp || rep.catchCtx == nil || rep.catchCtx.Err() != nil {
+		return
+	}
+	rep.catchingUp = true
+	go rep.catchUp(rep.catchCtx)
+}
+
+func (rep *Replica) catchUpTimeout() time.Duration {
+	if rep.CatchUpTimeout <= 0 {
+		return DefaultCatchUpTimeout
+	}
+	return rep.CatchUpTimeout
+}
+
+// catchUp pergunta a sequência dos Peers e, enquanto algum estiver
+// adiante, copia o estado do mais adiantado pelo caminho do reparo. A
+// réplica fica pronta quando nenhum par que responde está adiante, ou na
+// sequência local se nenhum par responder em CatchUpTimeout; até lá,
+// recusa commits.
+func (rep *Replica) catchUp(ctx context.Context) {
+	tr := rep.Transport
+	if tr == nil {
+		tr = network.TCP
+	}
+	log := rep.logger()
+	ready := func() {
+		rep.mu.Lock()
+		rep.catchingUp = false
+		rep.mu.Unlock()
+	}
+	clock := network.ClockOf(tr)
+	giveUp := clock.Now().Add(rep.catchUpTimeout())
+	for ctx.Err() == nil {
+		rep.mu.Lock()
+		mine := rep.LastCommitted
+		rep.mu.Unlock()
+		best, seq, answered := "", mine, 0
+		for _, peer := range rep.Peers {
+			var p types.PingReply
+			if err := network.RequestTimeout(tr, peer, types.PingRequest{Ping: true}, &p, peerTimeout); err != nil {
+				continue
+			}
+			answered++
+			if p.Seq > seq {
+				best, seq = peer, p.Seq
+			}
+		}
+		if answered > 0 && best == "" {
+			ready()
+			log.Info("replica ready", "seq", mine)
+			return
+		}
+		if answered == 0 && clock.Now().After(giveUp) {
+			// o cluster todo pode estar reiniciando: esperar para sempre
+			// deixaria todas as réplicas fora do ar
+			ready()
+			log.Warn("no peer answered, replica ready at its local seq", "seq", mine, "peers", rep.Peers)
+			return
+		}
+		if best != "" {
+			err := rep.copyFrom(tr, best, mine)
+			if err == nil {
+				continue
+			}
+			log.Warn("catch-up failed", "peer", best, "err", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-clock.After(catchUpRetry):
+		}
+	}
+}
+
+// copyFrom instala o estado de peer, com os tombstones, e a sua janela de
+// decisões, se a réplica ainda estiver em from
+func (rep *Replica) copyFrom(tr network.Transport, peer string, from uint64) error {
+	all := make([]int, DigestBuckets)
+	for i := range all {
+		all[i] = i
+	}
+	var d types.DigestReply
+	if err := network.RequestTimeout(tr, peer, types.DigestRequest{Digest: true, Buckets: all, Decisions: true}, &d, peerTimeout); err != nil {
+		return err
+	}
+	rep.logger().Info("catching up", "peer", peer, "from_seq", from, "seq", d.Seq, "items", len(d.Items))
+	r := rep.Repair(types.RepairRequest{Repair: true, FromSeq: from, Seq: d.Seq, Chain: d.Chain, Buckets: all, Items: d.Items, Decisions: d.Decisions})
+	if !r.Repaired {
+		return errors.New(r.Error)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hrodric0/dur-impl/logging"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// probe retorna o código de GET path em h
+func probe(h http.Handler, path string) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec.Code
+}
+
+func TestReplicaCatchUp(t *testing.T) {
+	tr := network.NewMemTransport()
+	dir := t.TempDir()
+	r2, err := OpenReplica("r2", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2.Peers, r2.Transport, r2.Logger = []string{"r1"}, tr, logging.Discard
+	h := r2.Health()
+	if probe(h, "/healthz") != http.StatusServiceUnavailable {
+		t.Errorf("Expected not live before Start")
+	}
+	if err := r2.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// sem resposta dos pares, r2 atende mas não fica pronta
+	time.Sleep(50 * time.Millisecond)
+	if probe(h, "/healthz") != http.StatusOK || probe(h, "/readyz") != http.StatusServiceUnavailable || r2.Ping().Ready {
+		t.Fatalf("Expected r2 live but not ready while its peers are silent")
+	}
+	if dec := put(r2, "t0", "k", "early"); dec.Commit || dec.Reason != types.ReasonUnavailable {
+		t.Fatalf("Expected commits refused while catching up, got %+v", dec)
+	}
+
+	r1 := NewReplica("r1")
+	r1.Transport, r1.Logger = tr, logging.Discard
+	put(r1, "t1", "k", "1")
+	put(r1, "t2", "j", "2")
+	if err := r1.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer r1.Shutdown(context.Background())
+	deadline := time.Now().Add(5 * time.Second)
+	for probe(h, "/readyz") != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("r2 never became ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if d1, d2 := r1.Digest(types.DigestRequest{}), r2.Digest(types.DigestRequest{}); d1.Root != d2.Root || d1.Chain != d2.Chain || d2.Seq != 2 {
+		t.Fatalf("Expected r2 to copy r1 at seq 2, got %+v", d2)
+	}
+	if st := r2.Status(types.StatusRequest{Cid: "c", Tid: "t2"}); !st.Known || st.Decision.Seq != 2 {
+		t.Errorf("Expected r2 to copy r1's decisions, got %+v", st)
+	}
+	if dec := put(r2, "t3", "k", "3"); !dec.Commit || dec.Seq != 3 {
+		t.Errorf("Expected commits accepted once ready, got %+v", dec)
+	}
+	r2.Shutdown(context.Background())
+	if probe(h, "/healthz") != http.StatusServiceUnavailable {
+		t.Errorf("Expected not live after Shutdown")
+	}
+
+	// a cópia foi para o log: reabrir recupera o estado alcançado
+	r2, err = OpenReplica("r2", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Close()
+	if vv := r2.Db["j"]; r2.LastCommitted != 3 || string(vv.Value) != "2" {
+		t.Errorf("Expected the caught-up state after reopening, got seq %d j=%q", r2.LastCommitted, vv.Value)
+	}
+}
+
+// waitReady espera GET /readyz responder 200 em rep
+func waitReady(t *testing.T, rep *Replica) {
+	t.Helper()
+	h := rep.Health()
+	deadline := time.Now().Add(5 * time.Second)
+	for probe(h, "/readyz") != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s never became ready", rep.Addr)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReplicaReadyWhenNoPeerAnswers(t *testing.T) {
+	rep := NewReplica("r1")
+	rep.Peers, rep.Transport, rep.Logger = []string{"r2", "r3"}, network.NewMemTransport(), logging.Discard
+	rep.CatchUpTimeout = 50 * time.Millisecond
+	put(rep, "t1", "k", "1")
+	if err := rep.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer rep.Shutdown(context.Background())
+	waitReady(t, rep)
+	if dec := put(rep, "t2", "k", "2"); !dec.Commit || dec.Seq != 2 {
+		t.Errorf("Expected commits accepted at the local seq, got %+v", dec)
+	}
+}
+
+func TestReplicaCatchUpWholeClusterRestart(t *testing.T) {
+	tr := network.NewMemTransport()
+	names := []string{"r1", "r2", "r3"}
+	dirs := map[string]string{}
+	// antes da queda, r1 aplicou dois commits, r2 um e r3 nenhum
+	for i, name := range names {
+		dirs[name] = t.TempDir()
+		rep, err := OpenReplica(name, dirs[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < 2 {
+			put(rep, "t1", "k", "1")
+		}
+		if i < 1 {
+			put(rep, "t2", "j", "2")
+		}
+		rep.Close()
+	}
+
+	reps := make([]*Replica, len(names))
+	for i, name := range names {
+		rep, err := OpenReplica(name, dirs[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		rep.Transport, rep.Logger = tr, logging.Discard
+		for _, peer := range names {
+			if peer != name {
+				rep.Peers = append(rep.Peers, peer)
+			}
+		}
+		reps[i] = rep
+	}
+	for _, rep := range reps {
+		if err := rep.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		defer rep.Shutdown(context.Background())
+	}
+	want := reps[0].Digest(types.DigestRequest{})
+	for _, rep := range reps {
+		waitReady(t, rep)
+		if d := rep.Digest(types.DigestRequest{}); d.Seq != 2 || d.Root != want.Root || d.Chain != want.Chain {
+			t.Errorf("Expected %s at r1's state (seq 2), got %+v", rep.Addr, d)
+		}
+	}
+}
+
+func TestReplicaBehindRefusesAndCatchesUp(t *testing.T) {
+	tr := network.NewMemTransport()
+	r1, r2 := NewReplica("r1"), NewReplica("r2")
+	r2.Peers = []string{"r1"}
+	for _, rep := range []*Replica{r1, r2} {
+		rep.Transport, rep.Logger = tr, logging.Discard
+		if err := rep.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		defer rep.Shutdown(context.Background())
+	}
+	waitReady(t, r2)
+	// o sequencer pulou r2 no commit 1
+	put(r1, "t1", "k", "1")
+
+	dec := r2.Certify(types.CommitRequest{Cid: "c", Tid: "t2", After: 1, Ws: []types.WriteEntry{{Item: "k", Value: []byte("2")}}})
+	if dec.Commit || dec.Reason != types.ReasonUnavailable || dec.Detail != "replica behind: at seq 0, sequencer at 1" {
+		t.Fatalf("Expected r2 to refuse a commit after seq 1, got %+v", dec)
+	}
+	waitReady(t, r2)
+	if d1, d2 := r1.Digest(types.DigestRequest{}), r2.Digest(types.DigestRequest{}); d2.Seq != 1 || d1.Chain != d2.Chain {
+		t.Fatalf("Expected r2 to copy r1 at seq 1, got %+v", d2)
+	}
+	// o mesmo Tid, reenviado, agora é certificado
+	if dec := r2.Certify(types.CommitRequest{Cid: "c", Tid: "t2", After: 1, Ws: []types.WriteEntry{{Item: "k", Value: []byte("2")}}}); !dec.Commit || dec.Seq != 2 {
+		t.Errorf("Expected t2 committed once caught up, got %+v", dec)
+	}
+}
+
+func TestReplicaCatchUpCopiesTombstones(t *testing.T) {
+	tr := network.NewMemTransport()
+	r1 := NewReplica("r1")
+	r1.Transport, r1.Logger = tr, logging.Discard
+	put(r1, "t1", "k", "1")
+	r1.Certify(types.CommitRequest{Cid: "c", Tid: "t2", Ws: []types.WriteEntry{{Item: "k", Op: types.OpDelete}}})
+	if err := r1.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer r1.Shutdown(context.Background())
+	r2 := NewReplica("r2")
+	r2.Peers, r2.Transport, r2.Logger = []string{"r1"}, tr, logging.Discard
+	if err := r2.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Shutdown(context.Background())
+	waitReady(t, r2)
+
+	if d1, d2 := r1.Digest(types.DigestRequest{}), r2.Digest(types.DigestRequest{}); d1.Root != d2.Root || d2.Seq != 2 {
+		t.Fatalf("Expected r2 to copy r1 with the tombstone of k, got %+v", d2)
+	}
+	absent := types.CommitRequest{Cid: "c", Tid: "t3", Rs: []types.ReadEntry{{Item: "k", Absent: true}}, Ws: []types.WriteEntry{{Item: "k", Value: []byte("x")}}}
+	for _, rep := range []*Replica{r1, r2} {
+		if dec := rep.Certify(absent); dec.Commit || dec.Reason != types.ReasonStaleRead {
+			t.Errorf("Expected %s to abort the stale absent read, got %+v", rep.Addr, dec)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import "sort"
+
+// keyIndex mantém as chaves do Db em ordem, para varreduras por intervalo
+type keyIndex struct {
+	keys []string
+}
+
+func (ix *keyIndex) insert(k string) {
+	i := sort.SearchStrings(ix.keys, k)
+	if i < len(ix.keys) && ix.keys[i] == k {
+		return
+	}
+	ix.keys = append(ix.keys, "")
+	copy(ix.keys[i+1:], ix.keys[i:])
+	ix.keys[i] = k
+}
+
+func (ix *keyIndex) remove(k string) {
+	i := sort.SearchStrings(ix.keys, k)
+	if i < len(ix.keys) && ix.keys[i] == k {
+		ix.keys = append(ix.keys[:i], ix.keys[i+1:]...)
+	}
+}
+
+// between retorna as chaves em [start, end); end vazio não limita
+func (ix *keyIndex) between(start, end string) []string {
+	lo := sort.SearchStrings(ix.keys, start)
+	hi := len(ix.keys)
+	if end != "" {
+		hi = sort.SearchStrings(ix.keys, end)
+	}
+	if hi <= lo {
+		return nil
+	}
+	return ix.keys[lo:hi]
+}
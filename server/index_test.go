@@ -0,0 +1,32 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyIndexBetween(t *testing.T) {
+	var ix keyIndex
+	for _, k := range []string{"b", "d", "a", "c", "b"} {
+		ix.insert(k)
+	}
+	if !reflect.DeepEqual(ix.keys, []string{"a", "b", "c", "d"}) {
+		t.Fatalf("Expected sorted unique keys, got %v", ix.keys)
+	}
+	ix.remove("c")
+	cases := []struct {
+		start, end string
+		want       []string
+	}{
+		{"", "", []string{"a", "b", "d"}},
+		{"b", "d", []string{"b"}},
+		{"b", "", []string{"b", "d"}},
+		{"c", "c", nil},
+		{"e", "", nil},
+	}
+	for _, c := range cases {
+		if got := ix.between(c.start, c.end); len(got) != len(c.want) || (len(got) > 0 && !reflect.DeepEqual(got, c.want)) {
+			t.Errorf("between(%q, %q) = %v, want %v", c.start, c.end, got, c.want)
+		}
+	}
+}
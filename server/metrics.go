@@ -0,0 +1,77 @@
+package server
+
+import (
+	"time"
+
+	"github.com/hrodric0/dur-impl/metrics"
+)
+
+// replicaMetrics são as métricas expostas por uma réplica
+type replicaMetrics struct {
+	reg     *metrics.Registry
+	commits *metrics.Counter
+	aborts  *metrics.Counter
+	certify *metrics.Histogram
+	reads   *metrics.Histogram
+}
+
+func newReplicaMetrics(rep *Replica) *replicaMetrics {
+	reg := metrics.NewRegistry()
+	m := &replicaMetrics{
+		reg:     reg,
+		commits: reg.Counter("dur_replica_commits_total", "Commit requests certified and applied."),
+		aborts:  reg.Counter("dur_replica_aborts_total", "Commit requests aborted, by reason.", "reason"),
+		certify: reg.Histogram("dur_replica_certify_seconds", "Time to certify and apply a commit request, including the wait for the store lock.", metrics.DefBuckets),
+		reads:   reg.Histogram("dur_replica_read_seconds", "Time to answer a read, by request kind (read, multi, scan).", metrics.DefBuckets, "kind"),
+	}
+	reg.GaugeFunc("dur_replica_last_committed", "Sequence number of the last commit applied.", func() float64 {
+		rep.mu.Lock()
+		defer rep.mu.Unlock()
+		return float64(rep.LastCommitted)
+	})
+	reg.GaugeFunc("dur_replica_keys", "Live keys in the store.", func() float64 {
+		keys, _ := rep.storeSize()
+		return float64(keys)
+	})
+	reg.GaugeFunc("dur_replica_value_bytes", "Bytes of live values in the store.", func() float64 {
+		_, bytes := rep.storeSize()
+		return float64(bytes)
+	})
+	reg.GaugeFunc("dur_replica_tombstones", "Tombstones not yet collected.", func() float64 {
+		rep.mu.Lock()
+		defer rep.mu.Unlock()
+		return float64(len(rep.tombstones))
+	})
+	return m
+}
+
+// decided conta a decisão de um commit certificado a partir de start
+func (m *replicaMetrics) decided(reason string, start time.Time) {
+	m.certify.Observe(time.Since(start).Seconds())
+	if reason == "" {
+		m.commits.Inc()
+	} else {
+		m.aborts.Inc(reason)
+	}
+}
+
+// read registra a latência de uma leitura do tipo kind iniciada em start
+func (m *replicaMetrics) read(kind string, start time.Time) {
+	m.reads.Observe(time.Since(start).Seconds(), kind)
+}
+
+// storeSize conta as chaves vivas e os bytes dos seus valores
+func (rep *Replica) storeSize() (keys, bytes int) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	for _, vv := range rep.Db {
+		if !vv.Deleted {
+			keys++
+			bytes += len(vv.Value)
+		}
+	}
+	return keys, bytes
+}
+
+// Metrics retorna o registro de métricas da réplica, para expor em /metrics
+func (rep *Replica) Metrics() *metrics.Registry { return rep.metrics.reg }
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/types"
+)
+
+func TestReplicaMetrics(t *testing.T) {
+	rep := NewReplica("r")
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t1", Ws: []types.WriteEntry{{Item: "y", Value: []byte("hello")}}})
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t1", Ws: []types.WriteEntry{{Item: "y", Value: []byte("hello")}}})
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t2", Rs: []types.ReadEntry{{Item: "x", Version: 7}}})
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t3", Ws: []types.WriteEntry{{Item: "x", Op: types.OpDelete}}})
+	rep.Read(types.ReadRequest{Item: "y"})
+	rep.Scan(types.ScanRequest{Start: "a"})
+
+	var b strings.Builder
+	rep.Metrics().WriteText(&b)
+	for _, want := range []string{
+		"dur_replica_commits_total 2\n",
+		`dur_replica_aborts_total{reason="stale-read"} 1` + "\n",
+		"dur_replica_certify_seconds_count 3\n",
+		`dur_replica_read_seconds_count{kind="read"} 1` + "\n",
+		`dur_replica_read_seconds_count{kind="scan"} 1` + "\n",
+		"dur_replica_last_committed 2\n",
+		"dur_replica_keys 1\n",
+		"dur_replica_value_bytes 5\n",
+		"dur_replica_tombstones 1\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, b.String())
+		}
+	}
+}
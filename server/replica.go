@@ -1,66 +1,495 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"net"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/hrodric0/dur-impl/admin"
+	"github.com/hrodric0/dur-impl/logging"
 	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/trace"
 	"github.com/hrodric0/dur-impl/types"
 )
 
-// VersionedValue armazena valor e versão de cada chave
+// VersionedValue armazena valor e versão de cada chave.
+// Deleted marca um tombstone: a chave foi removida na versão Version.
 type VersionedValue struct {
 	Value   []byte
 	Version uint64
+	Deleted bool
 }
 
-// Replica mantém estado do KV e contador de versões
+// DefaultTombstoneRetention é por quantas sequências um tombstone é mantido
+const DefaultTombstoneRetention = 1024
+
+// DefaultDecisionWindow é quantas decisões a réplica lembra para deduplicar commits
+const DefaultDecisionWindow = 4096
+
+// DefaultKeyHistory é quantas versões de cada chave a réplica guarda para
+// a API de administração
+const DefaultKeyHistory = 16
+
+// Replica mantém estado do KV e contador de versões.
+// mu serializa leituras e certificações vindas de conexões concorrentes.
 type Replica struct {
 	Addr          string
 	Db            map[string]VersionedValue
 	LastCommitted uint64
+	// TombstoneRetention: tombstones mais antigos que isso (em sequências)
+	// são coletados após cada commit; zero desliga a coleta automática
+	TombstoneRetention uint64
+	// DecisionWindow é quantas decisões recentes são lembradas por (Cid, Tid):
+	// um CommitRequest repetido recebe a decisão original sem ser reaplicado
+	DecisionWindow int
+	// KeyHistory é quantas versões de cada chave são guardadas para
+	// GET /admin/keys; zero não guarda histórico
+	KeyHistory int
+	// Peers são as outras réplicas. Com Peers, Start só deixa a réplica
+	// pronta (GET /readyz, PingReply.Ready) depois de alcançá-las, copiando
+	// o estado da mais adiantada; até lá os commits são recusados. O mesmo
+	// alcance recomeça quando um commit chega com After adiante da réplica.
+	Peers []string
+	// CatchUpTimeout é quanto o alcance espera por Peers que não respondem:
+	// se nenhum responder nesse prazo, a réplica fica pronta na sequência
+	// local. Zero usa DefaultCatchUpTimeout.
+	CatchUpTimeout time.Duration
+	// Transport usado por Start; nil usa TCP
+	Transport network.Transport
+	// Tracer, se não for nil, registra spans de certificação e leitura
+	// como filhos do TraceContext dos pedidos
+	Tracer *trace.Tracer
+	// Logger recebe os eventos da réplica; nil usa slog.Default(). Leituras
+	// e commits são registrados em Debug, sem os valores.
+	Logger *slog.Logger
+
+	server     network.Server
+	metrics    *replicaMetrics
+	mu         sync.Mutex
+	index      keyIndex    // chaves do Db em ordem
+	tombstones []tombstone // em ordem de versão
+	purged     uint64      // maior versão de tombstone já coletado
+	decisions  map[string]types.CommitDecision
+	decided    []string     // chaves de decisions, da mais antiga à mais nova
+	chain      [32]byte     // hash encadeado dos commits aplicados
+	chains     []chainPoint // chain depois de cada commit recente
+	history    map[string][]admin.Version
+	truncated  map[string]bool // chaves com versões descartadas do histórico
+	aborts     *admin.Aborts
+	wal        logFile // log de commits, se aberta com OpenReplica
+	closed     bool    // log fechado: commits não são mais aceitos
+	serving    bool    // entre Serve e Shutdown
+	catchingUp bool    // alcançando os Peers
+	catchCtx   context.Context
+	stopCatch  context.CancelFunc
+}
+
+type tombstone struct {
+	item    string
+	version uint64
+}
+
+// NewReplica cria uma réplica com o estado inicial padrão
+func NewReplica(addr string) *Replica {
+	rep := &Replica{Addr: addr, Db: map[string]VersionedValue{"x": {Value: []byte("init"), Version: 0}}, LastCommitted: 0, TombstoneRetention: DefaultTombstoneRetention, DecisionWindow: DefaultDecisionWindow, KeyHistory: DefaultKeyHistory}
+	rep.index.insert("x")
+	rep.server.Handler = rep.handle
+	rep.metrics = newReplicaMetrics(rep)
+	rep.aborts = admin.NewAborts(admin.DefaultAborts)
+	rep.pushChain()
+	return rep
 }
 
 // StartReplica inicia listener unificado para Read/Commit
 func StartReplica(addr string) error {
-	log.Printf("[Replica %s] Escutando...", addr)
-	rep := &Replica{Addr: addr, Db: map[string]VersionedValue{"x": {Value: []byte("init"), Version: 0}}, LastCommitted: 0}
-	handler := func(raw []byte, c net.Conn) {
-		var probe map[string]json.RawMessage
-		json.Unmarshal(raw, &probe)
-		if _, isCommit := probe["rs"]; isCommit {
-			var req types.CommitRequest
-			json.Unmarshal(raw, &req)
-			log.Printf("[Replica %s] Received CommitRequest cid=%s tid=%s", addr, req.Cid, req.Tid)
-			// certificação
-			abort := false
-			for _, re := range req.Rs {
-				if vv, ok := rep.Db[re.Item]; ok && vv.Version > re.Version {
-					abort = true
-					break
-				}
-			}
-			if abort {
-				log.Printf("[Replica %s] DECISION abort (rs stale)", addr)
-			} else {
-				rep.LastCommitted++
-				for _, we := range req.Ws {
-					rep.Db[we.Item] = VersionedValue{Value: we.Value, Version: rep.LastCommitted}
-					log.Printf("[Replica %s] Applied WS: %s=v%d", addr, we.Item, rep.LastCommitted)
-				}
-				log.Printf("[Replica %s] DECISION commit", addr)
-			}
-			decision := types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Commit: !abort}
-			json.NewEncoder(c).Encode(decision)
-		} else {
-			var req types.ReadRequest
-			json.Unmarshal(raw, &req)
-			vv := rep.Db[req.Item]
-			log.Printf("[Replica %s] Received ReadRequest cid=%s item=%s -> value=%s v%d", addr, req.Cid, req.Item, string(vv.Value), vv.Version)
-			repMsg := types.ReadReply{Cid: req.Cid, Item: req.Item, Value: vv.Value, Version: vv.Version}
-			json.NewEncoder(c).Encode(repMsg)
+	return StartReplicaWith(network.TCP, addr)
+}
+
+// StartReplicaWith é StartReplica sobre o transporte tr
+func StartReplicaWith(tr network.Transport, addr string) error {
+	ln, err := tr.Listen(addr)
+	if err != nil {
+		return err
+	}
+	return NewReplica(addr).Serve(ln)
+}
+
+// Serve atende ReadRequests e CommitRequests recebidos em ln até Shutdown
+func (rep *Replica) Serve(ln net.Listener) error {
+	rep.mu.Lock()
+	rep.serving = true
+	attrs := []any{"seq", rep.LastCommitted}
+	if rep.wal != nil {
+		attrs = append(attrs, "wal", rep.wal.Name())
+	}
+	rep.mu.Unlock()
+	rep.logger().Info("replica listening", attrs...)
+	return rep.server.Serve(ln)
+}
+
+// logger é o Logger da réplica, identificado pelo endereço
+func (rep *Replica) logger() *slog.Logger {
+	return logging.Or(rep.Logger).With("component", "replica", "replica", rep.Addr)
+}
+
+// Start escuta rep.Addr e atende em segundo plano; com Peers, alcança-os
+// antes de ficar pronta. Quando ctx acaba, a réplica é encerrada como em
+// Shutdown, sem prazo.
+func (rep *Replica) Start(ctx context.Context) error {
+	tr := rep.Transport
+	if tr == nil {
+		tr = network.TCP
+	}
+	ln, err := tr.Listen(rep.Addr)
+	if err != nil {
+		return err
+	}
+	catchCtx, cancel := context.WithCancel(ctx)
+	rep.mu.Lock()
+	rep.catchCtx, rep.stopCatch = catchCtx, cancel
+	rep.startCatchUp()
+	rep.mu.Unlock()
+	go rep.Serve(ln)
+	context.AfterFunc(ctx, func() { rep.Shutdown(context.Background()) })
+	return nil
+}
+
+// Shutdown para de aceitar conexões, espera as leituras e certificações em
+// andamento e fecha o log. Se ctx acabar antes, fecha as conexões restantes,
+// espera só a certificação corrente e retorna o erro de ctx.
+func (rep *Replica) Shutdown(ctx context.Context) error {
+	rep.mu.Lock()
+	rep.serving = false
+	if rep.stopCatch != nil {
+		rep.stopCatch()
+	}
+	rep.mu.Unlock()
+	err := rep.server.Shutdown(ctx)
+	if cerr := rep.Close(); err == nil {
+		err = cerr
+	}
+	rep.logger().Info("replica stopped", "seq", rep.LastCommitted)
+	return err
+}
+
+func (rep *Replica) handle(raw []byte, c net.Conn) {
+	var probe map[string]json.RawMessage
+	json.Unmarshal(raw, &probe)
+	if _, isPing := probe["ping"]; isPing {
+		json.NewEncoder(c).Encode(rep.Ping())
+	} else if _, isDigest := probe["digest"]; isDigest {
+		var req types.DigestRequest
+		json.Unmarshal(raw, &req)
+		json.NewEncoder(c).Encode(rep.Digest(req))
+	} else if _, isRepair := probe["repair"]; isRepair {
+		var req types.RepairRequest
+		json.Unmarshal(raw, &req)
+		json.NewEncoder(c).Encode(rep.Repair(req))
+	} else if _, isCommit := probe["rs"]; isCommit {
+		var req types.CommitRequest
+		json.Unmarshal(raw, &req)
+		json.NewEncoder(c).Encode(rep.Certify(req))
+	} else if _, isStatus := probe["tid"]; isStatus {
+		var req types.StatusRequest
+		json.Unmarshal(raw, &req)
+		json.NewEncoder(c).Encode(rep.Status(req))
+	} else if _, isMulti := probe["items"]; isMulti {
+		var req types.MultiReadRequest
+		json.Unmarshal(raw, &req)
+		json.NewEncoder(c).Encode(rep.ReadMany(req))
+	} else if _, isScan := probe["start"]; isScan {
+		var req types.ScanRequest
+		json.Unmarshal(raw, &req)
+		json.NewEncoder(c).Encode(rep.Scan(req))
+	} else {
+		var req types.ReadRequest
+		json.Unmarshal(raw, &req)
+		json.NewEncoder(c).Encode(rep.Read(req))
+	}
+}
+
+// Certify certifica req contra o estado atual e, se válido, aplica o ws
+// Um pedido já decidido (mesmo Cid e Tid, dentro da DecisionWindow) recebe
+// a decisão original, sem ser certificado nem aplicado de novo. Só commits e
+// aborts de certificação são lembrados, não os por indisponibilidade.
+func (rep *Replica) Certify(req types.CommitRequest) types.CommitDecision {
+	start := time.Now()
+	span := rep.Tracer.Start(req.Trace, "replica.certify")
+	defer span.End()
+	span.SetAttr("replica", rep.Addr)
+	wait := span.Child("replica.lock")
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	wait.End()
+	log := rep.logger().With("cid", req.Cid, "tid", req.Tid)
+	log.Debug("commit request", "rs", len(req.Rs), "ws", len(req.Ws))
+	key := req.Cid + "/" + req.Tid
+	if dec, ok := rep.decisions[key]; ok {
+		log.Debug("duplicate commit request", "commit", dec.Commit, "seq", dec.Seq)
+		span.SetAttr("duplicate", "true")
+		return dec
+	}
+	if rep.catchingUp {
+		log.Debug("abort", "reason", types.ReasonUnavailable, "detail", errCatchingUp)
+		return types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Reason: types.ReasonUnavailable, Detail: errCatchingUp.Error()}
+	}
+	if req.After > rep.LastCommitted {
+		// o sequencer pulou esta réplica em commits que ela não tem
+		detail := fmt.Sprintf("replica behind: at seq %d, sequencer at %d", rep.LastCommitted, req.After)
+		log.Warn("commit refused", "detail", detail)
+		rep.startCatchUp()
+		return types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Reason: types.ReasonUnavailable, Detail: detail}
+	}
+	dec := rep.decide(req, span)
+	if dec.Commit {
+		log.Debug("commit", "seq", dec.Seq)
+	} else {
+		log.Debug("abort", "reason", dec.Reason, "item", dec.Item, "detail", dec.Detail)
+		rep.aborts.Add(admin.Abort{At: time.Now(), Cid: req.Cid, Tid: req.Tid, Reason: dec.Reason, Item: dec.Item, Detail: dec.Detail})
+	}
+	span.SetAttr("commit", strconv.FormatBool(dec.Commit))
+	if dec.Reason != "" {
+		span.SetAttr("reason", dec.Reason)
+	}
+	rep.metrics.decided(dec.Reason, start)
+	// um abort por indisponibilidade não é uma decisão: o mesmo Tid pode ser
+	// reenviado e certificado quando a réplica voltar a gravar
+	if req.Tid != "" && dec.Reason != types.ReasonUnavailable {
+		rep.remember(key, dec)
+	}
+	return dec
+}
+
+// decide certifica req e, se válido, aplica o ws; chamado com mu travado.
+// A gravação no log vira um span filho de span, se houver.
+func (rep *Replica) decide(req types.CommitRequest, span *trace.Span) types.CommitDecision {
+	if dec := rep.certify(req); dec.Reason != "" {
+		return dec
+	}
+	// calcula os novos valores antes de aplicar: um operando inválido aborta
+	// a transação inteira, e da mesma forma em todas as réplicas
+	next := make([]VersionedValue, len(req.Ws))
+	for i, we := range req.Ws {
+		cur, ok := rep.Db[we.Item]
+		val, found, err := types.Apply(cur.Value, ok && !cur.Deleted, we)
+		if err != nil {
+			return types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Reason: types.ReasonBadOperand, Item: we.Item, Detail: err.Error()}
+		}
+		next[i] = VersionedValue{Value: val, Deleted: !found}
+	}
+	var wal *trace.Span
+	if rep.wal != nil {
+		wal = span.Child("replica.wal")
+	}
+	err := rep.persist(req)
+	wal.Fail(err)
+	wal.End()
+	if err != nil {
+		rep.logger().Error("wal write failed", "cid", req.Cid, "tid", req.Tid, "err", err)
+		return types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Reason: types.ReasonUnavailable, Detail: "wal: " + err.Error()}
+	}
+	rep.LastCommitted++
+	log := rep.logger()
+	for i, we := range req.Ws {
+		vv := next[i]
+		vv.Version = rep.LastCommitted
+		rep.put(we.Item, vv)
+		rep.record(we.Item, admin.Version{Version: vv.Version, Cid: req.Cid, Tid: req.Tid, Value: vv.Value, Deleted: vv.Deleted})
+		if vv.Deleted {
+			rep.tombstones = append(rep.tombstones, tombstone{item: we.Item, version: rep.LastCommitted})
+		}
+		log.Debug("applied write", "item", we.Item, "op", we.Op, "value", logging.Redacted(vv.Value), "deleted", vv.Deleted, "seq", rep.LastCommitted)
+	}
+	rep.chainCommit(req, next)
+	if rep.TombstoneRetention > 0 && rep.LastCommitted > rep.TombstoneRetention {
+		rep.collectTombstones(rep.LastCommitted - rep.TombstoneRetention)
+	}
+	return types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Commit: true, Seq: rep.LastCommitted}
+}
+
+// remember guarda dec para deduplicar, esquecendo as mais antigas além da janela
+func (rep *Replica) remember(key string, dec types.CommitDecision) {
+	if rep.DecisionWindow <= 0 {
+		return
+	}
+	if rep.decisions == nil {
+		rep.decisions = map[string]types.CommitDecision{}
+	}
+	rep.decisions[key] = dec
+	rep.decided = append(rep.decided, key)
+	for len(rep.decided) > rep.DecisionWindow {
+		delete(rep.decisions, rep.decided[0])
+		rep.decided = rep.decided[1:]
+	}
+}
+
+// Status informa a decisão lembrada para (req.Cid, req.Tid)
+func (rep *Replica) Status(req types.StatusRequest) types.StatusReply {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	dec, ok := rep.decisions[req.Cid+"/"+req.Tid]
+	rep.logger().Debug("status request", "cid", req.Cid, "tid", req.Tid, "known", ok, "commit", dec.Commit)
+	return types.StatusReply{Cid: req.Cid, Tid: req.Tid, Known: ok, Decision: dec, Seq: rep.LastCommitted}
+}
+
+// certify verifica rs, intervalos e preconditions de req contra o estado
+// atual; retorna uma decisão de abort com Reason, ou Reason vazio se req passa
+func (rep *Replica) certify(req types.CommitRequest) types.CommitDecision {
+	abort := func(reason, item, format string, args ...any) types.CommitDecision {
+		return types.CommitDecision{Cid: req.Cid, Tid: req.Tid, Reason: reason, Item: item, Detail: fmt.Sprintf(format, args...)}
+	}
+	for _, re := range req.Rs {
+		vv, ok := rep.Db[re.Item]
+		// lida como ausente, a chave inserida depois tem versão nova (fantasma)
+		if ok && vv.Version != re.Version {
+			return abort(types.ReasonStaleRead, re.Item, "read v%d, now v%d", re.Version, vv.Version)
+		}
+		// sem entrada e a leitura viu um valor: foi removido e o tombstone já coletado
+		if !ok && !re.Absent {
+			return abort(types.ReasonStaleRead, re.Item, "read v%d, now removed", re.Version)
+		}
+	}
+	for _, rr := range req.Ranges {
+		if item, ok := rep.rangeChange(rr); ok {
+			return abort(types.ReasonPhantom, item, "range [%q, %q) changed after seq %d", rr.Start, rr.End, rr.Seq)
+		}
+	}
+	for _, p := range req.Pre {
+		vv, ok := rep.Db[p.Item]
+		live := ok && !vv.Deleted
+		switch {
+		case p.Absent && live:
+			return abort(types.ReasonPrecondition, p.Item, "expected absent, found v%d", vv.Version)
+		case !p.Absent && !live:
+			return abort(types.ReasonPrecondition, p.Item, "expected v%d, found absent", p.Version)
+		case !p.Absent && vv.Version != p.Version:
+			return abort(types.ReasonPrecondition, p.Item, "expected v%d, found v%d", p.Version, vv.Version)
+		}
+	}
+	return types.CommitDecision{}
+}
+
+// Read retorna o valor atual e a versão do item pedido
+func (rep *Replica) Read(req types.ReadRequest) types.ReadReply {
+	defer rep.metrics.read("read", time.Now())
+	defer rep.traceRead(req.Trace, "replica.read").End()
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	if rep.LastCommitted < req.MinSeq {
+		rep.logger().Debug("read behind session", "cid", req.Cid, "seq", rep.LastCommitted, "min_seq", req.MinSeq)
+		return types.ReadReply{Cid: req.Cid, Item: req.Item, Seq: rep.LastCommitted, Behind: true}
+	}
+	rep.logger().Debug("read request", "cid", req.Cid, "item", req.Item)
+	return rep.read(req.Cid, req.Item)
+}
+
+// ReadMany responde todas as chaves de req sob o mesmo lock, logo no mesmo snapshot
+func (rep *Replica) ReadMany(req types.MultiReadRequest) types.MultiReadReply {
+	defer rep.metrics.read("multi", time.Now())
+	defer rep.traceRead(req.Trace, "replica.read_many").End()
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	if rep.LastCommitted < req.MinSeq {
+		rep.logger().Debug("read behind session", "cid", req.Cid, "seq", rep.LastCommitted, "min_seq", req.MinSeq)
+		return types.MultiReadReply{Cid: req.Cid, Seq: rep.LastCommitted, Behind: true}
+	}
+	rep.logger().Debug("multi-read request", "cid", req.Cid, "items", len(req.Items))
+	out := types.MultiReadReply{Cid: req.Cid, Seq: rep.LastCommitted, Replies: make([]types.ReadReply, len(req.Items))}
+	for i, item := range req.Items {
+		out.Replies[i] = rep.read(req.Cid, item)
+	}
+	return out
+}
+
+// traceRead abre o span de uma leitura, filho de parent
+func (rep *Replica) traceRead(parent *types.TraceContext, name string) *trace.Span {
+	span := rep.Tracer.Start(parent, name)
+	span.SetAttr("replica", rep.Addr)
+	return span
+}
+
+// read monta a resposta de uma chave; chamado com mu travado
+func (rep *Replica) read(cid, item string) types.ReadReply {
+	vv, ok := rep.Db[item]
+	return types.ReadReply{Cid: cid, Item: item, Value: vv.Value, Version: vv.Version, Seq: rep.LastCommitted, Found: ok && !vv.Deleted, Deleted: vv.Deleted}
+}
+
+// Scan retorna, em ordem, as chaves vivas em [req.Start, req.End)
+func (rep *Replica) Scan(req types.ScanRequest) types.ScanReply {
+	defer rep.metrics.read("scan", time.Now())
+	defer rep.traceRead(req.Trace, "replica.scan").End()
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	if rep.LastCommitted < req.MinSeq {
+		rep.logger().Debug("scan behind session", "cid", req.Cid, "seq", rep.LastCommitted, "min_seq", req.MinSeq)
+		return types.ScanReply{Cid: req.Cid, Seq: rep.LastCommitted, Behind: true}
+	}
+	out := types.ScanReply{Cid: req.Cid, Seq: rep.LastCommitted}
+	for _, k := range rep.index.between(req.Start, req.End) {
+		vv := rep.Db[k]
+		if vv.Deleted {
+			continue
+		}
+		if req.Limit > 0 && len(out.Items) == req.Limit {
+			out.More = true
+			break
+		}
+		out.Items = append(out.Items, types.ScanItem{Item: k, Value: vv.Value, Version: vv.Version})
+	}
+	rep.logger().Debug("scan request", "cid", req.Cid, "start", req.Start, "end", req.End, "items", len(out.Items))
+	return out
+}
+
+// put grava vv em item mantendo o índice ordenado
+func (rep *Replica) put(item string, vv VersionedValue) {
+	if _, ok := rep.Db[item]; !ok {
+		rep.index.insert(item)
+	}
+	rep.Db[item] = vv
+}
+
+// rangeChange retorna uma chave de rr inserida, alterada ou removida depois
+// do snapshot da varredura. Se um tombstone posterior ao snapshot pode já
+// ter sido coletado, a remoção não é mais visível e a resposta é
+// conservadora (com chave vazia).
+func (rep *Replica) rangeChange(rr types.RangeRead) (string, bool) {
+	if rr.Seq < rep.purged {
+		return "", true
+	}
+	for _, k := range rep.index.between(rr.Start, rr.End) {
+		if rep.Db[k].Version > rr.Seq {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// CollectTombstones remove os tombstones com versão até upTo e retorna quantos
+func (rep *Replica) CollectTombstones(upTo uint64) int {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	return rep.collectTombstones(upTo)
+}
+
+func (rep *Replica) collectTombstones(upTo uint64) int {
+	n := 0
+	for len(rep.tombstones) > 0 && rep.tombstones[0].version <= upTo {
+		ts := rep.tombstones[0]
+		rep.tombstones = rep.tombstones[1:]
+		// a chave pode ter sido reescrita depois da remoção
+		if vv, ok := rep.Db[ts.item]; ok && vv.Deleted && vv.Version == ts.version {
+			delete(rep.Db, ts.item)
+			delete(rep.history, ts.item)
+			delete(rep.truncated, ts.item)
+			rep.index.remove(ts.item)
+			rep.purged = max(rep.purged, ts.version)
+			n++
 		}
 	}
-	return network.Listen(rep.Addr, handler)
+	return n
 }
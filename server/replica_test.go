@@ -1,18 +1,25 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/hrodric0/dur-impl/logging"
+	"github.com/hrodric0/dur-impl/network"
 	"github.com/hrodric0/dur-impl/types"
 )
 
 func TestReplicaCertification(t *testing.T) {
 	// start replica
 	ln, _ := net.Listen("tcp", "localhost:0")
-	go StartReplica(ln.Addr().String())
+	go NewReplica(ln.Addr().String()).Serve(ln)
 	time.Sleep(10 * time.Millisecond)
 
 	// send commit with stale rs
@@ -26,3 +33,302 @@ func TestReplicaCertification(t *testing.T) {
 		t.Errorf("Expected abort for stale read, got commit")
 	}
 }
+
+func TestReplicaRejectsUnknownReadVersion(t *testing.T) {
+	tr := network.NewMemTransport()
+	ln, _ := tr.Listen("r")
+	go NewReplica("r").Serve(ln)
+	commit := func(tid string, rs []types.ReadEntry) bool {
+		var dec types.CommitDecision
+		req := types.CommitRequest{Cid: "c", Tid: tid, Rs: rs, Ws: []types.WriteEntry{{Item: "x", Value: []byte(tid)}}}
+		if err := network.RequestWith(tr, "r", req, &dec); err != nil {
+			t.Fatal(err)
+		}
+		return dec.Commit
+	}
+	if !commit("t1", []types.ReadEntry{{Item: "x", Version: 0}}) {
+		t.Fatal("Expected commit of a read of the current version")
+	}
+	// x está em v1: v2 nunca existiu, v0 já foi sobrescrita
+	if commit("t2", []types.ReadEntry{{Item: "x", Version: 2}}) {
+		t.Error("Expected abort for a read of a version the replica never produced")
+	}
+	if commit("t3", []types.ReadEntry{{Item: "x", Version: 0}}) {
+		t.Error("Expected abort for a stale read")
+	}
+	if !commit("t4", []types.ReadEntry{{Item: "x", Version: 1}}) {
+		t.Error("Expected commit of a read of v1")
+	}
+}
+
+func TestReplicaDeleteTombstone(t *testing.T) {
+	rep := NewReplica("r")
+	rep.TombstoneRetention = 0
+	del := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t1", Rs: []types.ReadEntry{{Item: "x", Value: []byte("init")}}, Ws: []types.WriteEntry{{Item: "x", Op: types.OpDelete}}})
+	if !del.Commit {
+		t.Fatalf("Expected delete to commit")
+	}
+	rr := rep.Read(types.ReadRequest{Cid: "c", Item: "x"})
+	if !rr.Deleted || rr.Value != nil || rr.Version != del.Seq {
+		t.Fatalf("Expected tombstone at v%d, got %+v", del.Seq, rr)
+	}
+	// quem leu o valor antigo aborta; quem leu o tombstone certifica
+	if dec := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t2", Rs: []types.ReadEntry{{Item: "x", Value: []byte("init")}}}); dec.Commit {
+		t.Errorf("Expected abort for read of deleted value")
+	}
+	if dec := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t3", Rs: []types.ReadEntry{{Item: "x", Version: del.Seq, Absent: true}}}); !dec.Commit {
+		t.Errorf("Expected commit for read of tombstone")
+	}
+
+	// após a coleta o tombstone some, mas uma leitura antiga continua abortando
+	if n := rep.CollectTombstones(del.Seq); n != 1 {
+		t.Fatalf("Expected 1 tombstone collected, got %d", n)
+	}
+	if _, ok := rep.Db["x"]; ok {
+		t.Fatalf("Expected x removed from Db")
+	}
+	if dec := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t4", Rs: []types.ReadEntry{{Item: "x", Value: []byte("init")}}}); dec.Commit {
+		t.Errorf("Expected abort for read of collected key")
+	}
+}
+
+func TestReplicaTombstoneRetention(t *testing.T) {
+	rep := NewReplica("r")
+	rep.TombstoneRetention = 2
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t1", Ws: []types.WriteEntry{{Item: "x", Op: types.OpDelete}}})
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t2", Ws: []types.WriteEntry{{Item: "y", Value: []byte("1")}}})
+	if _, ok := rep.Db["x"]; !ok {
+		t.Fatalf("Expected tombstone kept within retention")
+	}
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t3", Ws: []types.WriteEntry{{Item: "y", Value: []byte("2")}}})
+	if _, ok := rep.Db["x"]; ok {
+		t.Fatalf("Expected tombstone collected after retention")
+	}
+}
+
+func TestReplicaMissingKeyPhantom(t *testing.T) {
+	rep := NewReplica("r")
+	rr := rep.Read(types.ReadRequest{Cid: "c", Item: "k"})
+	if rr.Found || rr.Deleted || rr.Value != nil {
+		t.Fatalf("Expected missing key, got %+v", rr)
+	}
+	if rr := rep.Read(types.ReadRequest{Cid: "c", Item: "x"}); !rr.Found {
+		t.Fatalf("Expected x found, got %+v", rr)
+	}
+	absent := types.ReadEntry{Item: "k", Version: rr.Version, Absent: true}
+	// uma leitura "encontrada" de chave inexistente não certifica
+	if dec := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t0", Rs: []types.ReadEntry{{Item: "k"}}}); dec.Commit {
+		t.Errorf("Expected abort for found read of missing key")
+	}
+	if dec := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t1", Rs: []types.ReadEntry{absent}}); !dec.Commit {
+		t.Fatalf("Expected commit while key still absent")
+	}
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t2", Ws: []types.WriteEntry{{Item: "k", Value: []byte("new")}}})
+	if dec := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t3", Rs: []types.ReadEntry{absent}}); dec.Commit {
+		t.Errorf("Expected abort after concurrent insert of absent key")
+	}
+}
+
+func TestReplicaScan(t *testing.T) {
+	rep := NewReplica("r")
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t1", Ws: []types.WriteEntry{
+		{Item: "u/1", Value: []byte("a")}, {Item: "u/3", Value: []byte("c")}, {Item: "u/2", Value: []byte("b")}, {Item: "v/1", Value: []byte("z")},
+	}})
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t2", Ws: []types.WriteEntry{{Item: "u/2", Op: types.OpDelete}}})
+
+	sr := rep.Scan(types.ScanRequest{Cid: "c", Start: "u/", End: "u0"})
+	if len(sr.Items) != 2 || sr.Items[0].Item != "u/1" || sr.Items[1].Item != "u/3" || sr.More || sr.Seq != 2 {
+		t.Fatalf("Expected u/1, u/3 at seq 2 skipping the tombstone, got %+v", sr)
+	}
+	if sr := rep.Scan(types.ScanRequest{Cid: "c", Start: "u/", Limit: 1}); len(sr.Items) != 1 || !sr.More {
+		t.Fatalf("Expected one item and More, got %+v", sr)
+	}
+	if sr := rep.Scan(types.ScanRequest{Cid: "c", Start: "u/", MinSeq: 9}); !sr.Behind {
+		t.Fatalf("Expected Behind for a future snapshot, got %+v", sr)
+	}
+}
+
+func TestReplicaRangeCertification(t *testing.T) {
+	rep := NewReplica("r")
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t1", Ws: []types.WriteEntry{{Item: "u/1", Value: []byte("a")}}})
+	scanned := types.RangeRead{Start: "u/", End: "u0", Seq: rep.LastCommitted}
+
+	// escrita fora do intervalo não invalida a varredura
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t2", Ws: []types.WriteEntry{{Item: "v/1", Value: []byte("z")}}})
+	if dec := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t3", Ranges: []types.RangeRead{scanned}}); !dec.Commit {
+		t.Fatalf("Expected commit when range unchanged")
+	}
+	// inserção no intervalo é um fantasma
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t4", Ws: []types.WriteEntry{{Item: "u/2", Value: []byte("b")}}})
+	if dec := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t5", Ranges: []types.RangeRead{scanned}}); dec.Commit {
+		t.Errorf("Expected abort after insert in scanned range")
+	}
+
+	// remoção cujo tombstone já foi coletado também aborta
+	rep.TombstoneRetention = 0
+	scanned.Seq = rep.LastCommitted
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t6", Ws: []types.WriteEntry{{Item: "u/1", Op: types.OpDelete}}})
+	rep.CollectTombstones(rep.LastCommitted)
+	if dec := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t7", Ranges: []types.RangeRead{scanned}}); dec.Commit {
+		t.Errorf("Expected abort after collected delete in scanned range")
+	}
+}
+
+func TestReplicaReadMany(t *testing.T) {
+	rep := NewReplica("r")
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t1", Ws: []types.WriteEntry{{Item: "y", Value: []byte("1")}}})
+	mr := rep.ReadMany(types.MultiReadRequest{Cid: "c", Items: []string{"x", "missing", "y"}})
+	if mr.Seq != 1 || len(mr.Replies) != 3 {
+		t.Fatalf("Expected 3 replies at seq 1, got %+v", mr)
+	}
+	if r := mr.Replies[0]; !r.Found || string(r.Value) != "init" || r.Seq != mr.Seq {
+		t.Errorf("Unexpected reply for x: %+v", r)
+	}
+	if r := mr.Replies[1]; r.Found {
+		t.Errorf("Expected missing key not found, got %+v", r)
+	}
+	if r := mr.Replies[2]; !r.Found || r.Version != 1 {
+		t.Errorf("Unexpected reply for y: %+v", r)
+	}
+	if mr := rep.ReadMany(types.MultiReadRequest{Cid: "c", Items: []string{"x"}, MinSeq: 5}); !mr.Behind || mr.Replies != nil {
+		t.Errorf("Expected Behind for a future snapshot, got %+v", mr)
+	}
+}
+
+func TestReplicaCommutativeOps(t *testing.T) {
+	rep := NewReplica("r")
+	for i := 0; i < 3; i++ {
+		incr := types.CommitRequest{Cid: "c", Tid: fmt.Sprintf("t%d", i), Ws: []types.WriteEntry{{Item: "n", Op: types.OpIncrement, Delta: 2}}}
+		if dec := rep.Certify(incr); !dec.Commit {
+			t.Fatalf("Expected increment %d to commit", i)
+		}
+	}
+	if rr := rep.Read(types.ReadRequest{Item: "n"}); string(rr.Value) != "6" || rr.Version != 3 {
+		t.Fatalf("Expected n=6 at v3, got %+v", rr)
+	}
+
+	// operando inválido aborta a transação inteira, sem aplicar nada
+	bad := types.CommitRequest{Cid: "c", Tid: "t4", Ws: []types.WriteEntry{
+		{Item: "y", Value: []byte("1")}, {Item: "x", Op: types.OpIncrement, Delta: 1},
+	}}
+	if dec := rep.Certify(bad); dec.Commit {
+		t.Fatalf("Expected abort incrementing a non-integer value")
+	}
+	if _, ok := rep.Db["y"]; ok || rep.LastCommitted != 3 {
+		t.Errorf("Expected aborted write set not applied")
+	}
+}
+
+func TestReplicaPreconditions(t *testing.T) {
+	rep := NewReplica("r")
+	put := func(tid string, pre types.Precondition) types.CommitDecision {
+		return rep.Certify(types.CommitRequest{Cid: "c", Tid: tid, Ws: []types.WriteEntry{{Item: pre.Item, Value: []byte(tid)}}, Pre: []types.Precondition{pre}})
+	}
+	if dec := put("t1", types.Precondition{Item: "k", Absent: true}); !dec.Commit {
+		t.Fatalf("Expected insert of absent key to commit, got %+v", dec)
+	}
+	dec := put("t2", types.Precondition{Item: "k", Absent: true})
+	if dec.Commit || dec.Reason != types.ReasonPrecondition || dec.Item != "k" {
+		t.Fatalf("Expected precondition failure on k, got %+v", dec)
+	}
+	if dec := put("t3", types.Precondition{Item: "k", Version: 7}); dec.Commit || dec.Reason != types.ReasonPrecondition {
+		t.Fatalf("Expected version mismatch, got %+v", dec)
+	}
+	if dec := put("t4", types.Precondition{Item: "k", Version: 1}); !dec.Commit {
+		t.Fatalf("Expected compare-and-set at v1 to commit, got %+v", dec)
+	}
+	stale := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t5", Rs: []types.ReadEntry{{Item: "k", Version: 1}}})
+	if stale.Reason != types.ReasonStaleRead || stale.Item != "k" {
+		t.Errorf("Expected stale-read on k, got %+v", stale)
+	}
+	bad := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t6", Ws: []types.WriteEntry{{Item: "k", Op: types.OpIncrement, Delta: 1}}})
+	if bad.Reason != types.ReasonBadOperand || bad.Item != "k" {
+		t.Errorf("Expected bad-operand on k, got %+v", bad)
+	}
+}
+
+func TestReplicaDedupesCommits(t *testing.T) {
+	rep := NewReplica("r")
+	rep.DecisionWindow = 2
+	incr := func(tid string) types.CommitRequest {
+		return types.CommitRequest{Cid: "c", Tid: tid, Ws: []types.WriteEntry{{Item: "n", Op: types.OpIncrement, Delta: 1}}}
+	}
+	first := rep.Certify(incr("t1"))
+	if again := rep.Certify(incr("t1")); again != first {
+		t.Fatalf("Expected the original decision %+v, got %+v", first, again)
+	}
+	if rr := rep.Read(types.ReadRequest{Item: "n"}); string(rr.Value) != "1" {
+		t.Fatalf("Expected the duplicate not to be applied, got n=%s", rr.Value)
+	}
+	if st := rep.Status(types.StatusRequest{Cid: "c", Tid: "t1"}); !st.Known || st.Decision != first {
+		t.Errorf("Expected t1 known, got %+v", st)
+	}
+	if st := rep.Status(types.StatusRequest{Cid: "other", Tid: "t1"}); st.Known {
+		t.Errorf("Expected decisions keyed by cid and tid, got %+v", st)
+	}
+	// fora da janela a decisão é esquecida
+	rep.Certify(incr("t2"))
+	rep.Certify(incr("t3"))
+	if st := rep.Status(types.StatusRequest{Cid: "c", Tid: "t1"}); st.Known {
+		t.Errorf("Expected t1 evicted from the window, got %+v", st)
+	}
+}
+
+func TestReplicaForgetsUnavailableAborts(t *testing.T) {
+	rep := NewReplica("r")
+	req := types.CommitRequest{Cid: "c", Tid: "t1", Ws: []types.WriteEntry{{Item: "n", Op: types.OpIncrement, Delta: 1}}}
+	rep.closed = true
+	if dec := rep.Certify(req); dec.Reason != types.ReasonUnavailable {
+		t.Fatalf("Expected unavailable while the log is closed, got %+v", dec)
+	}
+	if st := rep.Status(types.StatusRequest{Cid: "c", Tid: "t1"}); st.Known {
+		t.Fatalf("Expected the unavailable abort not to be remembered, got %+v", st)
+	}
+	rep.closed = false
+	if dec := rep.Certify(req); !dec.Commit {
+		t.Fatalf("Expected the resubmitted Tid to commit, got %+v", dec)
+	}
+	if st := rep.Status(types.StatusRequest{Cid: "c", Tid: "t1"}); !st.Known || !st.Decision.Commit {
+		t.Errorf("Expected the commit to be remembered, got %+v", st)
+	}
+}
+
+func TestReplicaLogsRedactValues(t *testing.T) {
+	var buf bytes.Buffer
+	rep := NewReplica("r")
+	rep.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	dec := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t", Ws: []types.WriteEntry{{Item: "k", Value: []byte("secret")}}})
+	if !dec.Commit {
+		t.Fatalf("Expected commit, got %+v", dec)
+	}
+	rep.Read(types.ReadRequest{Cid: "c", Item: "k"})
+	if strings.Contains(buf.String(), "secret") {
+		t.Fatalf("Expected values redacted, got %s", buf.String())
+	}
+	var applied bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+		if rec["replica"] != "r" || rec["component"] != "replica" {
+			t.Errorf("Expected replica fields, got %v", rec)
+		}
+		if rec["msg"] == "applied write" {
+			applied = rec["item"] == "k" && rec["value"] == "<6 bytes>" && rec["seq"] == float64(1)
+		}
+	}
+	if !applied {
+		t.Errorf("Expected an applied write record, got %s", buf.String())
+	}
+}
+
+// BenchmarkCertify mede commits sem conflito, com o log silenciado
+func BenchmarkCertify(b *testing.B) {
+	rep := NewReplica("r")
+	rep.Logger = logging.Discard
+	ws := []types.WriteEntry{{Item: "k", Value: []byte("v")}}
+	for i := 0; b.Loop(); i++ {
+		rep.Certify(types.CommitRequest{Cid: "c", Tid: strconv.Itoa(i), Ws: ws})
+	}
+}
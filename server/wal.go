@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// walFile é o nome do log de commits dentro do diretório de dados
+const walFile = "wal.jsonl"
+
+// logFile é o que a réplica usa do arquivo de log; os testes o trocam
+// para simular falhas de disco
+type logFile interface {
+	io.WriteSeeker
+	Sync() error
+	Truncate(size int64) error
+	Name() string
+	Close() error
+}
+
+// OpenReplica cria uma réplica persistente em dir: cada commit é gravado
+// no log (dir/wal.jsonl) antes de ser aplicado, e o log é reaplicado na
+// abertura. Uma última linha incompleta (queda no meio da escrita) é descartada.
+func OpenReplica(addr, dir string) (*Replica, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, walFile)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	rep := NewReplica(addr)
+	good, err := rep.replay(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("replay %s: %w", path, err)
+	}
+	if err := f.Truncate(good); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(good, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	rep.wal = f
+	return rep, nil
+}
+
+// replay reaplica os commits de r e retorna o tamanho da parte íntegra
+func (rep *Replica) replay(r io.Reader) (int64, error) {
+	br := bufio.NewReader(r)
+	var good int64
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return good, nil
+		}
+		if err != nil {
+			return good, err
+		}
+		var probe map[string]json.RawMessage
+		if err := json.Unmarshal(line, &probe); err != nil {
+			return good, fmt.Errorf("line %d: %w", n, err)
+		}
+		if _, isRepair := probe["repair"]; isRepair {
+			var req types.RepairRequest
+			json.Unmarshal(line, &req)
+			if err := rep.applyRepair(req); err != nil {
+				return good, fmt.Errorf("line %d: %w", n, err)
+			}
+			good += int64(len(line))
+			continue
+		}
+		var req types.CommitRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			return good, fmt.Errorf("line %d: %w", n, err)
+		}
+		dec := rep.decide(req, nil)
+		if !dec.Commit {
+			return good, fmt.Errorf("line %d: commit %s/%s no longer certifies: %s", n, req.Cid, req.Tid, dec.Reason)
+		}
+		if req.Tid != "" {
+			rep.remember(req.Cid+"/"+req.Tid, dec)
+		}
+		good += int64(len(line))
+	}
+}
+
+// persist grava rec, um CommitRequest ou um RepairRequest, no log e espera
+// chegar ao disco; chamado com mu travado. Se a gravação falhar, o log é
+// truncado de volta, para que replay não aplique um commit que abortou;
+// se nem isso der certo, a réplica fecha o log e recusa novos commits.
+func (rep *Replica) persist(rec any) error {
+	if rep.closed {
+		return errReplicaClosed
+	}
+	if rep.wal == nil {
+		return nil
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	off, err := rep.wal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	_, err = rep.wal.Write(append(line, '\n'))
+	if err == nil {
+		err = rep.wal.Sync()
+	}
+	if err != nil {
+		rep.undo(off)
+	}
+	return err
+}
+
+// undo descarta o que foi escrito no log depois de off
+func (rep *Replica) undo(off int64) {
+	err := rep.wal.Truncate(off)
+	if err == nil {
+		_, err = rep.wal.Seek(off, io.SeekStart)
+	}
+	if err == nil {
+		err = rep.wal.Sync()
+	}
+	if err != nil {
+		rep.logger().Error("wal rollback failed, closing the log", "offset", off, "err", err)
+		rep.wal.Close()
+		rep.wal = nil
+		rep.closed = true
+	}
+}
+
+// errReplicaClosed aborta commits que chegam depois de Close
+var errReplicaClosed = errors.New("replica closed")
+
+// Close fecha o log de uma réplica aberta com OpenReplica; commits
+// posteriores abortam com ReasonUnavailable
+func (rep *Replica) Close() error {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	if rep.wal == nil {
+		return nil
+	}
+	err := rep.wal.Close()
+	rep.wal = nil
+	rep.closed = true
+	return err
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/logging"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+func TestOpenReplicaReplaysLog(t *testing.T) {
+	dir := t.TempDir()
+	rep, err := OpenReplica("r", dir)
+	if err != nil {
+		t.Fatalf("OpenReplica error: %v", err)
+	}
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t1", Ws: []types.WriteEntry{{Item: "x", Value: []byte("a")}, {Item: "n", Op: types.OpIncrement, Delta: 2}}})
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t2", Rs: []types.ReadEntry{{Item: "x", Version: 99}}, Ws: []types.WriteEntry{{Item: "x", Value: []byte("stale")}}})
+	last := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t3", Ws: []types.WriteEntry{{Item: "n", Op: types.OpIncrement, Delta: 3}}})
+	rep.Close()
+
+	again, err := OpenReplica("r", dir)
+	if err != nil {
+		t.Fatalf("Reopen error: %v", err)
+	}
+	defer again.Close()
+	if again.LastCommitted != last.Seq {
+		t.Fatalf("Expected LastCommitted %d after replay, got %d", last.Seq, again.LastCommitted)
+	}
+	if rr := again.Read(types.ReadRequest{Item: "x"}); string(rr.Value) != "a" {
+		t.Errorf("Expected x=a, got %+v", rr)
+	}
+	if rr := again.Read(types.ReadRequest{Item: "n"}); string(rr.Value) != "5" || rr.Version != last.Seq {
+		t.Errorf("Expected n=5 at v%d, got %+v", last.Seq, rr)
+	}
+	if st := again.Status(types.StatusRequest{Cid: "c", Tid: "t3"}); !st.Known || st.Seq != last.Seq {
+		t.Errorf("Expected replayed decision for t3, got %+v", st)
+	}
+	next := again.Certify(types.CommitRequest{Cid: "c", Tid: "t4", Ws: []types.WriteEntry{{Item: "y", Value: []byte("b")}}})
+	if !next.Commit || next.Seq != last.Seq+1 {
+		t.Errorf("Expected commit at seq %d after reopen, got %+v", last.Seq+1, next)
+	}
+}
+
+func TestOpenReplicaDropsTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	rep, _ := OpenReplica("r", dir)
+	rep.Certify(types.CommitRequest{Cid: "c", Tid: "t1", Ws: []types.WriteEntry{{Item: "x", Value: []byte("a")}}})
+	rep.Close()
+	f, _ := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"cid":"c","tid":"t2","rs":nu`)
+	f.Close()
+
+	again, err := OpenReplica("r", dir)
+	if err != nil {
+		t.Fatalf("Expected torn tail to be dropped, got %v", err)
+	}
+	if again.LastCommitted != 1 {
+		t.Errorf("Expected LastCommitted 1, got %d", again.LastCommitted)
+	}
+	again.Certify(types.CommitRequest{Cid: "c", Tid: "t3", Ws: []types.WriteEntry{{Item: "x", Value: []byte("b")}}})
+	again.Close()
+	if third, err := OpenReplica("r", dir); err != nil || third.LastCommitted != 2 {
+		t.Fatalf("Expected clean log after truncation, got err=%v", err)
+	}
+}
+
+func TestReplicaShutdown(t *testing.T) {
+	tr := network.NewMemTransport()
+	rep, _ := OpenReplica("r", t.TempDir())
+	rep.Transport = tr
+	if err := rep.Start(context.Background()); err != nil {
+		t.Fatalf("Start error: %v", err)
+	}
+	var dec types.CommitDecision
+	network.RequestWith(tr, "r", types.CommitRequest{Cid: "c", Tid: "t1", Ws: []types.WriteEntry{{Item: "x", Value: []byte("a")}}}, &dec)
+	if !dec.Commit {
+		t.Fatalf("Expected commit before shutdown, got %+v", dec)
+	}
+	if err := rep.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown error: %v", err)
+	}
+	var rr types.ReadReply
+	if err := network.RequestWith(tr, "r", types.ReadRequest{Item: "x"}, &rr); !errors.Is(err, network.ErrConnRefused) {
+		t.Errorf("Expected connection refused after shutdown, got %v", err)
+	}
+	if late := rep.Certify(types.CommitRequest{Cid: "c", Tid: "t2", Ws: []types.WriteEntry{{Item: "x", Value: []byte("b")}}}); late.Commit || late.Reason != types.ReasonUnavailable {
+		t.Errorf("Expected commits after the log closed to abort, got %+v", late)
+	}
+}
+
+// faultyLog é um log cujas próximas syncs e truncates falham
+type faultyLog struct {
+	*os.File
+	syncs, truncates int
+}
+
+func (f *faultyLog) Sync() error {
+	if f.syncs > 0 {
+		f.syncs--
+		return errors.New("sync: input/output error")
+	}
+	return f.File.Sync()
+}
+
+func (f *faultyLog) Truncate(size int64) error {
+	if f.truncates > 0 {
+		f.truncates--
+		return errors.New("truncate: input/output error")
+	}
+	return f.File.Truncate(size)
+}
+
+func TestReplicaRollsBackFailedSync(t *testing.T) {
+	dir := t.TempDir()
+	rep, err := OpenReplica("r", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep.Logger = logging.Discard
+	put(rep, "t1", "x", "a")
+	rep.wal = &faultyLog{File: rep.wal.(*os.File), syncs: 1}
+	if dec := put(rep, "t2", "x", "lost"); dec.Commit || dec.Reason != types.ReasonUnavailable {
+		t.Fatalf("Expected unavailable when Sync fails, got %+v", dec)
+	}
+	if dec := put(rep, "t3", "y", "b"); !dec.Commit || dec.Seq != 2 {
+		t.Fatalf("Expected t3 committed at seq 2, got %+v", dec)
+	}
+	rep.Close()
+
+	// o commit que abortou não volta no replay
+	again, err := OpenReplica("r", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer again.Close()
+	again.Logger = logging.Discard
+	if x := again.Db["x"]; again.LastCommitted != 2 || string(x.Value) != "a" {
+		t.Errorf("Expected seq 2 with x=a after reopening, got seq %d x=%q", again.LastCommitted, x.Value)
+	}
+	if st := again.Status(types.StatusRequest{Cid: "c", Tid: "t2"}); st.Known {
+		t.Errorf("Expected the aborted t2 not to be replayed, got %+v", st)
+	}
+
+	// sem conseguir desfazer a escrita, a réplica para de aceitar commits
+	again.wal = &faultyLog{File: again.wal.(*os.File), syncs: 1, truncates: 1}
+	put(again, "t4", "x", "lost")
+	if dec := put(again, "t5", "x", "b"); dec.Commit || dec.Reason != types.ReasonUnavailable {
+		t.Errorf("Expected commits refused after a failed rollback, got %+v", dec)
+	}
+}
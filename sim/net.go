@@ -0,0 +1,451 @@
+package sim
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hrodric0/dur-impl/network"
+)
+
+// errReset é visto por quem fala com um nó que caiu
+var errReset = fmt.Errorf("sim: %w", syscall.ECONNRESET)
+
+type nodeState int
+
+const (
+	up nodeState = iota
+	crashed
+	hung
+)
+
+// epoch é a hora do relógio virtual no início da execução
+var epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// simNet é um network.Transport em tempo virtual. Os componentes rodam em
+// suas próprias goroutines; o laço de Run entrega um evento por vez e, antes
+// do próximo, espera (synctest.Wait) que todas estejam bloqueadas. Eventos
+// criados pelas goroutines num passo ficam em pending e só entram na fila no
+// fim do passo, ordenados por uma chave que não depende da intercalação, e
+// só então recebem a latência sorteada. Assim a mesma seed dá a mesma
+// execução.
+type simNet struct {
+	mu        sync.Mutex
+	now       time.Duration
+	queue     eventQueue
+	nextID    uint64
+	pending   []*event
+	listeners map[string]*simListener
+	conns     []*simConn
+	dials     map[[2]string]int
+	timers    map[string]int
+	last      map[string]time.Duration // última entrega por sentido de conexão
+	state     map[string]nodeState
+	closed    bool
+}
+
+func newSimNet() *simNet {
+	return &simNet{
+		listeners: make(map[string]*simListener),
+		dials:     make(map[[2]string]int),
+		timers:    make(map[string]int),
+		last:      make(map[string]time.Duration),
+		state:     make(map[string]nodeState),
+	}
+}
+
+// Node retorna o transporte visto pelo nó name; ele também é o
+// network.Clock do nó, então prazos e esperas correm no tempo virtual
+func (s *simNet) Node(name string) network.Transport { return simNode{s, name} }
+
+type simNode struct {
+	s    *simNet
+	name string
+}
+
+// Now é a hora virtual
+func (n simNode) Now() time.Time {
+	n.s.mu.Lock()
+	defer n.s.mu.Unlock()
+	return epoch.Add(n.s.now)
+}
+
+// After dispara depois de d no tempo virtual, como um evento do laço de Run
+func (n simNode) After(d time.Duration) <-chan time.Time {
+	s := n.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	s.timers[n.name]++
+	at := s.now + max(d, 0)
+	s.pending = append(s.pending, &event{
+		key:  fmt.Sprintf("%s/timer%08d", n.name, s.timers[n.name]),
+		at:   at,
+		desc: n.name + " timer",
+		fn:   func() { ch <- epoch.Add(at) },
+	})
+	return ch
+}
+
+func (n simNode) Listen(addr string) (net.Listener, error) {
+	s := n.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.listeners[addr]; ok {
+		return nil, fmt.Errorf("sim listen %s: address already in use", addr)
+	}
+	ln := &simListener{s: s, node: n.name, addr: simAddr(addr), wake: make(chan struct{}, 1)}
+	s.listeners[addr] = ln
+	return ln, nil
+}
+
+// Dial conecta na hora; o listener só vê a conexão depois de uma latência
+func (n simNode) Dial(addr string) (net.Conn, error) {
+	s := n.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ln, ok := s.listeners[addr]
+	if !ok || ln.closed || s.closed || s.state[ln.node] == crashed {
+		return nil, fmt.Errorf("sim dial %s: %w", addr, network.ErrConnRefused)
+	}
+	pair := [2]string{n.name, ln.node}
+	s.dials[pair]++
+	id := fmt.Sprintf("%s>%s#%d", n.name, ln.node, s.dials[pair])
+	c := &simConn{s: s, id: id + "/c", node: n.name, local: simAddr(n.name), remote: ln.addr, wake: make(chan struct{}, 1)}
+	srv := &simConn{s: s, id: id + "/s", node: ln.node, local: ln.addr, remote: simAddr(n.name), wake: make(chan struct{}, 1)}
+	c.peer, srv.peer = srv, c
+	s.conns = append(s.conns, c, srv)
+	s.send(c, "connect", func() {
+		ln.conns = append(ln.conns, srv)
+		signal(ln.wake)
+	})
+	return c, nil
+}
+
+// send cria em pending a entrega de from ao outro lado; em s.mu
+func (s *simNet) send(from *simConn, desc string, fn func()) {
+	from.sent++
+	s.pending = append(s.pending, &event{
+		key:  fmt.Sprintf("%s/%08d", from.id, from.sent),
+		dir:  from.id,
+		from: from.node,
+		to:   from.peer.node,
+		desc: from.node + " -> " + from.peer.node + " " + desc,
+		fn:   fn,
+	})
+}
+
+// flush ordena os eventos do último passo e os põe na fila com latência
+// sorteada por draw; entregas no mesmo sentido de uma conexão ficam em ordem
+func (s *simNet) flush(draw func() time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sort.Slice(s.pending, func(i, j int) bool { return s.pending[i].key < s.pending[j].key })
+	for _, ev := range s.pending {
+		if ev.dir != "" {
+			ev.at = max(s.now+draw(), s.last[ev.dir])
+			s.last[ev.dir] = ev.at
+		}
+		s.nextID++
+		ev.id = s.nextID
+		heap.Push(&s.queue, ev)
+	}
+	s.pending = nil
+}
+
+// after agenda fn no tempo virtual now+d; só o laço de Run chama
+func (s *simNet) after(d time.Duration, key, desc string, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	heap.Push(&s.queue, &event{at: s.now + d, id: s.nextID, key: key, desc: desc, fn: fn})
+}
+
+// next retira o próximo evento e avança o relógio; nil se a fila acabou
+// ou o próximo evento passa de limit
+func (s *simNet) next(limit time.Duration) *event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queue.Len() == 0 || s.queue[0].at > limit {
+		return nil
+	}
+	ev := heap.Pop(&s.queue).(*event)
+	s.now = ev.at
+	return ev
+}
+
+// deliver aplica ev, a menos que uma das pontas esteja com falha: mensagens
+// de ou para um nó travado somem; para um nó caído, o remetente vê um reset
+func (s *simNet) deliver(ev *event) (delivered bool) {
+	if ev.dir == "" {
+		ev.fn()
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state[ev.from] != up || s.state[ev.to] == hung {
+		return false
+	}
+	if s.state[ev.to] == crashed {
+		s.resetDir(ev.dir)
+		return false
+	}
+	ev.fn()
+	return true
+}
+
+// resetDir derruba a conexão cujo lado id enviou a mensagem recusada; em s.mu
+func (s *simNet) resetDir(id string) {
+	for _, c := range s.conns {
+		if c.id == id {
+			c.reset = true
+			signal(c.wake)
+		}
+	}
+}
+
+// setState muda o estado de um nó; quando ele cai, os pares das suas
+// conexões veem reset, como ao fechar os sockets de um processo morto
+func (s *simNet) setState(node string, st nodeState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state[node] = st
+	if st != crashed {
+		return
+	}
+	for _, c := range s.conns {
+		if c.node == node {
+			c.peer.reset = true
+			signal(c.peer.wake)
+		}
+	}
+}
+
+func (s *simNet) stateOf(node string) nodeState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state[node]
+}
+
+// close fecha todas as conexões e listeners, para os componentes terminarem
+func (s *simNet) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, c := range s.conns {
+		c.closed = true
+		signal(c.wake)
+	}
+	for _, ln := range s.listeners {
+		ln.closed = true
+		signal(ln.wake)
+	}
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+type simAddr string
+
+func (a simAddr) Network() string { return "sim" }
+func (a simAddr) String() string  { return string(a) }
+
+type simListener struct {
+	s      *simNet
+	node   string
+	addr   simAddr
+	conns  []*simConn
+	closed bool
+	wake   chan struct{}
+}
+
+func (l *simListener) Accept() (net.Conn, error) {
+	for {
+		l.s.mu.Lock()
+		if l.closed {
+			l.s.mu.Unlock()
+			return nil, net.ErrClosed
+		}
+		if len(l.conns) > 0 {
+			c := l.conns[0]
+			l.conns = l.conns[1:]
+			l.s.mu.Unlock()
+			return c, nil
+		}
+		l.s.mu.Unlock()
+		<-l.wake
+	}
+}
+
+func (l *simListener) Close() error {
+	l.s.mu.Lock()
+	defer l.s.mu.Unlock()
+	l.closed = true
+	signal(l.wake)
+	return nil
+}
+
+func (l *simListener) Addr() net.Addr { return l.addr }
+
+// simConn é um lado de uma conexão simulada; o estado é protegido por s.mu
+type simConn struct {
+	s             *simNet
+	id            string
+	node          string
+	local, remote simAddr
+	peer          *simConn
+	wake          chan struct{}
+
+	buf      []byte
+	eof      bool // o outro lado fechou e tudo já chegou
+	reset    bool
+	closed   bool
+	sent     int
+	deadline int  // geração do prazo corrente
+	expired  bool // o prazo corrente passou
+}
+
+func (c *simConn) Read(p []byte) (int, error) {
+	for {
+		c.s.mu.Lock()
+		switch {
+		case c.closed:
+			c.s.mu.Unlock()
+			return 0, net.ErrClosed
+		case len(c.buf) > 0:
+			n := copy(p, c.buf)
+			c.buf = c.buf[n:]
+			c.s.mu.Unlock()
+			return n, nil
+		case c.reset:
+			c.s.mu.Unlock()
+			return 0, errReset
+		case c.eof:
+			c.s.mu.Unlock()
+			return 0, io.EOF
+		case c.expired:
+			c.s.mu.Unlock()
+			return 0, os.ErrDeadlineExceeded
+		}
+		c.s.mu.Unlock()
+		<-c.wake
+	}
+}
+
+// Write nunca bloqueia: a mensagem é entregue inteira depois da latência
+func (c *simConn) Write(p []byte) (int, error) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	switch {
+	case c.closed:
+		return 0, net.ErrClosed
+	case c.reset:
+		return 0, errReset
+	}
+	msg := bytes.Clone(p)
+	peer := c.peer
+	c.s.send(c, string(bytes.TrimSpace(msg)), func() {
+		if !peer.closed {
+			peer.buf = append(peer.buf, msg...)
+			signal(peer.wake)
+		}
+	})
+	return len(p), nil
+}
+
+func (c *simConn) Close() error {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	signal(c.wake)
+	peer := c.peer
+	c.s.send(c, "close", func() {
+		peer.eof = true
+		signal(peer.wake)
+	})
+	return nil
+}
+
+func (c *simConn) LocalAddr() net.Addr  { return c.local }
+func (c *simConn) RemoteAddr() net.Addr { return c.remote }
+
+// SetDeadline agenda o prazo t, da hora virtual de simNode.Now, como um
+// evento do laço de Run
+func (c *simConn) SetDeadline(t time.Time) error {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	c.deadline++
+	c.expired = false
+	if t.IsZero() {
+		return nil
+	}
+	d := t.Sub(epoch.Add(c.s.now))
+	if d <= 0 {
+		c.expired = true
+		signal(c.wake)
+		return nil
+	}
+	gen := c.deadline
+	c.s.pending = append(c.s.pending, &event{
+		key:  fmt.Sprintf("%s/deadline%08d", c.id, gen),
+		at:   c.s.now + d,
+		desc: c.node + " deadline",
+		fn: func() {
+			c.s.mu.Lock()
+			defer c.s.mu.Unlock()
+			if c.deadline == gen {
+				c.expired = true
+				signal(c.wake)
+			}
+		},
+	})
+	return nil
+}
+
+func (c *simConn) SetReadDeadline(t time.Time) error { return c.SetDeadline(t) }
+func (c *simConn) SetWriteDeadline(time.Time) error  { return nil }
+
+// event é uma entrega (dir não vazio), um prazo, um timer ou um evento do laço;
+// só as entregas rodam fn com s.mu travado
+type event struct {
+	at       time.Duration
+	id       uint64
+	key      string
+	dir      string // lado que enviou, para manter a ordem por sentido
+	from, to string
+	desc     string
+	fn       func()
+}
+
+// eventQueue ordena por tempo virtual e, em empate, por ordem de entrada
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].id < q[j].id
+}
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *eventQueue) Push(x any)   { *q = append(*q, x.(*event)) }
+func (q *eventQueue) Pop() any {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
@@ -0,0 +1,395 @@
+// Package sim executa o sequencer, as réplicas e os clientes reais
+// (broadcast.Sequencer, server.Replica e client.Transaction) sobre uma rede
+// simulada, com um escalonador semeado e tempo virtual. A rede é também o
+// network.Clock dos componentes: prazos e esperas são eventos do
+// escalonador. Ele entrega um evento por vez e, dentro de uma bolha de
+// testing/synctest, só segue quando todas as goroutines estão bloqueadas,
+// então a mesma Config produz sempre a mesma intercalação de entregas e
+// falhas, e uma execução que falha pode ser reproduzida exatamente.
+package sim
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/hrodric0/dur-impl/broadcast"
+	"github.com/hrodric0/dur-impl/client"
+	"github.com/hrodric0/dur-impl/health"
+	"github.com/hrodric0/dur-impl/logging"
+	"github.com/hrodric0/dur-impl/server"
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// Config parametriza uma execução simulada
+type Config struct {
+	Seed        int64
+	Replicas    int
+	Clients     int
+	TxPerClient int
+	Keys        int
+	MaxLatency  time.Duration // latência máxima de uma mensagem (tempo virtual)
+	CrashRate   float64       // chance, por entrega, de a réplica cair (conexões recusadas)
+	HangRate    float64       // chance, por entrega, de a réplica travar (aceita e nunca responde)
+	MaxFaults   int           // número máximo de réplicas com falha
+	ReadTimeout time.Duration // client.Transaction.ReadTimeout; zero não impõe prazo
+	Heartbeat   time.Duration // intervalo do Detector do sequencer; zero não usa Detector
+	MaxTime     time.Duration // a execução para neste tempo virtual
+}
+
+// withDefaults preenche os campos zerados
+func (c Config) withDefaults() Config {
+	if c.Replicas <= 0 {
+		c.Replicas = 2
+	}
+	if c.Clients <= 0 {
+		c.Clients = 2
+	}
+	if c.TxPerClient <= 0 {
+		c.TxPerClient = 5
+	}
+	if c.Keys <= 0 {
+		c.Keys = 3
+	}
+	if c.MaxLatency <= 0 {
+		c.MaxLatency = 10 * time.Millisecond
+	}
+	if c.MaxFaults <= 0 {
+		c.MaxFaults = 1
+	}
+	if c.MaxTime <= 0 {
+		c.MaxTime = time.Minute
+	}
+	return c
+}
+
+// Flags descreve c como os flags de go test que reproduzem a execução
+func (c Config) Flags() string {
+	return fmt.Sprintf("-sim.seed=%d -sim.replicas=%d -sim.clients=%d -sim.tx=%d -sim.keys=%d -sim.latency=%v -sim.crash=%v -sim.hang=%v -sim.faults=%d -sim.read-timeout=%v -sim.heartbeat=%v -sim.max-time=%v",
+		c.Seed, c.Replicas, c.Clients, c.TxPerClient, c.Keys, c.MaxLatency, c.CrashRate, c.HangRate, c.MaxFaults, c.ReadTimeout, c.Heartbeat, c.MaxTime)
+}
+
+// Violation descreve um invariante quebrado
+type Violation struct {
+	At     time.Duration
+	Kind   string // "stuck", "divergence" ou "decision"
+	Detail string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%v %s: %s", v.At, v.Kind, v.Detail)
+}
+
+// Result é o resultado de uma execução; Config já tem os padrões aplicados
+type Result struct {
+	Config     Config
+	Seed       int64
+	Trace      []string
+	Violations []Violation
+	Committed  int
+	Aborted    int
+	End        time.Duration
+}
+
+type simReplica struct {
+	name string
+	rep  *server.Replica
+}
+
+type simClient struct {
+	cid     string
+	txs     [][]op
+	done    int
+	tid     string
+	waiting string
+}
+
+type op struct {
+	write bool
+	key   string
+}
+
+// outcome é uma decisão recebida por um cliente
+type outcome struct {
+	cid, tid string
+	commit   bool
+}
+
+type world struct {
+	cfg      Config
+	rng      *rand.Rand
+	net      *simNet
+	replicas []*simReplica
+	clients  []*simClient
+	seq      *broadcast.Sequencer
+	detector *health.Detector
+	faults   int
+	running  sync.WaitGroup
+
+	mu       sync.Mutex // protege o que as goroutines dos clientes escrevem
+	res      Result
+	outcomes []outcome
+	over     bool
+}
+
+// Run executa a simulação descrita por cfg, numa bolha de synctest, até
+// não haver mais eventos ou o tempo virtual chegar a cfg.MaxTime
+func Run(t *testing.T, cfg Config) Result {
+	var res Result
+	synctest.Test(t, func(*testing.T) { res = run(cfg) })
+	return res
+}
+
+func run(cfg Config) Result {
+	cfg = cfg.withDefaults()
+	w := &world{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed)), net: newSimNet(), res: Result{Config: cfg, Seed: cfg.Seed}}
+	var names []string
+	for i := 0; i < cfg.Replicas; i++ {
+		name := fmt.Sprintf("r%d", i+1)
+		rep := server.NewReplica(name)
+		rep.Transport = w.net.Node(name)
+		// na simulação o log dos componentes reais é ruído
+		rep.Logger = logging.Discard
+		rep.Start(context.Background())
+		w.replicas = append(w.replicas, &simReplica{name: name, rep: rep})
+		names = append(names, name)
+	}
+	w.seq = broadcast.NewSequencer("seq", names)
+	w.seq.Transport = w.net.Node("seq")
+	w.seq.Logger = logging.Discard
+	if cfg.Heartbeat > 0 {
+		// o Detector não é iniciado: o laço chama Probe no tempo virtual
+		w.detector = &health.Detector{Addrs: names, Transport: w.net.Node("hb"), Interval: cfg.Heartbeat, Logger: logging.Discard}
+		w.seq.Detector = w.detector
+		w.net.after(cfg.Heartbeat, "heartbeat", "heartbeat", w.heartbeat)
+	}
+	w.seq.Start(context.Background())
+	for i := 0; i < cfg.Clients; i++ {
+		c := &simClient{cid: fmt.Sprintf("c%d", i+1)}
+		for range cfg.TxPerClient {
+			var ops []op
+			for n := 1 + w.rng.Intn(3); n > 0; n-- {
+				ops = append(ops, op{write: w.rng.Intn(2) == 0, key: fmt.Sprintf("k%d", w.rng.Intn(cfg.Keys))})
+			}
+			c.txs = append(c.txs, ops)
+		}
+		w.clients = append(w.clients, c)
+		w.net.after(w.latency(), c.cid, c.cid+" begins", func() {
+			w.running.Add(1)
+			go w.runClient(c, names)
+		})
+	}
+	for {
+		synctest.Wait()
+		w.net.flush(w.latency)
+		ev := w.net.next(cfg.MaxTime)
+		if ev == nil {
+			break
+		}
+		w.fault(ev)
+		if w.net.deliver(ev) {
+			w.tracef("%s", ev.desc)
+		} else {
+			w.tracef("%s (lost)", ev.desc)
+		}
+	}
+	w.mu.Lock()
+	w.res.End = w.net.now
+	w.mu.Unlock()
+	w.check()
+	w.stop()
+	return w.res
+}
+
+func (w *world) tracef(format string, args ...any) {
+	w.net.mu.Lock()
+	now := w.net.now
+	w.net.mu.Unlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.res.Trace = append(w.res.Trace, fmt.Sprintf("%10v ", now)+fmt.Sprintf(format, args...))
+}
+
+func (w *world) violate(kind, format string, args ...any) {
+	w.res.Violations = append(w.res.Violations, Violation{At: w.res.End, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (w *world) latency() time.Duration {
+	return time.Duration(1 + w.rng.Int63n(int64(w.cfg.MaxLatency)))
+}
+
+// fault sorteia, a cada entrega a uma réplica, se ela cai ou trava
+func (w *world) fault(ev *event) {
+	if ev.dir == "" || !strings.HasPrefix(ev.to, "r") || w.net.stateOf(ev.to) != up || w.faults >= w.cfg.MaxFaults {
+		return
+	}
+	switch p := w.rng.Float64(); {
+	case p < w.cfg.CrashRate:
+		w.net.setState(ev.to, crashed)
+		w.faults++
+		w.tracef("%s crashes", ev.to)
+	case p < w.cfg.CrashRate+w.cfg.HangRate:
+		w.net.setState(ev.to, hung)
+		w.faults++
+		w.tracef("%s hangs", ev.to)
+	}
+}
+
+// heartbeat roda uma rodada do Detector enquanto há clientes ativos
+func (w *world) heartbeat() {
+	if w.finished() {
+		return
+	}
+	go w.detector.Probe()
+	w.net.after(w.cfg.Heartbeat, "heartbeat", "heartbeat", w.heartbeat)
+}
+
+func (w *world) finished() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, c := range w.clients {
+		if c.done < len(c.txs) {
+			return false
+		}
+	}
+	return true
+}
+
+// runClient executa as transações de c com client.Transaction, em sequência
+func (w *world) runClient(c *simClient, replicas []string) {
+	defer w.running.Done()
+	for i, ops := range c.txs {
+		tid := fmt.Sprintf("t%d", i+1)
+		tx := client.NewTransaction(c.cid, tid, replicas, "seq")
+		tx.Transport = w.net.Node(c.cid)
+		tx.Logger = logging.Discard
+		tx.ReadTimeout = w.cfg.ReadTimeout
+		if !w.begin(c, tid) {
+			return
+		}
+		var err error
+		for _, o := range ops {
+			if o.write {
+				tx.Write(o.key, []byte(c.cid+"/"+tid))
+				continue
+			}
+			if !w.wait(c, "read "+o.key) {
+				return
+			}
+			if _, _, err = tx.Get(o.key); err != nil {
+				break
+			}
+		}
+		var commit bool
+		if err == nil {
+			if !w.wait(c, "commit decision") {
+				return
+			}
+			commit, err = tx.Commit()
+		}
+		if !w.end(c, commit, err) {
+			return
+		}
+	}
+}
+
+// begin, wait e end registram o progresso de c; retornam falso quando a
+// simulação acabou e a goroutine deve sair
+func (w *world) begin(c *simClient, tid string) bool {
+	w.mu.Lock()
+	c.tid = tid
+	over := w.over
+	w.mu.Unlock()
+	if !over {
+		w.tracef("%s/%s begins", c.cid, tid)
+	}
+	return !over
+}
+
+func (w *world) wait(c *simClient, what string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	c.waiting = what
+	return !w.over
+}
+
+func (w *world) end(c *simClient, commit bool, err error) bool {
+	w.mu.Lock()
+	if w.over {
+		w.mu.Unlock()
+		return false
+	}
+	c.done++
+	c.waiting = ""
+	if commit {
+		w.res.Committed++
+	} else {
+		w.res.Aborted++
+	}
+	if err == nil {
+		w.outcomes = append(w.outcomes, outcome{cid: c.cid, tid: c.tid, commit: commit})
+	}
+	w.mu.Unlock()
+	if err != nil {
+		w.tracef("%s/%s fails: %v", c.cid, c.tid, err)
+	} else {
+		w.tracef("%s/%s commit=%v", c.cid, c.tid, commit)
+	}
+	return true
+}
+
+// check verifica liveness e concordância entre réplicas no fim da execução
+func (w *world) check() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, c := range w.clients {
+		if c.done < len(c.txs) {
+			w.violate("stuck", "%s/%s waiting for %s", c.cid, c.tid, c.waiting)
+		}
+	}
+	for _, o := range w.outcomes {
+		for _, r := range w.replicas {
+			if w.net.stateOf(r.name) != up {
+				continue
+			}
+			st := r.rep.Status(types.StatusRequest{Cid: o.cid, Tid: o.tid})
+			if applied := st.Known && st.Decision.Commit; applied != o.commit {
+				w.violate("decision", "%s/%s told commit=%v but %s applied=%v", o.cid, o.tid, o.commit, r.name, applied)
+			}
+		}
+	}
+	var ref *simReplica
+	var refDigest types.DigestReply
+	for _, r := range w.replicas {
+		if w.net.stateOf(r.name) != up {
+			continue
+		}
+		d := r.rep.Digest(types.DigestRequest{Digest: true})
+		if ref == nil {
+			ref, refDigest = r, d
+			continue
+		}
+		if d.Seq != refDigest.Seq || d.Root != refDigest.Root {
+			w.violate("divergence", "%s (seq %d) and %s (seq %d) differ", ref.name, refDigest.Seq, r.name, d.Seq)
+		}
+	}
+}
+
+// stop fecha a rede e encerra os componentes; o que os clientes fizerem
+// depois disso não entra no resultado
+func (w *world) stop() {
+	w.mu.Lock()
+	w.over = true
+	w.mu.Unlock()
+	w.net.close()
+	w.running.Wait()
+	w.seq.Shutdown(context.Background())
+	for _, r := range w.replicas {
+		r.rep.Shutdown(context.Background())
+	}
+}
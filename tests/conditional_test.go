@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// TestWriteIfAbsentAndCompareAndSet: sem ler antes, só a primeira criação da
+// chave confirma, e um compare-and-set só vale na versão esperada
+func TestWriteIfAbsentAndCompareAndSet(t *testing.T) {
+	sequencer := "seq"
+	reps := []string{"r1", "r2"}
+	tr := startSystem(t, sequencer, reps)
+
+	first := newTx(tr, "c1", "t1", reps, sequencer)
+	first.WriteIfAbsent("lock", []byte("c1"))
+	if ok, err := first.Commit(); err != nil || !ok {
+		t.Fatalf("Expected first insert to commit, got ok=%v err=%v", ok, err)
+	}
+	second := newTx(tr, "c2", "t2", reps, sequencer)
+	second.WriteIfAbsent("lock", []byte("c2"))
+	if ok, _ := second.Commit(); ok {
+		t.Fatal("Expected second insert to abort")
+	}
+	if d := second.Decision; d.Reason != types.ReasonPrecondition || d.Item != "lock" || d.Detail == "" {
+		t.Fatalf("Expected precondition failure on lock, got %+v", d)
+	}
+
+	cas := newTx(tr, "c1", "t3", reps, sequencer)
+	cas.WriteIf("lock", []byte("released"), first.Decision.Seq)
+	if ok, _ := cas.Commit(); !ok {
+		t.Fatalf("Expected compare-and-set at v%d to commit, got %+v", first.Decision.Seq, cas.Decision)
+	}
+	late := newTx(tr, "c2", "t4", reps, sequencer)
+	late.WriteIf("lock", []byte("c2"), first.Decision.Seq)
+	if ok, _ := late.Commit(); ok || late.Decision.Reason != types.ReasonPrecondition {
+		t.Fatalf("Expected stale compare-and-set to abort, got %+v", late.Decision)
+	}
+	if len(late.Rs) != 0 {
+		t.Errorf("Expected no reads, got %+v", late.Rs)
+	}
+}
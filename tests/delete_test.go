@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/client"
+)
+
+// TestDeleteThenRead: depois do commit de um Delete, leituras retornam
+// ErrNotFound em todas as réplicas e a chave pode ser recriada.
+func TestDeleteThenRead(t *testing.T) {
+	sequencer := "seq"
+	reps := []string{"r1", "r2"}
+	tr := startSystem(t, sequencer, reps)
+
+	del := newTx(tr, "c1", "t1", reps, sequencer)
+	del.Delete("x")
+	if _, err := del.Read("x"); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("Expected own delete to hide x, got err=%v", err)
+	}
+	if ok, err := del.Commit(); err != nil || !ok {
+		t.Fatalf("Expected delete to commit, got ok=%v err=%v", ok, err)
+	}
+	for _, r := range reps {
+		tx := newTx(tr, "c2", "t2-"+r, []string{r}, sequencer)
+		if _, err := tx.Read("x"); !errors.Is(err, client.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound from %s, got err=%v", r, err)
+		}
+	}
+
+	re := newTx(tr, "c3", "t3", reps, sequencer)
+	re.Read("x")
+	re.Write("x", []byte("again"))
+	if ok, err := re.Commit(); err != nil || !ok {
+		t.Fatalf("Expected recreate to commit, got ok=%v err=%v", ok, err)
+	}
+	if v, err := newTx(tr, "c4", "t4", reps, sequencer).Read("x"); err != nil || string(v) != "again" {
+		t.Fatalf("Expected 'again', got %s err=%v", v, err)
+	}
+}
+
+// TestDeleteConflictsWithReader: quem leu o valor antes da remoção aborta
+func TestDeleteConflictsWithReader(t *testing.T) {
+	sequencer := "seq"
+	reps := []string{"r1", "r2"}
+	tr := startSystem(t, sequencer, reps)
+
+	reader := newTx(tr, "c1", "t1", reps, sequencer)
+	if _, err := reader.Read("x"); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	del := newTx(tr, "c2", "t2", reps, sequencer)
+	del.Delete("x")
+	if ok, _ := del.Commit(); !ok {
+		t.Fatal("Expected delete to commit")
+	}
+	reader.Write("y", []byte("derived"))
+	if ok, _ := reader.Commit(); ok {
+		t.Fatal("Expected reader of deleted value to abort")
+	}
+}
+
+// TestInsertAbortsAbsentReader: quem leu a chave como ausente aborta se
+// outra transação a criar antes do seu commit
+func TestInsertAbortsAbsentReader(t *testing.T) {
+	sequencer := "seq"
+	reps := []string{"r1", "r2"}
+	tr := startSystem(t, sequencer, reps)
+
+	reader := newTx(tr, "c1", "t1", reps, sequencer)
+	if _, found, err := reader.Get("k"); err != nil || found {
+		t.Fatalf("Expected k absent, got found=%v err=%v", found, err)
+	}
+	ins := newTx(tr, "c2", "t2", reps, sequencer)
+	ins.Write("k", []byte(""))
+	if ok, _ := ins.Commit(); !ok {
+		t.Fatal("Expected insert to commit")
+	}
+	if v, found, err := newTx(tr, "c3", "t3", reps, sequencer).Get("k"); err != nil || !found || len(v) != 0 {
+		t.Fatalf("Expected empty value found, got %q found=%v err=%v", v, found, err)
+	}
+	reader.Write("k", []byte("mine"))
+	if ok, _ := reader.Commit(); ok {
+		t.Fatal("Expected reader of absent key to abort")
+	}
+}
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hrodric0/dur-impl/client"
+	"github.com/hrodric0/dur-impl/network"
+)
+
+// startFaultySystem inicializa o cluster sobre ft, cada componente com seu nome de nó
+func startFaultySystem(t testing.TB, ft *network.FaultTransport, sequencer string, reps []string) {
+	startCluster(t, func(addr string) network.Transport { return ft.Node(addr) }, sequencer, reps)
+}
+
+// readAt lê item diretamente da réplica addr
+func readAt(t *testing.T, tr network.Transport, addr, item string) string {
+	t.Helper()
+	tx := client.NewTransaction("probe", "probe", []string{addr}, "")
+	tx.Transport = tr
+	val, err := tx.Read(item)
+	if err != nil {
+		t.Fatalf("read %s at %s: %v", item, addr, err)
+	}
+	return string(val)
+}
+
+// TestFaultPartitionedReplicaIsSkipped: o sequencer não alcança r2 e decide
+// com r1. r2 fica para trás e recusa os commits seguintes, que passam por
+// r1, até alcançar os pares (aqui não há Peers).
+func TestFaultPartitionedReplicaIsSkipped(t *testing.T) {
+	ft := network.NewFaultTransport(network.NewMemTransport(), 1)
+	seq, reps := "seq", []string{"r1", "r2"}
+	startFaultySystem(t, ft, seq, reps)
+	ft.Partition([]string{seq}, []string{"r2"})
+
+	tx := newTx(ft.Node("c1"), "c1", "t1", reps, seq)
+	tx.Write("x", []byte("v1"))
+	if ok, err := tx.Commit(); err != nil || !ok {
+		t.Fatalf("Expected commit decided by r1, got ok=%v err=%v", ok, err)
+	}
+	ft.Heal()
+	tx = newTx(ft.Node("c1"), "c1", "t2", reps, seq)
+	tx.Write("y", []byte("v2"))
+	if ok, err := tx.Commit(); err != nil || !ok {
+		t.Fatalf("Expected commit decided by r1, got ok=%v err=%v", ok, err)
+	}
+	if v := readAt(t, ft, "r1", "x"); v != "v1" {
+		t.Errorf("Expected r1 to have applied v1, got %s", v)
+	}
+	if v := readAt(t, ft, "r2", "x"); v != "init" {
+		t.Errorf("Expected r2 behind, still at init, got %s", v)
+	}
+}
+
+// TestFaultLostReplyStillCommits: a resposta de r1 ao sequencer se perde e
+// r2 decide. Todas as réplicas aplicaram, e o cliente recebe o commit.
+func TestFaultLostReplyStillCommits(t *testing.T) {
+	ft := network.NewFaultTransport(network.NewMemTransport(), 1)
+	seq, reps := "seq", []string{"r1", "r2"}
+	startFaultySystem(t, ft, seq, reps)
+	ft.SetLink("r1", seq, network.Faults{Drop: 1})
+
+	tx := newTx(ft.Node("c1"), "c1", "t1", reps, seq)
+	tx.Write("x", []byte("v1"))
+	if ok, err := tx.Commit(); err != nil || !ok {
+		t.Fatalf("Expected commit decided by r2, got ok=%v err=%v", ok, err)
+	}
+	ft.Heal()
+	for _, r := range reps {
+		if v := readAt(t, ft, r, "x"); v != "v1" {
+			t.Errorf("Expected %s to have applied v1, got %s", r, v)
+		}
+	}
+}
+
+// TestFaultLostRequestToSequencer: o pedido do cliente se perde; nada é aplicado.
+func TestFaultLostRequestToSequencer(t *testing.T) {
+	ft := network.NewFaultTransport(network.NewMemTransport(), 1)
+	seq, reps := "seq", []string{"r1", "r2"}
+	startFaultySystem(t, ft, seq, reps)
+	ft.SetLink("c1", seq, network.Faults{Drop: 1})
+
+	tx := newTx(ft.Node("c1"), "c1", "t1", reps, seq)
+	tx.Write("x", []byte("v1"))
+	if _, err := tx.Commit(); err == nil {
+		t.Fatalf("Expected commit error when the request is lost")
+	}
+	for _, r := range reps {
+		if v := readAt(t, ft, r, "x"); v != "init" {
+			t.Errorf("Expected %s unchanged, got %s", r, v)
+		}
+	}
+}
+
+// TestFaultDelaysPreserveOrder: atrasos, duplicação e reordenação entre conexões
+// não quebram a ordem total; as réplicas terminam idênticas.
+func TestFaultDelaysPreserveOrder(t *testing.T) {
+	ft := network.NewFaultTransport(network.NewMemTransport(), 7)
+	seq, reps := "seq", []string{"r1", "r2", "r3"}
+	startFaultySystem(t, ft, seq, reps)
+	ft.Set(network.Faults{
+		MaxDelay:    2 * time.Millisecond,
+		Duplicate:   0.2,
+		Reorder:     0.3,
+		ReorderHold: 5 * time.Millisecond,
+	})
+
+	var wg sync.WaitGroup
+	for c := 0; c < 8; c++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			cid := fmt.Sprintf("c%d", id)
+			tx := newTx(ft.Node(cid), cid, "t"+cid, reps, seq)
+			tx.Write("x", []byte("v"+cid))
+			tx.Write(cid, []byte("done"))
+			if ok, err := tx.Commit(); err != nil || !ok {
+				t.Errorf("%s: expected blind write to commit, got ok=%v err=%v", cid, ok, err)
+			}
+		}(c)
+	}
+	wg.Wait()
+	ft.Heal()
+
+	want := readAt(t, ft, reps[0], "x")
+	for _, r := range reps[1:] {
+		if v := readAt(t, ft, r, "x"); v != want {
+			t.Errorf("Expected replicas to agree on x=%s, %s has %s", want, r, v)
+		}
+	}
+}
+
+// TestFaultReadFailover: com r1 inalcançável para o cliente, as leituras
+// passam para r2 e a transação ainda confirma.
+func TestFaultReadFailover(t *testing.T) {
+	ft := network.NewFaultTransport(network.NewMemTransport(), 1)
+	seq, reps := "seq", []string{"r1", "r2"}
+	startFaultySystem(t, ft, seq, reps)
+	ft.Partition([]string{"c1"}, []string{"r1"})
+
+	tx := newTx(ft.Node("c1"), "c1", "t1", reps, seq)
+	tx.ReadTimeout = time.Second
+	if v, err := tx.Read("x"); err != nil || string(v) != "init" {
+		t.Fatalf("Expected read via r2, got %s err=%v", v, err)
+	}
+	tx.Write("x", []byte("v1"))
+	if ok, err := tx.Commit(); err != nil || !ok {
+		t.Fatalf("Expected commit, got ok=%v err=%v", ok, err)
+	}
+}
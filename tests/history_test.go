@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/history"
+)
+
+// TestConcurrentHistorySerializable grava uma carga concorrente com conflitos
+// e verifica que a história das transações confirmadas é serializável.
+func TestConcurrentHistorySerializable(t *testing.T) {
+	sequencer := "seq"
+	reps := []string{"r1", "r2"}
+	tr := startSystem(t, sequencer, reps)
+	rec := history.NewRecorder()
+
+	var wg sync.WaitGroup
+	for c := 0; c < 6; c++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(id)))
+			cid := fmt.Sprintf("c%d", id)
+			for i := 0; i < 15; i++ {
+				tx := newTx(tr, cid, fmt.Sprintf("t%d", i), reps, sequencer)
+				tx.Recorder = rec
+				for n := 0; n < 3; n++ {
+					key := fmt.Sprintf("k%d", rng.Intn(4))
+					if rng.Intn(2) == 0 {
+						tx.Read(key)
+					} else {
+						tx.Write(key, []byte(cid))
+					}
+				}
+				tx.Commit()
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	rep := history.Check(rec.Events())
+	if !rep.Serializable() {
+		t.Fatalf("Expected serializable history, got %v", rep)
+	}
+	if rep.Transactions == 0 {
+		t.Fatalf("Expected some committed transactions")
+	}
+	t.Logf("%v", rep)
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
@@ -9,47 +10,86 @@ import (
 
 	"github.com/hrodric0/dur-impl/broadcast"
 	"github.com/hrodric0/dur-impl/client"
+	"github.com/hrodric0/dur-impl/network"
 	"github.com/hrodric0/dur-impl/server"
 )
 
-// startSystem inicializa sequencer e réplicas, aguardando até que estejam escutando
-func startSystem(sequencer string, reps []string) {
-	// Sequencer
-	go broadcast.StartSequencer(sequencer, reps)
-	// Réplicas
-	for _, addr := range reps {
-		go server.StartReplica(addr)
-	}
-	// Aguarda sequencer e réplicas estarem prontos
-	// Verifica sequencer
-	for i := 0; i < 50; i++ {
-		conn, err := net.Dial("tcp", sequencer)
-		if err == nil {
-			conn.Close()
-			break
+// startSystem inicializa sequencer e réplicas num transporte em memória.
+// Os listeners são registrados antes de retornar, então não há espera; o
+// cluster é encerrado ao fim do teste.
+func startSystem(t testing.TB, sequencer string, reps []string) network.Transport {
+	tr := network.NewMemTransport()
+	startCluster(t, func(string) network.Transport { return tr }, sequencer, reps)
+	return tr
+}
+
+// startCluster inicia réplicas e sequencer, cada um no transporte dado por
+// node, e registra o Shutdown de todos no fim do teste: o sequencer primeiro,
+// para que os commits em andamento cheguem às réplicas.
+func startCluster(t testing.TB, node func(addr string) network.Transport, sequencer string, reps []string) {
+	type stopper interface{ Shutdown(context.Context) error }
+	start := func(s stopper, err error) {
+		if err != nil {
+			t.Fatalf("start: %v", err)
 		}
-		time.Sleep(20 * time.Millisecond)
+		t.Cleanup(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := s.Shutdown(ctx); err != nil {
+				t.Errorf("shutdown: %v", err)
+			}
+		})
 	}
-	// Verifica réplicas
 	for _, addr := range reps {
-		for i := 0; i < 50; i++ {
-			conn, err := net.Dial("tcp", addr)
-			if err == nil {
-				conn.Close()
-				break
-			}
-			time.Sleep(20 * time.Millisecond)
+		rep := server.NewReplica(addr)
+		rep.Transport = node(addr)
+		start(rep, rep.Start(context.Background()))
+	}
+	seq := broadcast.NewSequencer(sequencer, reps)
+	seq.Transport = node(sequencer)
+	start(seq, seq.Start(context.Background()))
+}
+
+// newTx cria uma transação ligada ao transporte do sistema
+func newTx(tr network.Transport, cid, tid string, reps []string, seq string) *client.Transaction {
+	tx := client.NewTransaction(cid, tid, reps, seq)
+	tx.Transport = tr
+	return tx
+}
+
+// TestTCPTransportCommit exercita o sistema sobre sockets reais em portas efêmeras
+func TestTCPTransportCommit(t *testing.T) {
+	listen := func() net.Listener {
+		ln, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
 		}
+		return ln
+	}
+	seqLn := listen()
+	reps := []string{}
+	for i := 0; i < 2; i++ {
+		ln := listen()
+		reps = append(reps, ln.Addr().String())
+		go server.NewReplica(ln.Addr().String()).Serve(ln)
+	}
+	go broadcast.ServeSequencer(seqLn, network.TCP, reps)
+
+	tx := client.NewTransaction("c1", "t1", reps, seqLn.Addr().String())
+	if val, err := tx.Read("x"); err != nil || string(val) != "init" {
+		t.Fatalf("Expected 'init', got '%s' err=%v", val, err)
+	}
+	tx.Write("x", []byte("v1"))
+	if ok, err := tx.Commit(); err != nil || !ok {
+		t.Fatalf("Expected commit success, got ok=%v err=%v", ok, err)
 	}
-	// Aguarda inicialização
-	time.Sleep(100 * time.Millisecond)
 }
 
 func TestSingleTransactionCommit(t *testing.T) {
 	sequencer := "localhost:9100"
 	reps := []string{"localhost:9101", "localhost:9102"}
-	startSystem(sequencer, reps)
-	tx := client.NewTransaction("c1", "t1", reps, sequencer)
+	tr := startSystem(t, sequencer, reps)
+	tx := newTx(tr, "c1", "t1", reps, sequencer)
 	val, err := tx.Read("x")
 	if err != nil {
 		t.Fatalf("Read failed: %v", err)
@@ -67,9 +107,9 @@ func TestSingleTransactionCommit(t *testing.T) {
 func TestNonConflictingTransactions(t *testing.T) {
 	sequencer := "localhost:9200"
 	reps := []string{"localhost:9201", "localhost:9202"}
-	startSystem(sequencer, reps)
-	tx1 := client.NewTransaction("c1", "t1", reps, sequencer)
-	tx2 := client.NewTransaction("c2", "t2", reps, sequencer)
+	tr := startSystem(t, sequencer, reps)
+	tx1 := newTx(tr, "c1", "t1", reps, sequencer)
+	tx2 := newTx(tr, "c2", "t2", reps, sequencer)
 	tx1.Write("a", []byte("1"))
 	tx2.Write("b", []byte("2"))
 	ok1, err1 := tx1.Commit()
@@ -85,10 +125,10 @@ func TestNonConflictingTransactions(t *testing.T) {
 func TestCommitAndAbort(t *testing.T) {
 	sequencer := "localhost:9300"
 	reps := []string{"localhost:9301", "localhost:9302"}
-	startSystem(sequencer, reps)
-	t1 := client.NewTransaction("c1", "t1", reps, sequencer)
+	tr := startSystem(t, sequencer, reps)
+	t1 := newTx(tr, "c1", "t1", reps, sequencer)
 	x1, _ := t1.Read("x")
-	t2 := client.NewTransaction("c2", "t2", reps, sequencer)
+	t2 := newTx(tr, "c2", "t2", reps, sequencer)
 	t2.Write("x", []byte("v2"))
 	if ok, _ := t2.Commit(); !ok {
 		t.Fatal("t2 should commit")
@@ -104,8 +144,8 @@ func TestCommitAndAbort(t *testing.T) {
 func TestMultiKeyTransaction(t *testing.T) {
 	sequencer := "localhost:9400"
 	reps := []string{"localhost:9401", "localhost:9402"}
-	startSystem(sequencer, reps)
-	tx := client.NewTransaction("c1", "t1", reps, sequencer)
+	tr := startSystem(t, sequencer, reps)
+	tx := newTx(tr, "c1", "t1", reps, sequencer)
 	tx.Write("a", []byte("1"))
 	tx.Write("b", []byte("2"))
 	ok, err := tx.Commit()
@@ -114,7 +154,7 @@ func TestMultiKeyTransaction(t *testing.T) {
 	}
 
 	// nova tx lê ambas
-	tx2 := client.NewTransaction("c2", "t2", reps, sequencer)
+	tx2 := newTx(tr, "c2", "t2", reps, sequencer)
 	va, _ := tx2.Read("a")
 	vb, _ := tx2.Read("b")
 	if string(va) != "1" || string(vb) != "2" {
@@ -126,12 +166,12 @@ func TestMultiKeyTransaction(t *testing.T) {
 func TestReadAfterCommit(t *testing.T) {
 	sequencer := "localhost:9500"
 	reps := []string{"localhost:9501", "localhost:9502"}
-	startSystem(sequencer, reps)
-	tx1 := client.NewTransaction("c1", "t1", reps, sequencer)
+	tr := startSystem(t, sequencer, reps)
+	tx1 := newTx(tr, "c1", "t1", reps, sequencer)
 	tx1.Write("x", []byte("v1"))
 	tx1.Commit()
 
-	tx2 := client.NewTransaction("c2", "t2", reps, sequencer)
+	tx2 := newTx(tr, "c2", "t2", reps, sequencer)
 	val, err := tx2.Read("x")
 	if err != nil || string(val) != "v1" {
 		t.Fatalf("expected read-after-commit v1, got %s err=%v", val, err)
@@ -142,12 +182,12 @@ func TestReadAfterCommit(t *testing.T) {
 func TestAbortThenCommitThenRead(t *testing.T) {
 	sequencer := "localhost:9600"
 	reps := []string{"localhost:9601", "localhost:9602"}
-	startSystem(sequencer, reps)
+	tr := startSystem(t, sequencer, reps)
 
-	t1 := client.NewTransaction("c1", "t1", reps, sequencer)
+	t1 := newTx(tr, "c1", "t1", reps, sequencer)
 	_, _ = t1.Read("x")
 
-	t2 := client.NewTransaction("c2", "t2", reps, sequencer)
+	t2 := newTx(tr, "c2", "t2", reps, sequencer)
 	t2.Write("x", []byte("v2"))
 	t2.Commit()
 
@@ -158,7 +198,7 @@ func TestAbortThenCommitThenRead(t *testing.T) {
 	}
 
 	// t3 lee após t2
-	t3 := client.NewTransaction("c3", "t3", reps, sequencer)
+	t3 := newTx(tr, "c3", "t3", reps, sequencer)
 	val, _ := t3.Read("x")
 	if string(val) != "v2" {
 		t.Fatalf("expected t3 read v2, got %s", val)
@@ -169,10 +209,10 @@ func TestAbortThenCommitThenRead(t *testing.T) {
 func TestReadOnlyTransactions(t *testing.T) {
 	sequencer := "localhost:9700"
 	reps := []string{"localhost:9701", "localhost:9702"}
-	startSystem(sequencer, reps)
+	tr := startSystem(t, sequencer, reps)
 
 	for i := 0; i < 5; i++ {
-		tx := client.NewTransaction(fmt.Sprintf("c%d", i), fmt.Sprintf("t%d", i), reps, sequencer)
+		tx := newTx(tr, fmt.Sprintf("c%d", i), fmt.Sprintf("t%d", i), reps, sequencer)
 		val, err := tx.Read("x")
 		if err != nil || string(val) == "" {
 			t.Fatalf("read-only tx failed read: val=%s err=%v", val, err)
@@ -188,10 +228,10 @@ func TestReadOnlyTransactions(t *testing.T) {
 func TestSequentialCommits(t *testing.T) {
 	sequencer := "localhost:9800"
 	reps := []string{"localhost:9801", "localhost:9802"}
-	startSystem(sequencer, reps)
+	tr := startSystem(t, sequencer, reps)
 
 	for i := 0; i < 10; i++ {
-		tx := client.NewTransaction(fmt.Sprintf("c%d", i), fmt.Sprintf("t%d", i), reps, sequencer)
+		tx := newTx(tr, fmt.Sprintf("c%d", i), fmt.Sprintf("t%d", i), reps, sequencer)
 		tx.Write("x", []byte(fmt.Sprintf("v%d", i)))
 		ok, err := tx.Commit()
 		if err != nil || !ok {
@@ -200,7 +240,7 @@ func TestSequentialCommits(t *testing.T) {
 	}
 
 	// verifica valor final
-	tx := client.NewTransaction("cF", "tF", reps, sequencer)
+	tx := newTx(tr, "cF", "tF", reps, sequencer)
 	val, _ := tx.Read("x")
 	if string(val) != "v9" {
 		t.Fatalf("expected final value v9, got %s", val)
@@ -219,20 +259,24 @@ func TestVariableReplicasAndClients(t *testing.T) {
 		for i := 0; i < cfg.reps; i++ {
 			repsAddrs[i] = fmt.Sprintf("localhost:%d", base+1+i)
 		}
-		startSystem(sequencer, repsAddrs)
+		tr := startSystem(t, sequencer, repsAddrs)
 		t.Logf("Config %d: %d replicas, %d clients on base port %d", idx, cfg.reps, cfg.clients, base)
-		var wg sync.WaitGroup
+		var wg, reads sync.WaitGroup
 		wg.Add(cfg.clients)
+		reads.Add(cfg.clients)
 		var mu sync.Mutex
 		successes := 0
 		for c := 0; c < cfg.clients; c++ {
 			go func(id int) {
 				defer wg.Done()
-				tx := client.NewTransaction(
+				tx := newTx(tr,
 					fmt.Sprintf("c%d", id), fmt.Sprintf("t%d", id),
 					repsAddrs, sequencer)
 				// Leitura
 				_, err := tx.Read("x")
+				// Todos leem antes de qualquer commit, garantindo o conflito
+				reads.Done()
+				reads.Wait()
 				if err != nil {
 					t.Errorf("[%d] Client %d: read error: %v", idx, id, err)
 					return
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentIncrementsNeverAbort: incrementos concorrentes do mesmo
+// contador não entram no rs, então todos confirmam sem retry
+func TestConcurrentIncrementsNeverAbort(t *testing.T) {
+	sequencer := "seq"
+	reps := []string{"r1", "r2"}
+	tr := startSystem(t, sequencer, reps)
+
+	const clients, each = 8, 5
+	var wg sync.WaitGroup
+	for c := 0; c < clients; c++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for i := 0; i < each; i++ {
+				tx := newTx(tr, fmt.Sprintf("c%d", id), fmt.Sprintf("t%d", i), reps, sequencer)
+				tx.Increment("hits", 1)
+				tx.SetAdd("seen", fmt.Sprintf("c%d", id))
+				if ok, err := tx.Commit(); err != nil || !ok {
+					t.Errorf("client %d: expected commit, got ok=%v err=%v", id, ok, err)
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	for _, r := range reps {
+		got, err := newTx(tr, "check", "check-"+r, []string{r}, sequencer).ReadMany("hits", "seen")
+		if err != nil {
+			t.Fatalf("ReadMany from %s: %v", r, err)
+		}
+		if string(got["hits"]) != fmt.Sprint(clients*each) {
+			t.Errorf("%s: expected hits=%d, got %s", r, clients*each, got["hits"])
+		}
+		if string(got["seen"]) != `["c0","c1","c2","c3","c4","c5","c6","c7"]` {
+			t.Errorf("%s: unexpected set %s", r, got["seen"])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hrodric0/dur-impl/client"
+	"github.com/hrodric0/dur-impl/network"
+)
+
+// TestRetryConcurrentIncrements: clientes concorrentes fazem read-modify-write
+// no mesmo contador; com RunTransaction nenhum incremento se perde.
+func TestRetryConcurrentIncrements(t *testing.T) {
+	sequencer := "seq"
+	reps := []string{"r1", "r2"}
+	tr := startSystem(t, sequencer, reps)
+	policy := client.RetryPolicy{MaxAttempts: 100, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+	const clients = 8
+	var wg sync.WaitGroup
+	for c := 0; c < clients; c++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			tmpl := newTx(tr, fmt.Sprintf("c%d", id), "", reps, sequencer)
+			_, err := client.RunTransaction(context.Background(), tmpl, policy, func(tx *client.Transaction) error {
+				v, _, err := tx.Get("counter") // ausente conta como zero
+				if err != nil {
+					return err
+				}
+				n, _ := strconv.Atoi(string(v))
+				tx.Write("counter", []byte(strconv.Itoa(n+1)))
+				return nil
+			})
+			if err != nil {
+				t.Errorf("client %d: %v", id, err)
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	tx := newTx(tr, "check", "check", reps, sequencer)
+	if v, _ := tx.Read("counter"); string(v) != strconv.Itoa(clients) {
+		t.Fatalf("Expected counter=%d, got %s", clients, v)
+	}
+}
+
+// lostReply descarta a primeira resposta de replica ao sequencer: a réplica
+// aplica o commit, mas o sequencer não fica sabendo
+type lostReply struct {
+	network.Transport
+	replica string
+	done    atomic.Bool
+}
+
+func (l *lostReply) Dial(addr string) (net.Conn, error) {
+	c, err := l.Transport.Dial(addr)
+	if err != nil || addr != l.replica || !l.done.CompareAndSwap(false, true) {
+		return c, err
+	}
+	return dropReply{c}, nil
+}
+
+type dropReply struct{ net.Conn }
+
+func (c dropReply) Read(p []byte) (int, error) {
+	c.Conn.Read(p)
+	c.Conn.Close()
+	return 0, net.ErrClosed
+}
+
+// TestRetryLostReplyIncrementsOnce: a resposta de r1 se perde depois de ele
+// aplicar o incremento, e o sequencer aborta com unavailable. RunTransaction
+// reenvia o mesmo Tid, as réplicas respondem a decisão lembrada e o
+// incremento é aplicado uma única vez.
+func TestRetryLostReplyIncrementsOnce(t *testing.T) {
+	sequencer := "seq"
+	reps := []string{"r1", "r2"}
+	tr := network.NewMemTransport()
+	lost := &lostReply{Transport: tr, replica: "r1"}
+	startCluster(t, func(addr string) network.Transport {
+		if addr == sequencer {
+			return lost
+		}
+		return tr
+	}, sequencer, reps)
+
+	runs := 0
+	tmpl := newTx(tr, "c1", "", reps, sequencer)
+	policy := client.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	tx, err := client.RunTransaction(context.Background(), tmpl, policy, func(tx *client.Transaction) error {
+		runs++
+		return tx.Increment("n", 1)
+	})
+	if err != nil {
+		t.Fatalf("Expected the resubmitted commit to succeed, got %v", err)
+	}
+	if !lost.done.Load() || runs != 1 {
+		t.Fatalf("Expected one lost reply and a single run, got lost=%v runs=%d", lost.done.Load(), runs)
+	}
+	for _, r := range reps {
+		if v := readAt(t, tr, r, "n"); v != "1" {
+			t.Errorf("Expected %s to have n=1 after %s, got %s", r, tx.Tid, v)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/client"
+)
+
+// TestScanPhantomAborts: uma transação conta as chaves de um prefixo e grava
+// o total; uma inserção concorrente no prefixo (fantasma) a faz abortar,
+// enquanto uma escrita fora do prefixo não interfere.
+func TestScanPhantomAborts(t *testing.T) {
+	sequencer := "seq"
+	reps := []string{"r1", "r2"}
+	tr := startSystem(t, sequencer, reps)
+
+	setup := newTx(tr, "c0", "t0", reps, sequencer)
+	setup.Write("user/1", []byte("ana"))
+	setup.Write("user/2", []byte("bia"))
+	if ok, _ := setup.Commit(); !ok {
+		t.Fatal("setup commit failed")
+	}
+
+	count := func(tid string) (*client.Transaction, int) {
+		tx := newTx(tr, "c1", tid, reps, sequencer)
+		items, err := tx.ScanPrefix("user/", 0)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		return tx, len(items)
+	}
+
+	tx, n := count("t1")
+	if n != 2 {
+		t.Fatalf("Expected 2 users, got %d", n)
+	}
+	other := newTx(tr, "c2", "t2", reps, sequencer)
+	other.Write("group/1", []byte("x"))
+	if ok, _ := other.Commit(); !ok {
+		t.Fatal("Expected write outside the prefix to commit")
+	}
+	tx.Write("users", []byte(fmt.Sprint(n)))
+	if ok, _ := tx.Commit(); !ok {
+		t.Fatal("Expected commit when no key in the prefix changed")
+	}
+
+	tx, n = count("t3")
+	ins := newTx(tr, "c2", "t4", reps, sequencer)
+	ins.Write("user/3", []byte("caio"))
+	if ok, _ := ins.Commit(); !ok {
+		t.Fatal("Expected insert to commit")
+	}
+	tx.Write("users", []byte(fmt.Sprint(n)))
+	if ok, _ := tx.Commit(); ok {
+		t.Fatal("Expected phantom insert to abort the scanning transaction")
+	}
+}
@@ -0,0 +1,31 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/hrodric0/dur-impl/client"
+)
+
+// TestClientReadYourWrites: a sessão avança para o Seq do commit e a
+// transação seguinte lê o valor escrito.
+func TestClientReadYourWrites(t *testing.T) {
+	sequencer := "seq"
+	reps := []string{"r1", "r2"}
+	tr := startSystem(t, sequencer, reps)
+	c := client.New(client.Config{Cid: "c1", Replicas: reps, Sequencer: sequencer, Transport: tr, Selector: client.RoundRobin()})
+
+	tx := c.Begin()
+	tx.Write("x", []byte("mine"))
+	if ok, err := tx.Commit(); err != nil || !ok {
+		t.Fatalf("Expected commit, got ok=%v err=%v", ok, err)
+	}
+	if c.Session() != tx.Decision.Seq || c.Session() == 0 {
+		t.Fatalf("Expected session at commit seq %d, got %d", tx.Decision.Seq, c.Session())
+	}
+	for i := 0; i < len(reps); i++ {
+		next := c.Begin()
+		if v, err := next.Read("x"); err != nil || string(v) != "mine" {
+			t.Fatalf("Expected to read own write, got %s err=%v", v, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/client"
+	"github.com/hrodric0/dur-impl/network"
+)
+
+// TestAmbiguousCommitResolved: a decisão do sequencer se perde no caminho
+// de volta. O cliente recebe ErrAmbiguousCommit, descobre por QueryStatus
+// que a transação confirmou, e reenviar o mesmo commit não reaplica o ws.
+func TestAmbiguousCommitResolved(t *testing.T) {
+	ft := network.NewFaultTransport(network.NewMemTransport(), 1)
+	seq, reps := "seq", []string{"r1", "r2"}
+	startFaultySystem(t, ft, seq, reps)
+	ft.SetLink(seq, "c1", network.Faults{Drop: 1})
+
+	tx := newTx(ft.Node("c1"), "c1", "t1", reps, seq)
+	tx.Increment("hits", 1)
+	if _, err := tx.Commit(); !errors.Is(err, client.ErrAmbiguousCommit) {
+		t.Fatalf("Expected ErrAmbiguousCommit, got %v", err)
+	}
+	st, err := tx.QueryStatus()
+	if err != nil || !st.Known || !st.Decision.Commit {
+		t.Fatalf("Expected status committed, got %+v err=%v", st, err)
+	}
+
+	ft.Heal()
+	if ok, err := tx.Commit(); err != nil || !ok || tx.Decision.Seq != st.Decision.Seq {
+		t.Fatalf("Expected the original decision at seq %d, got ok=%v err=%v dec=%+v", st.Decision.Seq, ok, err, tx.Decision)
+	}
+	for _, r := range reps {
+		if v := readAt(t, ft, r, "hits"); v != "1" {
+			t.Errorf("Expected %s to apply the increment once, got %s", r, v)
+		}
+	}
+}
+
+// TestQueryStatusUnknown: nenhuma réplica conhece um Tid nunca enviado
+func TestQueryStatusUnknown(t *testing.T) {
+	sequencer := "seq"
+	reps := []string{"r1", "r2"}
+	tr := startSystem(t, sequencer, reps)
+	c := client.New(client.Config{Cid: "c1", Replicas: reps, Sequencer: sequencer, Transport: tr})
+	st, err := c.QueryStatus("never-sent")
+	if err != nil || st.Known {
+		t.Fatalf("Expected unknown status, got %+v err=%v", st, err)
+	}
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hrodric0/dur-impl/broadcast"
+	"github.com/hrodric0/dur-impl/client"
+	"github.com/hrodric0/dur-impl/network"
+	"github.com/hrodric0/dur-impl/server"
+	"github.com/hrodric0/dur-impl/trace"
+)
+
+// TestTraceAcrossComponents segue uma transação do cliente às réplicas:
+// todos os spans estão no mesmo trace, encadeados pelos pais
+func TestTraceAcrossComponents(t *testing.T) {
+	tr := network.NewMemTransport()
+	mem := &trace.Memory{}
+	reps := []string{"r1", "r2"}
+	var stops []func(context.Context) error
+	for _, addr := range reps {
+		rep := server.NewReplica(addr)
+		rep.Transport = tr
+		rep.Tracer = trace.New("dur-replica", mem)
+		if err := rep.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		stops = append(stops, rep.Shutdown)
+	}
+	seq := broadcast.NewSequencer("seq", reps)
+	seq.Transport = tr
+	seq.Tracer = trace.New("dur-sequencer", mem)
+	if err := seq.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	c := client.New(client.Config{Replicas: reps, Sequencer: "seq", Transport: tr, Tracer: trace.New("dur-client", mem)})
+	tx := c.Begin()
+	if _, _, err := tx.Get("x"); err != nil {
+		t.Fatal(err)
+	}
+	tx.Write("x", []byte("1"))
+	if ok, err := tx.Commit(); err != nil || !ok {
+		t.Fatalf("commit = %v, %v", ok, err)
+	}
+	// Shutdown espera o handler do sequencer, que termina o último span
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := seq.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, stop := range stops {
+		if err := stop(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	id := tx.TraceID()
+	if id == "" {
+		t.Fatal("transaction has no trace id")
+	}
+	spans := mem.Spans(id)
+	if got, all := len(spans), len(mem.Spans("")); got != all {
+		t.Fatalf("%d of %d spans in trace %s", got, all, id)
+	}
+	byID := map[string]trace.SpanData{}
+	count := map[string]int{}
+	for _, s := range spans {
+		byID[s.SpanID] = s
+		count[s.Name]++
+	}
+	want := map[string]int{
+		"transaction": 1, "client.read": 1, "replica.read": 1, "client.commit": 1,
+		"sequencer.commit": 1, "sequencer.queue": 1, "sequencer.reply": 1,
+		"sequencer.replica": 2, "sequencer.dial": 2,
+		"replica.certify": 2, "replica.lock": 2,
+	}
+	for name, n := range want {
+		if count[name] != n {
+			t.Errorf("%d %q spans, want %d", count[name], name, n)
+		}
+	}
+	parent := func(s trace.SpanData) string { return byID[s.ParentID].Name }
+	for _, s := range spans {
+		var p string
+		switch s.Name {
+		case "transaction":
+			if s.ParentID != "" {
+				t.Errorf("root has parent %s", s.ParentID)
+			}
+			continue
+		case "replica.read":
+			p = "client.read"
+		case "sequencer.commit":
+			p = "client.commit"
+		case "sequencer.queue", "sequencer.reply", "sequencer.replica":
+			p = "sequencer.commit"
+		case "sequencer.dial", "replica.certify":
+			p = "sequencer.replica"
+		case "replica.lock":
+			p = "replica.certify"
+		default:
+			p = "transaction"
+		}
+		if got := parent(s); got != p {
+			t.Errorf("parent of %s = %q, want %q", s.Name, got, p)
+		}
+	}
+	for _, s := range spans {
+		if s.Name == "sequencer.commit" && s.Attrs["commit"] != "true" {
+			t.Errorf("sequencer.commit attrs = %v", s.Attrs)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package trace
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JSONExporter grava cada span como uma linha JSON no formato OTLP
+// (ExportTraceServiceRequest), como o exportador de arquivo do
+// OpenTelemetry Collector, pronta para ser importada por ele
+type JSONExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONExporter cria um exportador que escreve em w
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttr struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"` // 2 = erro
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"` // 1 = interno
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []otlpAttr `json:"attributes,omitempty"`
+	Status            otlpStatus `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttr `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+// Export grava d
+func (e *JSONExporter) Export(service string, d SpanData) {
+	span := otlpSpan{
+		TraceID:           d.TraceID,
+		SpanID:            d.SpanID,
+		ParentSpanID:      d.ParentID,
+		Name:              d.Name,
+		Kind:              1,
+		StartTimeUnixNano: strconv.FormatInt(d.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(d.End.UnixNano(), 10),
+		Attributes:        attrs(d.Attrs),
+	}
+	if d.Error != "" {
+		span.Status = otlpStatus{Code: 2, Message: d.Error}
+	}
+	var rs otlpResourceSpans
+	rs.Resource.Attributes = []otlpAttr{{Key: "service.name", Value: otlpValue{service}}}
+	var ss otlpScopeSpans
+	ss.Scope.Name = "github.com/hrodric0/dur-impl"
+	ss.Spans = []otlpSpan{span}
+	rs.ScopeSpans = []otlpScopeSpans{ss}
+	line, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{rs}})
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.w.Write(append(line, '\n'))
+}
+
+func attrs(m map[string]string) []otlpAttr {
+	out := make([]otlpAttr, 0, len(m))
+	for k, v := range m {
+		out = append(out, otlpAttr{Key: k, Value: otlpValue{v}})
+	}
+	slices.SortFunc(out, func(a, b otlpAttr) int { return cmp.Compare(a.Key, b.Key) })
+	return out
+}
+
+// LogExporter registra cada span como um evento Info "span" em Logger;
+// nil usa slog.Default()
+type LogExporter struct {
+	Logger *slog.Logger
+}
+
+// Export registra d no log
+func (e LogExporter) Export(service string, d SpanData) {
+	l := e.Logger
+	if l == nil {
+		l = slog.Default()
+	}
+	args := []any{"service", service, "name", d.Name, "trace", d.TraceID, "span", d.SpanID, "parent", d.ParentID,
+		"took", d.Duration().Round(time.Microsecond), "attrs", d.Attrs}
+	if d.Error != "" {
+		args = append(args, "error", d.Error)
+	}
+	l.Info("span", args...)
+}
+
+// Memory guarda os spans em memória, para testes e inspeção
+type Memory struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// Export guarda d
+func (m *Memory) Export(service string, d SpanData) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spans = append(m.spans, d)
+}
+
+// Spans retorna os spans do trace traceID, na ordem em que terminaram;
+// com traceID vazio, retorna todos
+func (m *Memory) Spans(traceID string) []SpanData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []SpanData
+	for _, s := range m.spans {
+		if traceID == "" || s.TraceID == traceID {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+// Package trace acompanha uma transação de ponta a ponta: cliente,
+// sequencer e réplicas criam spans ligados pelo types.TraceContext que
+// viaja nos pedidos, e um Exporter grava os spans terminados.
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/hrodric0/dur-impl/types"
+)
+
+// Tracer cria spans de um serviço e os entrega ao Exporter. Um *Tracer
+// nil é válido e não registra nada.
+type Tracer struct {
+	Service  string
+	Exporter Exporter
+}
+
+// New cria um Tracer para service que exporta para exp
+func New(service string, exp Exporter) *Tracer {
+	return &Tracer{Service: service, Exporter: exp}
+}
+
+// Exporter recebe cada span ao terminar
+type Exporter interface {
+	Export(service string, s SpanData)
+}
+
+// SpanData é um span terminado
+type SpanData struct {
+	TraceID  string
+	SpanID   string
+	ParentID string // vazio na raiz do trace
+	Name     string
+	Start    time.Time
+	End      time.Time
+	Attrs    map[string]string
+	Error    string // vazio se o estágio terminou bem
+}
+
+// Duration é o tempo gasto no span
+func (d SpanData) Duration() time.Duration { return d.End.Sub(d.Start) }
+
+// Span é um estágio em andamento. Um *Span nil, de um Tracer nil, ignora
+// todas as chamadas.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// Start abre um span name filho de parent; sem parent, começa um trace novo
+func (t *Tracer) Start(parent *types.TraceContext, name string) *Span {
+	if t == nil {
+		return nil
+	}
+	d := SpanData{SpanID: newID(8), Name: name, Start: time.Now()}
+	if parent != nil && parent.TraceID != "" {
+		d.TraceID, d.ParentID = parent.TraceID, parent.SpanID
+	} else {
+		d.TraceID = newID(16)
+	}
+	return &Span{tracer: t, data: d}
+}
+
+// Child abre um span filho de s, no mesmo Tracer
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.tracer.Start(s.Context(), name)
+}
+
+// Context é o contexto a propagar nos pedidos feitos dentro de s; nil se
+// s é nil, o que deixa o campo Trace vazio na mensagem
+func (s *Span) Context() *types.TraceContext {
+	if s == nil {
+		return nil
+	}
+	return &types.TraceContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID}
+}
+
+// SetAttr anota o span
+func (s *Span) SetAttr(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Attrs == nil {
+		s.data.Attrs = make(map[string]string)
+	}
+	s.data.Attrs[key] = value
+}
+
+// Fail marca o span com o erro err, se não for nil
+func (s *Span) Fail(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Error = err.Error()
+	s.mu.Unlock()
+}
+
+// End termina o span e o exporta; chamadas repetidas são ignoradas
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	d := s.data
+	s.mu.Unlock()
+	if s.tracer.Exporter != nil {
+		s.tracer.Exporter.Export(s.tracer.Service, d)
+	}
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
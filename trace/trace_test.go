@@ -0,0 +1,103 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/hrodric0/dur-impl/types"
+)
+
+func TestNilTracer(t *testing.T) {
+	var tr *Tracer
+	s := tr.Start(nil, "op")
+	if s != nil {
+		t.Fatalf("nil tracer started %v", s)
+	}
+	// nenhuma chamada num span nil entra em pânico
+	c := s.Child("child")
+	c.SetAttr("k", "v")
+	c.Fail(errors.New("boom"))
+	c.End()
+	if ctx := s.Context(); ctx != nil {
+		t.Fatalf("Context() = %v, want nil", ctx)
+	}
+}
+
+func TestSpanParents(t *testing.T) {
+	mem := &Memory{}
+	tr := New("svc", mem)
+	root := tr.Start(nil, "root")
+	child := root.Child("child")
+	remote := New("other", mem).Start(child.Context(), "remote")
+	remote.Fail(errors.New("boom"))
+	remote.End()
+	remote.End()
+	child.End()
+	root.End()
+
+	spans := mem.Spans(root.Context().TraceID)
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	r, c, x := spans[2], spans[1], spans[0]
+	if r.ParentID != "" || c.ParentID != r.SpanID || x.ParentID != c.SpanID {
+		t.Fatalf("parents: root=%q child=%q remote=%q", r.ParentID, c.ParentID, x.ParentID)
+	}
+	if len(r.TraceID) != 32 || len(r.SpanID) != 16 {
+		t.Fatalf("ids %q %q", r.TraceID, r.SpanID)
+	}
+	if x.Error != "boom" || x.End.Before(x.Start) {
+		t.Fatalf("remote = %+v", x)
+	}
+	if got := tr.Start(&types.TraceContext{}, "fresh").Context().TraceID; got == r.TraceID || got == "" {
+		t.Fatalf("empty parent reused trace %q", got)
+	}
+}
+
+func TestJSONExporter(t *testing.T) {
+	var buf bytes.Buffer
+	s := New("dur-test", NewJSONExporter(&buf)).Start(nil, "op")
+	s.SetAttr("b", "2")
+	s.SetAttr("a", "1")
+	s.Fail(errors.New("boom"))
+	s.End()
+
+	var req otlpRequest
+	if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 1 {
+		t.Fatalf("%d lines, want 1", n)
+	}
+	rs := req.ResourceSpans[0]
+	if a := rs.Resource.Attributes[0]; a.Key != "service.name" || a.Value.StringValue != "dur-test" {
+		t.Fatalf("resource = %+v", rs.Resource)
+	}
+	span := rs.ScopeSpans[0].Spans[0]
+	ctx := s.Context()
+	if span.TraceID != ctx.TraceID || span.SpanID != ctx.SpanID || span.Name != "op" {
+		t.Fatalf("span = %+v", span)
+	}
+	if len(span.Attributes) != 2 || span.Attributes[0].Key != "a" {
+		t.Fatalf("attributes = %+v", span.Attributes)
+	}
+	if span.Status.Code != 2 || span.Status.Message != "boom" {
+		t.Fatalf("status = %+v", span.Status)
+	}
+}
+
+func TestLogExporter(t *testing.T) {
+	var buf bytes.Buffer
+	s := New("dur-test", LogExporter{Logger: slog.New(slog.NewTextHandler(&buf, nil))}).Start(nil, "op")
+	s.SetAttr("k", "v")
+	s.Fail(errors.New("boom"))
+	s.End()
+	out := buf.String()
+	if !strings.Contains(out, "msg=span service=dur-test name=op trace="+s.Context().TraceID) || !strings.Contains(out, "attrs=map[k:v]") || !strings.Contains(out, "error=boom") {
+		t.Fatalf("log = %q", out)
+	}
+}
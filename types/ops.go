@@ -0,0 +1,101 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// ErrBadOperand indica que uma operação não se aplica ao valor atual
+// (por exemplo, incremento de um valor que não é inteiro)
+var ErrBadOperand = errors.New("bad operand")
+
+// Commutative diz se a escrita é uma operação comutativa
+func (we WriteEntry) Commutative() bool {
+	return we.Op == OpIncrement || we.Op == OpAppend || we.Op == OpSet
+}
+
+// Apply aplica we sobre o valor atual (cur, found) e retorna o novo valor;
+// found falso no resultado significa chave removida. Uma chave ausente vale
+// zero, vazio ou conjunto vazio para as operações comutativas.
+func Apply(cur []byte, found bool, we WriteEntry) ([]byte, bool, error) {
+	switch we.Op {
+	case OpPut:
+		return we.Value, true, nil
+	case OpDelete:
+		return nil, false, nil
+	case OpIncrement:
+		var n int64
+		if found {
+			var err error
+			if n, err = strconv.ParseInt(string(cur), 10, 64); err != nil {
+				return nil, false, fmt.Errorf("%w: increment %s: %q is not an integer", ErrBadOperand, we.Item, cur)
+			}
+		}
+		return []byte(strconv.FormatInt(n+we.Delta, 10)), true, nil
+	case OpAppend:
+		if !found {
+			return append([]byte(nil), we.Value...), true, nil
+		}
+		return append(append([]byte(nil), cur...), we.Value...), true, nil
+	case OpSet:
+		var set []string
+		if found {
+			if err := json.Unmarshal(cur, &set); err != nil {
+				return nil, false, fmt.Errorf("%w: set %s: %q is not a set", ErrBadOperand, we.Item, cur)
+			}
+		}
+		set = setUpdate(set, we.Add, we.Remove)
+		out, _ := json.Marshal(set)
+		return out, true, nil
+	}
+	return nil, false, fmt.Errorf("%w: unknown operation %q on %s", ErrBadOperand, we.Op, we.Item)
+}
+
+// Merge combina next com a escrita prev já pendente na mesma chave.
+// Sobre um put ou delete, a operação é aplicada localmente e vira um put;
+// operações comutativas do mesmo tipo se acumulam.
+func Merge(prev, next WriteEntry) (WriteEntry, error) {
+	if !next.Commutative() {
+		return next, nil
+	}
+	if !prev.Commutative() {
+		val, found, err := Apply(prev.Value, prev.Op == OpPut, next)
+		if err != nil {
+			return prev, err
+		}
+		if !found {
+			return WriteEntry{Item: next.Item, Op: OpDelete}, nil
+		}
+		return WriteEntry{Item: next.Item, Value: val}, nil
+	}
+	if prev.Op != next.Op {
+		return prev, fmt.Errorf("%w: %s after %s on %s", ErrBadOperand, next.Op, prev.Op, next.Item)
+	}
+	out := prev
+	switch next.Op {
+	case OpIncrement:
+		out.Delta += next.Delta
+	case OpAppend:
+		out.Value = append(append([]byte(nil), prev.Value...), next.Value...)
+	case OpSet:
+		// a última operação sobre cada membro vence
+		out.Add = setUpdate(slices.Clone(prev.Add), next.Add, next.Remove)
+		out.Remove = setUpdate(slices.Clone(prev.Remove), next.Remove, next.Add)
+	}
+	return out, nil
+}
+
+// setUpdate inclui add e retira remove de set, mantendo-o ordenado e sem repetições
+func setUpdate(set, add, remove []string) []string {
+	set = append(set, add...)
+	slices.Sort(set)
+	set = slices.Compact(set)
+	set = slices.DeleteFunc(set, func(m string) bool { return slices.Contains(remove, m) })
+	if set == nil {
+		set = []string{}
+	}
+	return set
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	cases := []struct {
+		name  string
+		cur   string
+		found bool
+		we    WriteEntry
+		want  string
+	}{
+		{"incr missing", "", false, WriteEntry{Op: OpIncrement, Delta: 3}, "3"},
+		{"incr", "40", true, WriteEntry{Op: OpIncrement, Delta: 2}, "42"},
+		{"decr", "1", true, WriteEntry{Op: OpIncrement, Delta: -5}, "-4"},
+		{"append missing", "", false, WriteEntry{Op: OpAppend, Value: []byte("a")}, "a"},
+		{"append", "ab", true, WriteEntry{Op: OpAppend, Value: []byte("c")}, "abc"},
+		{"set missing", "", false, WriteEntry{Op: OpSet, Add: []string{"b", "a", "b"}}, `["a","b"]`},
+		{"set", `["a","c"]`, true, WriteEntry{Op: OpSet, Add: []string{"b"}, Remove: []string{"a"}}, `["b","c"]`},
+		{"set empty", `["a"]`, true, WriteEntry{Op: OpSet, Remove: []string{"a"}}, `[]`},
+	}
+	for _, c := range cases {
+		got, found, err := Apply([]byte(c.cur), c.found, c.we)
+		if err != nil || !found || string(got) != c.want {
+			t.Errorf("%s: got %q found=%v err=%v, want %q", c.name, got, found, err, c.want)
+		}
+	}
+	if _, found, _ := Apply([]byte("v"), true, WriteEntry{Op: OpDelete}); found {
+		t.Errorf("Expected delete to remove the key")
+	}
+	for _, op := range []WriteEntry{{Op: OpIncrement, Delta: 1}, {Op: OpSet, Add: []string{"a"}}, {Op: "bogus"}} {
+		if _, _, err := Apply([]byte("not-a-number"), true, op); !errors.Is(err, ErrBadOperand) {
+			t.Errorf("%s: expected ErrBadOperand, got %v", op.Op, err)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	incr := func(d int64) WriteEntry { return WriteEntry{Item: "k", Op: OpIncrement, Delta: d} }
+	if got, _ := Merge(incr(2), incr(3)); got.Delta != 5 || got.Op != OpIncrement {
+		t.Errorf("Expected deltas to add up, got %+v", got)
+	}
+	if got, _ := Merge(WriteEntry{Item: "k", Value: []byte("10")}, incr(1)); got.Op != OpPut || string(got.Value) != "11" {
+		t.Errorf("Expected increment over a put to become a put, got %+v", got)
+	}
+	if got, _ := Merge(WriteEntry{Item: "k", Op: OpDelete}, incr(1)); got.Op != OpPut || string(got.Value) != "1" {
+		t.Errorf("Expected increment over a delete to start from zero, got %+v", got)
+	}
+	if got, _ := Merge(incr(1), WriteEntry{Item: "k", Value: []byte("x")}); got.Op != OpPut || string(got.Value) != "x" {
+		t.Errorf("Expected a put to replace a pending op, got %+v", got)
+	}
+	set, _ := Merge(WriteEntry{Item: "k", Op: OpSet, Add: []string{"a", "b"}}, WriteEntry{Item: "k", Op: OpSet, Remove: []string{"a"}, Add: []string{"c"}})
+	if !reflect.DeepEqual(set.Add, []string{"b", "c"}) || !reflect.DeepEqual(set.Remove, []string{"a"}) {
+		t.Errorf("Unexpected merged set op: %+v", set)
+	}
+	if _, err := Merge(incr(1), WriteEntry{Item: "k", Op: OpAppend, Value: []byte("x")}); !errors.Is(err, ErrBadOperand) {
+		t.Errorf("Expected ErrBadOperand mixing ops, got %v", err)
+	}
+	if _, err := Merge(WriteEntry{Item: "k", Value: []byte("x")}, incr(1)); !errors.Is(err, ErrBadOperand) {
+		t.Errorf("Expected ErrBadOperand incrementing a non-integer put, got %v", err)
+	}
+}
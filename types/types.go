@@ -1,43 +1,252 @@
 package types
 
-// ReadRequest para leitura 1:1
+// ReadRequest para leitura 1:1.
+// MinSeq é a menor sequência aplicada que a réplica deve ter para responder.
 type ReadRequest struct {
-	Cid  string `json:"cid"`
-	Item string `json:"item"`
+	Cid    string        `json:"cid"`
+	Item   string        `json:"item"`
+	MinSeq uint64        `json:"minSeq,omitempty"`
+	Trace  *TraceContext `json:"trace,omitempty"`
 }
 
-// ReadReply com valor e versão
+// TraceContext identifica o span de quem enviou um pedido, para que o
+// servidor crie os seus como filhos. IDs em hexadecimal, como no OTLP.
+type TraceContext struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+// ReadReply com valor e versão.
+// Seq é a última sequência aplicada pela réplica; Behind indica que ela
+// ainda não alcançou o MinSeq pedido e não retornou valor.
 type ReadReply struct {
 	Cid     string `json:"cid"`
 	Item    string `json:"item"`
 	Value   []byte `json:"value"`
 	Version uint64 `json:"version"`
+	Seq     uint64 `json:"seq,omitempty"`
+	Behind  bool   `json:"behind,omitempty"`
+	// Found indica que a chave existe; se falso, Value é nil e Version é a do
+	// tombstone (Deleted) ou zero se a chave nunca existiu
+	Found   bool `json:"found"`
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// MultiReadRequest lê várias chaves de uma vez, no mesmo snapshot
+type MultiReadRequest struct {
+	Cid    string        `json:"cid"`
+	Items  []string      `json:"items"`
+	MinSeq uint64        `json:"minSeq,omitempty"`
+	Trace  *TraceContext `json:"trace,omitempty"`
+}
+
+// MultiReadReply traz um ReadReply por chave pedida, na mesma ordem
+type MultiReadReply struct {
+	Cid     string      `json:"cid"`
+	Replies []ReadReply `json:"replies"`
+	Seq     uint64      `json:"seq"`
+	Behind  bool        `json:"behind,omitempty"`
 }
 
-// ReadEntry para uso interno do client
+// ReadEntry para uso interno do client.
+// Absent indica que a leitura não encontrou a chave (inexistente ou removida).
 type ReadEntry struct {
 	Item    string
 	Value   []byte
 	Version uint64
+	Absent  bool
 }
 
+// Operações de escrita carregadas no ws
+const (
+	OpPut    = ""       // grava Value
+	OpDelete = "delete" // remove a chave, deixando um tombstone versionado
+	// operações comutativas: aplicadas sobre o valor atual, sem entrar no rs
+	OpIncrement = "incr"   // soma Delta a um contador decimal
+	OpAppend    = "append" // concatena Value ao valor atual
+	OpSet       = "set"    // inclui Add e retira Remove de um conjunto (array JSON)
+)
+
 // WriteEntry para uso interno do client
 type WriteEntry struct {
-	Item  string
-	Value []byte
+	Item   string
+	Value  []byte
+	Op     string
+	Delta  int64
+	Add    []string
+	Remove []string
+}
+
+// ScanRequest pede, em ordem, as chaves em [Start, End); End vazio vai até
+// o fim. Limit > 0 limita o número de chaves retornadas.
+type ScanRequest struct {
+	Cid    string        `json:"cid"`
+	Start  string        `json:"start"`
+	End    string        `json:"end"`
+	Limit  int           `json:"limit,omitempty"`
+	MinSeq uint64        `json:"minSeq,omitempty"`
+	Trace  *TraceContext `json:"trace,omitempty"`
+}
+
+// ScanItem é uma chave retornada por uma varredura
+type ScanItem struct {
+	Item    string `json:"item"`
+	Value   []byte `json:"value"`
+	Version uint64 `json:"version"`
+}
+
+// ScanReply resposta da réplica; More indica que o Limit cortou o intervalo
+type ScanReply struct {
+	Cid    string     `json:"cid"`
+	Items  []ScanItem `json:"items"`
+	More   bool       `json:"more,omitempty"`
+	Seq    uint64     `json:"seq"`
+	Behind bool       `json:"behind,omitempty"`
+}
+
+// RangeRead é um predicado do rs: o intervalo [Start, End) foi varrido no
+// snapshot Seq. Qualquer escrita no intervalo depois de Seq invalida a leitura.
+type RangeRead struct {
+	Start string
+	End   string
+	Seq   uint64
+}
+
+// Precondition é verificada na certificação sem leitura prévia: a chave
+// deve estar na versão Version ou, com Absent, não existir
+type Precondition struct {
+	Item    string
+	Version uint64
+	Absent  bool
 }
 
 // CommitRequest enviado ao sequencer
 type CommitRequest struct {
-	Cid string       `json:"cid"`
-	Tid string       `json:"tid"`
-	Rs  []ReadEntry  `json:"rs"`
-	Ws  []WriteEntry `json:"ws"`
+	Cid    string         `json:"cid"`
+	Tid    string         `json:"tid"`
+	Rs     []ReadEntry    `json:"rs"`
+	Ws     []WriteEntry   `json:"ws"`
+	Ranges []RangeRead    `json:"ranges,omitempty"`
+	Pre    []Precondition `json:"pre,omitempty"`
+	Trace  *TraceContext  `json:"trace,omitempty"`
+	// After é a sequência que o sequencer espera que a réplica já tenha
+	// aplicado; uma réplica atrás dela perdeu commits e recusa o pedido
+	After uint64 `json:"after,omitempty"`
 }
 
-// CommitDecision resposta agregada do sequencer
+// Motivos de abort em CommitDecision.Reason
+const (
+	ReasonStaleRead    = "stale-read"   // uma leitura do rs foi sobrescrita
+	ReasonPhantom      = "phantom"      // um intervalo varrido mudou
+	ReasonPrecondition = "precondition" // uma Precondition não vale
+	ReasonBadOperand   = "bad-operand"  // uma operação comutativa não se aplica ao valor
+	ReasonUnavailable  = "unavailable"  // o sequencer não obteve a decisão de uma réplica
+)
+
+// CommitDecision resposta agregada do sequencer.
+// Seq é o número de sequência em que o ws foi aplicado (versão das escritas).
+// Num abort, Reason diz o motivo, Item a chave envolvida e Detail o descreve.
 type CommitDecision struct {
 	Cid    string `json:"cid"`
 	Tid    string `json:"tid"`
 	Commit bool   `json:"commit"`
+	Seq    uint64 `json:"seq,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Item   string `json:"item,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// StatusRequest pergunta a uma réplica a decisão da transação (Cid, Tid)
+type StatusRequest struct {
+	Cid string `json:"cid"`
+	Tid string `json:"tid"`
+}
+
+// StatusReply responde um StatusRequest. Known falso significa que a
+// réplica não certificou a transação (ainda) ou que ela saiu da janela de
+// decisões lembradas; Seq é a última sequência aplicada pela réplica.
+type StatusReply struct {
+	Cid      string         `json:"cid"`
+	Tid      string         `json:"tid"`
+	Known    bool           `json:"known"`
+	Decision CommitDecision `json:"decision"`
+	Seq      uint64         `json:"seq"`
+}
+
+// Retryable diz se reexecutar a transação com um Tid novo pode mudar o
+// resultado, o que só vale para conflitos (leitura obsoleta ou fantasma).
+// Um abort por indisponibilidade não é: as réplicas que responderam podem
+// ter aplicado o commit, então ele deve ser reenviado com o mesmo Tid.
+func (d CommitDecision) Retryable() bool {
+	return !d.Commit && (d.Reason == ReasonStaleRead || d.Reason == ReasonPhantom)
+}
+
+// DigestRequest pede a uma réplica o resumo do seu estado, para comparar
+// réplicas. Com Seq, o hash encadeado é o de depois do commit Seq; com
+// Buckets, a resposta traz as chaves desses buckets, tombstones incluídos.
+type DigestRequest struct {
+	Digest  bool   `json:"digest"` // sempre true; distingue a mensagem
+	Seq     uint64 `json:"seq,omitempty"`
+	Buckets []int  `json:"buckets,omitempty"`
+	// Decisions pede também a janela de decisões lembradas, para um reparo
+	Decisions bool `json:"decisions,omitempty"`
+}
+
+// DigestItem é uma chave de um digest ou reparo; Deleted marca um
+// tombstone, que também conta na certificação
+type DigestItem struct {
+	Item    string `json:"item"`
+	Value   []byte `json:"value,omitempty"`
+	Version uint64 `json:"version"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// DigestReply resume o estado de uma réplica na sequência Seq.
+// Chain é o hash encadeado dos commits aplicados até ChainSeq (vazio se a
+// réplica não guarda mais esse ponto); Root é a raiz da árvore de hashes
+// das chaves, tombstones incluídos, e Buckets são as folhas, uma por bucket.
+type DigestReply struct {
+	Seq      uint64       `json:"seq"`
+	ChainSeq uint64       `json:"chainSeq"`
+	Chain    string       `json:"chain,omitempty"`
+	Root     string       `json:"root"`
+	Buckets  []string     `json:"buckets"`
+	Items    []DigestItem `json:"items,omitempty"`
+	// Decisions é a janela de decisões, da mais antiga à mais nova
+	Decisions []CommitDecision `json:"decisions,omitempty"`
+}
+
+// RepairRequest substitui, numa réplica que ainda está em FromSeq, as
+// chaves dos Buckets pelas Items de uma réplica de referência (com os
+// tombstones), e adota a
+// sequência Seq e o hash encadeado Chain da referência. Com Decisions, a
+// janela de decisões lembradas também passa a ser a da referência, para
+// que um Tid já decidido lá não seja certificado de novo aqui.
+type RepairRequest struct {
+	Repair    bool             `json:"repair"` // sempre true; distingue a mensagem
+	FromSeq   uint64           `json:"fromSeq"`
+	Seq       uint64           `json:"seq"`
+	Chain     string           `json:"chain"`
+	Buckets   []int            `json:"buckets"`
+	Items     []DigestItem     `json:"items"`
+	Decisions []CommitDecision `json:"decisions,omitempty"`
+}
+
+// RepairReply responde um RepairRequest; Error explica uma recusa
+type RepairReply struct {
+	Repaired bool   `json:"repaired"`
+	Seq      uint64 `json:"seq"`
+	Error    string `json:"error,omitempty"`
+}
+
+// PingRequest é o heartbeat do detector de falhas (health.Detector)
+type PingRequest struct {
+	Ping bool `json:"ping"` // sempre true; distingue a mensagem
+}
+
+// PingReply responde um PingRequest com a sequência da réplica e se ela
+// está pronta (recuperada e alcançada)
+type PingReply struct {
+	Seq   uint64 `json:"seq"`
+	Ready bool   `json:"ready"`
 }
@@ -35,3 +35,15 @@ func TestCommitDecisionJSON(t *testing.T) {
 		t.Errorf("Expected %+v, got %+v", cd, decoded)
 	}
 }
+
+func TestCommitDecisionRetryable(t *testing.T) {
+	cases := map[string]bool{"": false, ReasonStaleRead: true, ReasonPhantom: true, ReasonUnavailable: false, ReasonPrecondition: false, ReasonBadOperand: false}
+	for reason, want := range cases {
+		if got := (CommitDecision{Reason: reason}).Retryable(); got != want {
+			t.Errorf("Retryable(%q) = %v, want %v", reason, got, want)
+		}
+	}
+	if (CommitDecision{Commit: true}).Retryable() {
+		t.Errorf("Expected a commit not to be retryable")
+	}
+}